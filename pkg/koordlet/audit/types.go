@@ -44,6 +44,9 @@ type Event struct {
 	Container string    `json:"container,omitempty"`
 	Reason    string    `json:"reason,omitempty"`
 	Message   string    `json:"message,omitempty"`
+	// OldValue and NewValue are the values before and after the update of a resource, e.g. a cgroup file.
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
 }
 
 // EventHelper is a helper struct use to support fluent APIs
@@ -134,6 +137,13 @@ func (e *EventHelper) Message(format string, args ...interface{}) *EventHelper {
 	return e
 }
 
+// Diff set the values before and after the update
+func (e *EventHelper) Diff(oldValue, newValue string) *EventHelper {
+	e.Event.OldValue = oldValue
+	e.Event.NewValue = newValue
+	return e
+}
+
 // Do write the event to the writer
 func (e *EventHelper) Do() error {
 	e.Event.CreatedAt = time.Now().Local()
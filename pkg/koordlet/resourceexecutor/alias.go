@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"sync"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+type resourceAlias struct {
+	v1Type sysutil.ResourceType
+	v2Type sysutil.ResourceType
+}
+
+var resourceAliases = struct {
+	lock    sync.RWMutex
+	aliases map[string]resourceAlias
+}{
+	aliases: map[string]resourceAlias{},
+}
+
+func init() {
+	// register the logical names of the resources whose interfaces differ between the cgroup versions, the resource
+	// types of both versions are the same since the registry resolves the version-specific interface by the type
+	RegisterAlias("cpu.quota", sysutil.CPUCFSQuotaName, sysutil.CPUCFSQuotaName)
+	RegisterAlias("cpu.period", sysutil.CPUCFSPeriodName, sysutil.CPUCFSPeriodName)
+	RegisterAlias("memory.limit", sysutil.MemoryLimitName, sysutil.MemoryLimitName)
+}
+
+// RegisterAlias registers a logical name which resolves to the concrete resource type of the current cgroup version,
+// so the callers do not need to know the version-specific resource types, e.g. "cpu.quota" resolves to
+// `cpu.cfs_quota_us` on cgroups-v1 and `cpu.max` on cgroups-v2.
+func RegisterAlias(logical string, v1Type, v2Type sysutil.ResourceType) {
+	resourceAliases.lock.Lock()
+	defer resourceAliases.lock.Unlock()
+	resourceAliases.aliases[logical] = resourceAlias{v1Type: v1Type, v2Type: v2Type}
+}
+
+// ResolveAlias returns the concrete resource type of the logical name for the current cgroup version. The resource
+// type is returned as it is if it is not an alias.
+func ResolveAlias(resourceType sysutil.ResourceType) sysutil.ResourceType {
+	resourceAliases.lock.RLock()
+	defer resourceAliases.lock.RUnlock()
+	alias, ok := resourceAliases.aliases[string(resourceType)]
+	if !ok {
+		return resourceType
+	}
+	if sysutil.GetCurrentCgroupVersion() == sysutil.CgroupVersionV2 {
+		return alias.v2Type
+	}
+	return alias.v1Type
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestResolveAlias(t *testing.T) {
+	RegisterAlias("io.weight", sysutil.BlkioBFQWeightName, sysutil.BlkioWeightName)
+	defer func() {
+		resourceAliases.lock.Lock()
+		delete(resourceAliases.aliases, "io.weight")
+		resourceAliases.lock.Unlock()
+	}()
+
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		resourceType sysutil.ResourceType
+		want         sysutil.ResourceType
+		wantFile     string
+	}{
+		{
+			name:         "resolve cpu.quota on cgroups-v1",
+			resourceType: "cpu.quota",
+			want:         sysutil.CPUCFSQuotaName,
+			wantFile:     "cpu.cfs_quota_us",
+		},
+		{
+			name:         "resolve cpu.quota on cgroups-v2",
+			useCgroupsV2: true,
+			resourceType: "cpu.quota",
+			want:         sysutil.CPUCFSQuotaName,
+			wantFile:     "cpu.max",
+		},
+		{
+			name:         "resolve memory.limit on cgroups-v2",
+			useCgroupsV2: true,
+			resourceType: "memory.limit",
+			want:         sysutil.MemoryLimitName,
+			wantFile:     "memory.max",
+		},
+		{
+			name:         "resolve io.weight on cgroups-v1",
+			resourceType: "io.weight",
+			want:         sysutil.BlkioBFQWeightName,
+			wantFile:     "blkio.bfq.weight",
+		},
+		{
+			name:         "resolve io.weight on cgroups-v2",
+			useCgroupsV2: true,
+			resourceType: "io.weight",
+			want:         sysutil.BlkioWeightName,
+			wantFile:     "io.weight",
+		},
+		{
+			name:         "keep the concrete resource type",
+			resourceType: sysutil.CPUSharesName,
+			want:         sysutil.CPUSharesName,
+			wantFile:     "cpu.shares",
+		},
+		{
+			name:         "keep the concrete resource type on cgroups-v2",
+			useCgroupsV2: true,
+			resourceType: sysutil.CPUCFSQuotaName,
+			want:         sysutil.CPUCFSQuotaName,
+			wantFile:     "cpu.max",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+			r, ok := sysutil.DefaultRegistry.Get(sysutil.GetCurrentCgroupVersion(), tt.want)
+			assert.True(t, ok)
+			helper.CreateCgroupFile("/kubepods.slice", r)
+
+			assert.Equal(t, tt.want, ResolveAlias(tt.resourceType))
+			u, err := DefaultCgroupUpdaterFactory.New(tt.resourceType, "/kubepods.slice", "100", nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, u.ResourceType())
+			assert.Equal(t, tt.wantFile, filepath.Base(u.Path()))
+		})
+	}
+}
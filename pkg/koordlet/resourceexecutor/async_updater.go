@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// asyncUpdateWorkers is the number of workers in the shared pool of the asynchronous updates.
+	asyncUpdateWorkers = 4
+	// asyncUpdateQueueSize is the number of pending asynchronous updates. UpdateAsync blocks when the queue is full.
+	asyncUpdateQueueSize = 256
+)
+
+type asyncUpdateTask struct {
+	updater ResourceUpdater
+	errCh   chan<- error
+}
+
+var asyncUpdatePool = struct {
+	once  sync.Once
+	tasks chan asyncUpdateTask
+}{
+	tasks: make(chan asyncUpdateTask, asyncUpdateQueueSize),
+}
+
+// UpdateAsync performs the write on the shared worker pool and returns a channel which receives the result of the
+// write, e.g. for the fire-and-forget reconcile of the non-critical knobs. The last update timestamp is updated on the
+// worker after a successful write.
+func (u *CgroupResourceUpdater) UpdateAsync() <-chan error {
+	return updateAsync(u)
+}
+
+// UpdateAsync performs the write on the shared worker pool and returns a channel which receives the result of the
+// write. The last update timestamp is updated on the worker after a successful write.
+func (u *DefaultResourceUpdater) UpdateAsync() <-chan error {
+	return updateAsync(u)
+}
+
+func updateAsync(updater ResourceUpdater) <-chan error {
+	asyncUpdatePool.once.Do(func() {
+		for i := 0; i < asyncUpdateWorkers; i++ {
+			go runAsyncUpdateWorker()
+		}
+	})
+	errCh := make(chan error, 1)
+	asyncUpdatePool.tasks <- asyncUpdateTask{updater: updater, errCh: errCh}
+	return errCh
+}
+
+func runAsyncUpdateWorker() {
+	for task := range asyncUpdatePool.tasks {
+		err := task.updater.update()
+		if err == nil {
+			task.updater.UpdateLastUpdateTimestamp(time.Now())
+		}
+		task.errCh <- err
+		close(task.errCh)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestCgroupResourceUpdater_UpdateAsync(t *testing.T) {
+	tests := []struct {
+		name     string
+		writeErr error
+		want     string
+	}{
+		{
+			name: "receive nil on success",
+			want: "20000",
+		},
+		{
+			name:     "receive the error on failure",
+			writeErr: fmt.Errorf("expected error"),
+			want:     "10000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+				if tt.writeErr != nil {
+					return tt.writeErr
+				}
+				return CommonCgroupUpdateFunc(resource)
+			})
+
+			select {
+			case gotErr := <-c.UpdateAsync():
+				assert.Equal(t, tt.writeErr, gotErr)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the async update")
+			}
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+			assert.Equal(t, tt.writeErr == nil, !c.GetLastUpdateTimestamp().IsZero())
+		})
+	}
+}
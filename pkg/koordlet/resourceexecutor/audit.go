@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+)
+
+var auditCoalescer struct {
+	lock sync.RWMutex
+	c    *AuditCoalescer
+	// stopCh is the stop channel of the running ResourceUpdateExecutor, which stops the running coalescer.
+	stopCh <-chan struct{}
+	// replacedCh is closed when the running coalescer is replaced.
+	replacedCh chan struct{}
+}
+
+// SetAuditCoalescer sets the AuditCoalescer used by the updaters to record the audit events. The coalescing is
+// disabled if it is nil. If the ResourceUpdateExecutor is already running, the coalescer starts flushing the events
+// immediately, and the replaced one flushes all its events and stops.
+func SetAuditCoalescer(c *AuditCoalescer) {
+	auditCoalescer.lock.Lock()
+	defer auditCoalescer.lock.Unlock()
+	if auditCoalescer.replacedCh != nil {
+		close(auditCoalescer.replacedCh)
+		auditCoalescer.replacedCh = nil
+	}
+	auditCoalescer.c = c
+	runAuditCoalescerLocked()
+}
+
+func getAuditCoalescer() *AuditCoalescer {
+	auditCoalescer.lock.RLock()
+	defer auditCoalescer.lock.RUnlock()
+	return auditCoalescer.c
+}
+
+// runAuditCoalescer starts flushing the events of the current and the later set coalescers until the stopCh is
+// closed. It is called when the ResourceUpdateExecutor runs.
+func runAuditCoalescer(stopCh <-chan struct{}) {
+	auditCoalescer.lock.Lock()
+	defer auditCoalescer.lock.Unlock()
+	auditCoalescer.stopCh = stopCh
+	if auditCoalescer.replacedCh == nil {
+		runAuditCoalescerLocked()
+	}
+}
+
+func runAuditCoalescerLocked() {
+	c, stopCh := auditCoalescer.c, auditCoalescer.stopCh
+	if c == nil || stopCh == nil {
+		return
+	}
+	replacedCh := make(chan struct{})
+	auditCoalescer.replacedCh = replacedCh
+	doneCh := make(chan struct{})
+	go func() {
+		select {
+		case <-stopCh:
+			auditCoalescer.lock.Lock()
+			if auditCoalescer.replacedCh == replacedCh {
+				auditCoalescer.replacedCh = nil
+			}
+			auditCoalescer.lock.Unlock()
+		case <-replacedCh:
+		}
+		close(doneCh)
+	}()
+	go c.Run(doneCh)
+}
+
+// auditDiffLogLevel is the log level from which the common updates read the old values before the writes for the
+// audit diffs. The merge updates always attach the diffs since they read the old values anyway.
+var auditDiffLogLevel klog.Level = 5
+
+// auditUpdate records the audit event of a resource update. It uses the given event helper if it is not nil,
+// otherwise it records a default event with the reason. The tags are appended to the message if not empty.
+func auditUpdate(e *audit.EventHelper, reason string, path, value string, tags map[string]string) {
+	auditUpdateDiff(e, reason, path, nil, value, tags)
+}
+
+// auditUpdateVia records the audit event of a resource update like auditUpdate, whose default message notes the
+// update is delegated to another writer, e.g. "via runtime hook".
+func auditUpdateVia(e *audit.EventHelper, reason string, path, value, via string, tags map[string]string) {
+	recordAuditUpdate(e, reason, path, nil, value, via, tags)
+}
+
+// auditUpdateDiff records the audit event of a resource update like auditUpdate. If the oldValue is not nil, the
+// values before and after the update are attached to the event as the structured fields.
+func auditUpdateDiff(e *audit.EventHelper, reason string, path string, oldValue *string, value string, tags map[string]string) {
+	recordAuditUpdate(e, reason, path, oldValue, value, "", tags)
+}
+
+func recordAuditUpdate(e *audit.EventHelper, reason string, path string, oldValue *string, value, via string, tags map[string]string) {
+	do := func() error {
+		var event *audit.EventHelper
+		if e != nil {
+			tagged := *e
+			if len(tags) > 0 {
+				tagged.Event.Message = fmt.Sprintf("%s, tags: %s", e.Event.Message, formatAuditTags(tags))
+			}
+			event = &tagged
+		} else {
+			message := fmt.Sprintf("update %v to %v", path, value)
+			if via != "" {
+				message += " via " + via
+			}
+			if len(tags) > 0 {
+				message += ", tags: " + formatAuditTags(tags)
+			}
+			event = audit.V(3).Reason(reason).Message("%s", message)
+		}
+		if oldValue != nil {
+			event.Diff(*oldValue, value)
+		}
+		return event.Do()
+	}
+	if c := getAuditCoalescer(); c != nil {
+		c.Do(reason, path, oldValue, value, tags, do)
+		return
+	}
+	_ = do()
+}
+
+// formatAuditTags formats the tags into "k1=v1,k2=v2" sorted by the keys.
+func formatAuditTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// auditSummary is the summary of the coalesced audit events of a path.
+type auditSummary struct {
+	reason string
+	path   string
+	// oldValue is the value before the first write of the coalesced events, which is nil if not read.
+	oldValue *string
+	value    string
+	count    int
+	tags     map[string]string
+}
+
+type coalescedAuditEvent struct {
+	auditSummary
+	start time.Time
+	do    func() error
+}
+
+// AuditCoalescer suppresses the duplicate audit events of the same path and value within a time window. When the
+// window expires, it records the original event if there is only one write, otherwise a summary event like
+// "N writes of value X", which carries the tags and the diff from the value before the first write. The coalescing is
+// disabled if the window <= 0.
+type AuditCoalescer struct {
+	window time.Duration
+	lock   sync.Mutex
+	events map[string]*coalescedAuditEvent
+
+	now         func() time.Time
+	emitSummary func(s *auditSummary) error
+}
+
+func NewAuditCoalescer(window time.Duration) *AuditCoalescer {
+	return &AuditCoalescer{
+		window:      window,
+		events:      map[string]*coalescedAuditEvent{},
+		now:         time.Now,
+		emitSummary: emitAuditSummary,
+	}
+}
+
+func emitAuditSummary(s *auditSummary) error {
+	message := fmt.Sprintf("%d writes of value %v to %v", s.count, s.value, s.path)
+	if len(s.tags) > 0 {
+		message += ", tags: " + formatAuditTags(s.tags)
+	}
+	event := audit.V(3).Reason(s.reason).Message("%s", message)
+	if s.oldValue != nil {
+		event.Diff(*s.oldValue, s.value)
+	}
+	return event.Do()
+}
+
+// Do records an audit event for the path and value, where the oldValue and the tags are carried by the summary. The
+// event is delayed until the window expires or a different value of the path arrives.
+func (a *AuditCoalescer) Do(reason, path string, oldValue *string, value string, tags map[string]string, do func() error) {
+	if a.window <= 0 {
+		if err := do(); err != nil {
+			klog.V(6).Infof("failed to record audit event for %s, err: %v", path, err)
+		}
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.now()
+	e, ok := a.events[path]
+	if ok && e.value == value && now.Sub(e.start) < a.window {
+		e.count++
+		return
+	}
+	if ok {
+		a.emitLocked(path, e)
+	}
+	if oldValue != nil {
+		v := *oldValue
+		oldValue = &v
+	}
+	a.events[path] = &coalescedAuditEvent{
+		auditSummary: auditSummary{reason: reason, path: path, oldValue: oldValue, value: value, count: 1, tags: tags},
+		start:        now,
+		do:           do,
+	}
+}
+
+// Flush emits the events whose window expires. If force is true, all events are emitted.
+func (a *AuditCoalescer) Flush(force bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.now()
+	for path, e := range a.events {
+		if force || now.Sub(e.start) >= a.window {
+			a.emitLocked(path, e)
+			delete(a.events, path)
+		}
+	}
+}
+
+// Run flushes the expired events periodically until the stopCh is closed.
+func (a *AuditCoalescer) Run(stopCh <-chan struct{}) {
+	if a.window <= 0 {
+		<-stopCh
+		return
+	}
+	ticker := time.NewTicker(a.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			a.Flush(true)
+			return
+		case <-ticker.C:
+			a.Flush(false)
+		}
+	}
+}
+
+func (a *AuditCoalescer) emitLocked(path string, e *coalescedAuditEvent) {
+	var err error
+	if e.count <= 1 {
+		err = e.do()
+	} else {
+		err = a.emitSummary(&e.auditSummary)
+	}
+	if err != nil {
+		klog.V(6).Infof("failed to record audit event for %s, err: %v", path, err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestAuditCoalescer(t *testing.T) {
+	now := time.Now()
+	c := NewAuditCoalescer(time.Minute)
+	c.now = func() time.Time { return now }
+	var summaries, events []string
+	c.emitSummary = func(s *auditSummary) error {
+		summaries = append(summaries, fmt.Sprintf("%s %d writes of value %v to %v", s.reason, s.count, s.value, s.path))
+		return nil
+	}
+	doEvent := func(path, value string) func() error {
+		return func() error {
+			events = append(events, fmt.Sprintf("update %v to %v", path, value))
+			return nil
+		}
+	}
+
+	// ten identical writes produce one summary
+	for i := 0; i < 10; i++ {
+		c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", nil, "10000", nil, doEvent("/cpu.cfs_quota_us", "10000"))
+	}
+	// a single write produces the original event
+	c.Do(ReasonUpdateCgroups, "/cpu.shares", nil, "1024", nil, doEvent("/cpu.shares", "1024"))
+	c.Flush(false)
+	assert.Empty(t, summaries)
+	assert.Empty(t, events)
+
+	now = now.Add(time.Minute)
+	c.Flush(false)
+	assert.Equal(t, []string{"UpdateCgroups 10 writes of value 10000 to /cpu.cfs_quota_us"}, summaries)
+	assert.Equal(t, []string{"update /cpu.shares to 1024"}, events)
+
+	// a different value flushes the previous one
+	summaries, events = nil, nil
+	c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", nil, "10000", nil, doEvent("/cpu.cfs_quota_us", "10000"))
+	c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", nil, "20000", nil, doEvent("/cpu.cfs_quota_us", "20000"))
+	assert.Equal(t, []string{"update /cpu.cfs_quota_us to 10000"}, events)
+	c.Flush(true)
+	assert.Equal(t, []string{"update /cpu.cfs_quota_us to 10000", "update /cpu.cfs_quota_us to 20000"}, events)
+	assert.Empty(t, summaries)
+}
+
+func TestAuditCoalescer_Summary(t *testing.T) {
+	oldAuditor := audit.Default
+	defer func() {
+		audit.Default = oldAuditor
+	}()
+	auditDir := t.TempDir()
+	audit.Default = audit.NewAuditor(&audit.Config{LogDir: auditDir, Verbose: 3, MaxDiskSpaceMB: 16})
+
+	// the summary carries the tags and the diff from the value before the first write
+	c := NewAuditCoalescer(time.Minute)
+	oldValue := "10000"
+	tags := map[string]string{"pod": "test-pod"}
+	var events int
+	for i := 0; i < 3; i++ {
+		c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", &oldValue, "20000", tags, func() error {
+			events++
+			return nil
+		})
+		oldValue = "20000"
+	}
+	c.Flush(true)
+	assert.Equal(t, 0, events)
+	assert.NoError(t, audit.Default.LoggerWriter().Flush())
+	iter := audit.NewEventReader(auditDir).NewReverseInterator()
+	defer iter.Close()
+	event, err := iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, ReasonUpdateCgroups, event.Reason)
+	assert.Equal(t, "3 writes of value 20000 to /cpu.cfs_quota_us, tags: pod=test-pod", event.Message)
+	assert.Equal(t, "10000", event.OldValue)
+	assert.Equal(t, "20000", event.NewValue)
+
+	// the coalescing is disabled with a non-positive window
+	c = NewAuditCoalescer(0)
+	c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", nil, "20000", nil, func() error {
+		events++
+		return nil
+	})
+	assert.Equal(t, 1, events)
+	stopCh := make(chan struct{})
+	close(stopCh)
+	c.Run(stopCh)
+}
+
+func TestAuditUpdateDiff(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	oldAuditor := audit.Default
+	defer func() {
+		audit.Default = oldAuditor
+	}()
+	auditDir := t.TempDir()
+	audit.Default = audit.NewAuditor(&audit.Config{LogDir: auditDir, Verbose: 3, MaxDiskSpaceMB: 16})
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-1")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+
+	// the merge update always carries the diff
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "2-3", nil)
+	assert.NoError(t, err)
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+	// the common update carries the diff only if the log level is enabled
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	oldLevel := auditDiffLogLevel
+	auditDiffLogLevel = klog.Level(0)
+	defer func() {
+		auditDiffLogLevel = oldLevel
+	}()
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "2048", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	assert.NoError(t, audit.Default.LoggerWriter().Flush())
+
+	iter := audit.NewEventReader(auditDir).NewReverseInterator()
+	defer iter.Close()
+	event, err := iter.Next()
+	assert.NoError(t, err)
+	assert.Contains(t, event.Message, "cpu.shares to 2048")
+	assert.Equal(t, "1024", event.OldValue)
+	assert.Equal(t, "2048", event.NewValue)
+	event, err = iter.Next()
+	assert.NoError(t, err)
+	assert.Contains(t, event.Message, "cpu.cfs_quota_us to 20000")
+	assert.Empty(t, event.OldValue)
+	assert.Empty(t, event.NewValue)
+	event, err = iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, ReasonUpdateCgroups, event.Reason)
+	assert.Contains(t, event.Message, "cpuset.cpus to 0-3")
+	assert.Equal(t, "0-1", event.OldValue)
+	assert.Equal(t, "0-3", event.NewValue)
+}
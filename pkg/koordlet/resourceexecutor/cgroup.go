@@ -42,40 +42,49 @@ const ErrCgroupDir = "cgroup path or file not exist"
 
 // CgroupFileWriteIfDifferent writes the cgroup file if current value is different from the given value.
 func cgroupFileWriteIfDifferent(cgroupTaskDir string, r sysutil.Resource, value string) (bool, error) {
-	if supported, msg := r.IsSupported(cgroupTaskDir); !supported {
-		return false, sysutil.ResourceUnsupportedErr(fmt.Sprintf("write cgroup %s failed, msg: %s", r.ResourceType(), msg))
-	}
-	if valid, msg := r.IsValid(value); !valid {
-		return false, fmt.Errorf("write cgroup %s failed, value[%v] not valid, msg: %s", r.ResourceType(), value, msg)
-	}
-	if exist, msg := IsCgroupPathExist(cgroupTaskDir, r); !exist {
-		return false, ResourceCgroupDirErr(fmt.Sprintf("write cgroup %s failed, msg: %s", r.ResourceType(), msg))
+	return cgroupFileWriteIfDifferentBy(cgroupTaskDir, r, value, cgroupFileWrite)
+}
+
+// cgroupFileWriteIfDifferentBy writes the cgroup file by the given write func if current value is different from the
+// given value.
+func cgroupFileWriteIfDifferentBy(cgroupTaskDir string, r sysutil.Resource, value string, write cgroupWriteFunc) (bool, error) {
+	if err := checkCgroupFileWrite(cgroupTaskDir, r, value); err != nil {
+		return false, err
 	}
 
 	currentValue, currentErr := cgroupFileRead(cgroupTaskDir, r)
 	if currentErr != nil {
 		return false, currentErr
 	}
-	// FIXME(saintube): Instead of handling cpuset resource in writing function, we should use a updater and do
-	//  MergeUpdate in resourceexecutor's LeveledUpdateBatch.
-	if r.ResourceType() == sysutil.CPUSetCPUSName && cpuset.IsEqualStrCpus(currentValue, value) {
-		return false, nil
-	}
-	if value == currentValue || value == CgroupMaxValueStr && currentValue == CgroupMaxSymbolStr {
-		// compatible with cgroup valued "max"
-		klog.V(6).Infof("read before write %s and got str value, considered as MaxInt64", r.Path(cgroupTaskDir))
+	if isCgroupValueEqual(r, currentValue, value) {
+		klog.V(6).Infof("read before write %s and got the same value, skip the write", r.Path(cgroupTaskDir))
 		return false, nil
 	}
-	if err := cgroupFileWrite(cgroupTaskDir, r, value); err != nil {
+	if err := write(cgroupTaskDir, r, value); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-// CgroupFileWrite writes the cgroup file with the given value.
-func cgroupFileWrite(cgroupTaskDir string, r sysutil.Resource, value string) error {
+// isCgroupValueEqual checks if the current value of the cgroup file is equal to the given value.
+func isCgroupValueEqual(r sysutil.Resource, currentValue, value string) bool {
+	// FIXME(saintube): Instead of handling cpuset resource in writing function, we should use a updater and do
+	//  MergeUpdate in resourceexecutor's LeveledUpdateBatch.
+	if r.ResourceType() == sysutil.CPUSetCPUSName && cpuset.IsEqualStrCpus(currentValue, value) {
+		return true
+	}
+	// compatible with cgroup valued "max"
+	return value == currentValue || value == CgroupMaxValueStr && currentValue == CgroupMaxSymbolStr
+}
+
+// cgroupWriteFunc writes the cgroup file with the given value.
+type cgroupWriteFunc func(cgroupTaskDir string, r sysutil.Resource, value string) error
+
+// checkCgroupFileWrite checks whether the cgroup file is supported, existing and the value is valid before writing.
+func checkCgroupFileWrite(cgroupTaskDir string, r sysutil.Resource, value string) error {
 	if supported, msg := r.IsSupported(cgroupTaskDir); !supported {
-		return sysutil.ResourceUnsupportedErr(fmt.Sprintf("write cgroup %s failed, msg: %s", r.ResourceType(), msg))
+		return sysutil.NewUnsupportedResourceErr(r.ResourceType(), sysutil.GetCgroupVersionOf(r),
+			fmt.Sprintf("write cgroup %s failed, msg: %s", r.ResourceType(), msg))
 	}
 	if valid, msg := r.IsValid(value); !valid {
 		return fmt.Errorf("write cgroup %s failed, value[%v] not valid, msg: %s", r.ResourceType(), value, msg)
@@ -83,6 +92,14 @@ func cgroupFileWrite(cgroupTaskDir string, r sysutil.Resource, value string) err
 	if exist, msg := IsCgroupPathExist(cgroupTaskDir, r); !exist {
 		return ResourceCgroupDirErr(fmt.Sprintf("write cgroup %s failed, msg: %s", r.ResourceType(), msg))
 	}
+	return nil
+}
+
+// CgroupFileWrite writes the cgroup file with the given value.
+func cgroupFileWrite(cgroupTaskDir string, r sysutil.Resource, value string) error {
+	if err := checkCgroupFileWrite(cgroupTaskDir, r, value); err != nil {
+		return err
+	}
 
 	filePath := r.Path(cgroupTaskDir)
 	klog.V(5).Infof("write %s [%s]", filePath, value)
@@ -93,7 +110,8 @@ func cgroupFileWrite(cgroupTaskDir string, r sysutil.Resource, value string) err
 // CgroupFileReadInt reads the cgroup file and returns an int64 value.
 func cgroupFileReadInt(cgroupTaskDir string, r sysutil.Resource) (*int64, error) {
 	if supported, msg := r.IsSupported(cgroupTaskDir); !supported {
-		return nil, sysutil.ResourceUnsupportedErr(fmt.Sprintf("read cgroup %s failed, msg: %s", r.ResourceType(), msg))
+		return nil, sysutil.NewUnsupportedResourceErr(r.ResourceType(), sysutil.GetCgroupVersionOf(r),
+			fmt.Sprintf("read cgroup %s failed, msg: %s", r.ResourceType(), msg))
 	}
 
 	dataStr, err := cgroupFileRead(cgroupTaskDir, r)
@@ -119,7 +137,8 @@ func cgroupFileReadInt(cgroupTaskDir string, r sysutil.Resource) (*int64, error)
 // CgroupFileRead reads the cgroup file.
 func cgroupFileRead(cgroupTaskDir string, r sysutil.Resource) (string, error) {
 	if supported, msg := r.IsSupported(cgroupTaskDir); !supported {
-		return "", sysutil.ResourceUnsupportedErr(fmt.Sprintf("read cgroup %s failed, msg: %s", r.ResourceType(), msg))
+		return "", sysutil.NewUnsupportedResourceErr(r.ResourceType(), sysutil.GetCgroupVersionOf(r),
+			fmt.Sprintf("read cgroup %s failed, msg: %s", r.ResourceType(), msg))
 	}
 	if exist, msg := IsCgroupPathExist(cgroupTaskDir, r); !exist {
 		return "", ResourceCgroupDirErr(fmt.Sprintf("read cgroup %s failed, msg: %s", r.ResourceType(), msg))
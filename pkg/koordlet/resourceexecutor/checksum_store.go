@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// ChecksumStore stores the checksums of the last-written values keyed by the file paths. It is a cheaper alternative
+// to reading back the files for skipping the unchanged writes, while the entry should be invalidated once the file is
+// detected changed externally, e.g. by a watcher or a periodic resync.
+type ChecksumStore struct {
+	lock sync.RWMutex
+	sums map[string]uint64
+}
+
+func NewChecksumStore() *ChecksumStore {
+	return &ChecksumStore{sums: map[string]uint64{}}
+}
+
+// Matches returns whether the checksum of the value matches the stored one of the path.
+func (s *ChecksumStore) Matches(path string, value string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	sum, ok := s.sums[path]
+	return ok && sum == checksum(value)
+}
+
+// Set stores the checksum of the value written into the path.
+func (s *ChecksumStore) Set(path string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sums[path] = checksum(value)
+}
+
+// Invalidate drops the checksum of the path, so the next write of the path is not skipped.
+func (s *ChecksumStore) Invalidate(path string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sums, path)
+}
+
+// InvalidateAll drops all the checksums.
+func (s *ChecksumStore) InvalidateAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sums = map[string]uint64{}
+}
+
+func checksum(value string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(value))
+	return h.Sum64()
+}
@@ -33,6 +33,8 @@ var Conf = NewDefaultConfig()
 
 type Config struct {
 	ResourceForceUpdateSeconds int
+	// WriteHistorySize is the number of the recent writes kept in memory for debugging. It is disabled if zero.
+	WriteHistorySize int
 }
 
 func NewDefaultConfig() *Config {
@@ -43,4 +45,5 @@ func NewDefaultConfig() *Config {
 
 func (c *Config) InitFlags(fs *flag.FlagSet) {
 	fs.IntVar(&c.ResourceForceUpdateSeconds, "resource-force-update-seconds", c.ResourceForceUpdateSeconds, "executor force update resources interval by seconds")
+	fs.IntVar(&c.WriteHistorySize, "resource-write-history-size", c.WriteHistorySize, "the number of the recent resource writes kept in memory for debugging, disabled if zero")
 }
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+const (
+	ControllerCPUSet = "cpuset"
+	ControllerCPU    = "cpu"
+	ControllerMemory = "memory"
+	ControllerIO     = "io"
+)
+
+var controllerOrder = struct {
+	lock  sync.RWMutex
+	order map[string]int
+}{
+	order: newControllerOrderTable(ControllerCPUSet, ControllerCPU, ControllerMemory, ControllerIO),
+}
+
+func newControllerOrderTable(controllers ...string) map[string]int {
+	order := make(map[string]int, len(controllers))
+	for i, controller := range controllers {
+		order[controller] = i
+	}
+	return order
+}
+
+// SetControllerOrder sets the order of the controllers used by SortByControllerOrder.
+// The default order is cpuset, cpu, memory, io.
+func SetControllerOrder(controllers ...string) {
+	controllerOrder.lock.Lock()
+	defer controllerOrder.lock.Unlock()
+	controllerOrder.order = newControllerOrderTable(controllers...)
+}
+
+// ControllerOf returns the cgroup controller of the registered resource type according to its cgroups-v1 subsystem
+// in the registry, e.g. "cpu" for `cpu.shares`, "cpuset" for `cpuset.cpus`, "io" for `blkio.weight`. For the
+// cgroups-v2 only resources, the controller is derived from the filename prefix.
+func ControllerOf(resourceType sysutil.ResourceType) (string, bool) {
+	if r, ok := sysutil.DefaultRegistry.Get(sysutil.CgroupVersionV1, resourceType); ok {
+		if c, ok := r.(*sysutil.CgroupResource); ok && c.Subfs != "" {
+			return normalizeController(strings.TrimSuffix(c.Subfs, "/")), true
+		}
+	}
+	if r, ok := sysutil.DefaultRegistry.Get(sysutil.CgroupVersionV2, resourceType); ok {
+		if c, ok := r.(*sysutil.CgroupResource); ok {
+			if controller := controllerOfFilename(c.FileName); controller != "" {
+				return controller, true
+			}
+		}
+	}
+	return "", false
+}
+
+// GetController returns the cgroup controller of the resource type. For the unregistered resource types, e.g. the
+// file path of a DefaultResourceUpdater, the controller is derived from the filename prefix. It returns an empty
+// string for the resources not belonging to a controller.
+func GetController(resourceType sysutil.ResourceType) string {
+	if controller, ok := ControllerOf(resourceType); ok {
+		return controller
+	}
+	return controllerOfFilename(filepath.Base(string(resourceType)))
+}
+
+func controllerOfFilename(name string) string {
+	idx := strings.Index(name, ".")
+	if idx <= 0 {
+		return ""
+	}
+	controller := name[:idx]
+	if controller == "cgroup" { // core interface files
+		return ""
+	}
+	return normalizeController(controller)
+}
+
+func normalizeController(controller string) string {
+	switch controller {
+	case "blkio":
+		return ControllerIO
+	case "cpuacct":
+		return ControllerCPU
+	}
+	return controller
+}
+
+// SortByControllerOrder returns a copy of the updaters sorted by the controller order, e.g. writing cpuset before cpu
+// to avoid the transient inconsistencies. The updaters of the unknown controllers are placed at the end, and the
+// original order is kept for the updaters of the same controller.
+func SortByControllerOrder(updaters []ResourceUpdater) []ResourceUpdater {
+	controllerOrder.lock.RLock()
+	order := controllerOrder.order
+	controllerOrder.lock.RUnlock()
+
+	rank := func(u ResourceUpdater) int {
+		if i, ok := order[GetController(u.ResourceType())]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sorted := make([]ResourceUpdater, len(updaters))
+	copy(sorted, updaters)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
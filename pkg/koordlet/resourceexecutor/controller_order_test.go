@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestSortByControllerOrder(t *testing.T) {
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	newUpdater := func(resourceType sysutil.ResourceType, value string) ResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u
+	}
+	shuffled := []ResourceUpdater{
+		newUpdater(sysutil.MemoryLimitName, "1048576"),
+		newUpdater(sysutil.CPUTasksName, "1"),
+		newUpdater(sysutil.BlkioIOWeightName, "100"),
+		newUpdater(sysutil.CPUCFSQuotaName, "10000"),
+		newUpdater(sysutil.MemoryMinName, "0"),
+		newUpdater(sysutil.CPUSetCPUSName, "0-1"),
+		newUpdater(sysutil.CPUSharesName, "1024"),
+	}
+	got := SortByControllerOrder(shuffled)
+	var gotTypes []sysutil.ResourceType
+	for _, u := range got {
+		gotTypes = append(gotTypes, u.ResourceType())
+	}
+	assert.Equal(t, []sysutil.ResourceType{
+		sysutil.CPUSetCPUSName,
+		sysutil.CPUTasksName,
+		sysutil.CPUCFSQuotaName,
+		sysutil.CPUSharesName,
+		sysutil.MemoryLimitName,
+		sysutil.MemoryMinName,
+		sysutil.BlkioIOWeightName,
+	}, gotTypes)
+	assert.Equal(t, sysutil.ResourceType(sysutil.MemoryLimitName), shuffled[0].ResourceType(), "the input should not be modified")
+
+	SetControllerOrder(ControllerMemory, ControllerCPU)
+	defer SetControllerOrder(ControllerCPUSet, ControllerCPU, ControllerMemory, ControllerIO)
+	got = SortByControllerOrder(shuffled[:4])
+	gotTypes = nil
+	for _, u := range got {
+		gotTypes = append(gotTypes, u.ResourceType())
+	}
+	assert.Equal(t, []sysutil.ResourceType{
+		sysutil.MemoryLimitName,
+		sysutil.CPUTasksName,
+		sysutil.CPUCFSQuotaName,
+		sysutil.BlkioIOWeightName,
+	}, gotTypes)
+}
+
+func TestGetController(t *testing.T) {
+	assert.Equal(t, ControllerCPUSet, GetController(sysutil.CPUSetCPUSName))
+	assert.Equal(t, ControllerCPU, GetController(sysutil.CPUBurstName))
+	assert.Equal(t, ControllerCPU, GetController(sysutil.CPUAcctUsageName))
+	assert.Equal(t, ControllerMemory, GetController(sysutil.MemoryHighName))
+	assert.Equal(t, ControllerIO, GetController(sysutil.BlkioTRIopsName))
+	assert.Equal(t, "pids", GetController(sysutil.PidsMaxName))
+	assert.Equal(t, ControllerCPU, GetController(sysutil.CPUTasksName))
+	assert.Equal(t, ControllerMemory, GetController("/sys/fs/cgroup/memory/memory.high"))
+	assert.Equal(t, "", GetController("/proc/sys/vm/min_free_kbytes"))
+}
+
+func TestControllerOf(t *testing.T) {
+	tests := []struct {
+		resourceType sysutil.ResourceType
+		want         string
+		wantOK       bool
+	}{
+		{resourceType: sysutil.CPUSharesName, want: ControllerCPU, wantOK: true},
+		{resourceType: sysutil.CPUSetCPUSName, want: ControllerCPUSet, wantOK: true},
+		{resourceType: sysutil.CPUAcctUsageName, want: ControllerCPU, wantOK: true},
+		{resourceType: sysutil.MemoryHighName, want: ControllerMemory, wantOK: true},
+		{resourceType: sysutil.BlkioWeightName, want: ControllerIO, wantOK: true},
+		{resourceType: sysutil.PidsMaxName, want: "pids", wantOK: true},
+		{resourceType: sysutil.CPUWeightNiceName, want: ControllerCPU, wantOK: true},
+		{resourceType: sysutil.CgroupTypeName, want: "", wantOK: false},
+		{resourceType: "unknown.resource", want: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.resourceType), func(t *testing.T) {
+			got, gotOK := ControllerOf(tt.resourceType)
+			assert.Equal(t, tt.wantOK, gotOK)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+	"github.com/koordinator-sh/koordinator/pkg/util/cpuset"
+)
+
+// HandoffCPUs moves the cpus from the `cpuset.cpus` of the from cgroup to the to cgroup, e.g. the exclusive cpus
+// handed off between the pods. The cpus are reserved in the to cgroup first and verified by reading back, and then
+// removed from the from cgroup, so there is no window where no cgroup owns the cpus. If any step fails, the to cgroup
+// is rolled back to its previous cpuset. The values of the from and the to updaters are ignored.
+func HandoffCPUs(from, to ResourceUpdater, cpus cpuset.CPUSet) error {
+	fromUpdater, err := asCPUSetUpdater(from)
+	if err != nil {
+		return err
+	}
+	toUpdater, err := asCPUSetUpdater(to)
+	if err != nil {
+		return err
+	}
+	fromCPUs, err := fromUpdater.ReadCurrentCPUSet()
+	if err != nil {
+		return fmt.Errorf("failed to read cpuset of %s, err: %w", fromUpdater.Path(), err)
+	}
+	toCPUs, err := toUpdater.ReadCurrentCPUSet()
+	if err != nil {
+		return fmt.Errorf("failed to read cpuset of %s, err: %w", toUpdater.Path(), err)
+	}
+
+	// reserve the cpus in the to cgroup
+	reserved := toCPUs.Union(cpus)
+	previous := cloneCgroupUpdaterWithValue(toUpdater, toCPUs.String(), toUpdater.eventHelper)
+	if toCPUs.IsEmpty() { // e.g. a new cgroup whose cpuset is not initialized
+		previous = previous.WithEmptyCPUSetAllowed()
+	}
+	if err = cloneCgroupUpdaterWithValue(toUpdater, reserved.String(), toUpdater.eventHelper).update(); err != nil {
+		return handoffRollback(previous, fmt.Errorf("failed to reserve cpus %s in %s, err: %w", cpus, toUpdater.Path(), err))
+	}
+	current, err := toUpdater.ReadCurrentCPUSet()
+	if err != nil {
+		return handoffRollback(previous, fmt.Errorf("failed to verify cpuset of %s, err: %w", toUpdater.Path(), err))
+	}
+	if !cpus.IsSubsetOf(current) {
+		return handoffRollback(previous, fmt.Errorf("failed to verify cpuset of %s, expect %s reserved, current %s",
+			toUpdater.Path(), cpus, current))
+	}
+
+	// release the cpus from the from cgroup
+	released := fromCPUs.Difference(cpus)
+	if err = cloneCgroupUpdaterWithValue(fromUpdater, released.String(), fromUpdater.eventHelper).update(); err != nil {
+		return handoffRollback(previous, fmt.Errorf("failed to release cpus %s from %s, err: %w", cpus, fromUpdater.Path(), err))
+	}
+	klog.V(5).Infof("handed off cpus %s from %s to %s", cpus, fromUpdater.Path(), toUpdater.Path())
+	return nil
+}
+
+func asCPUSetUpdater(u ResourceUpdater) (*CgroupResourceUpdater, error) {
+	c, ok := u.(*CgroupResourceUpdater)
+	if !ok || c.ResourceType() != sysutil.CPUSetCPUSName {
+		return nil, fmt.Errorf("updater %T of %s is not a cgroup updater of %s", u, u.ResourceType(), sysutil.CPUSetCPUSName)
+	}
+	return c, nil
+}
+
+func handoffRollback(previous ResourceUpdater, err error) error {
+	return utilerrors.NewAggregate([]error{err, rollbackUpdaters([]ResourceUpdater{previous})})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+	"github.com/koordinator-sh/koordinator/pkg/util/cpuset"
+)
+
+func TestHandoffCPUs(t *testing.T) {
+	fromDir := "/kubepods.slice/kubepods-pod1.slice"
+	toDir := "/kubepods.slice/kubepods-pod2.slice"
+	newUpdater := func(parentDir string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "", nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater)
+	}
+	// revertingUpdateFunc simulates another writer which reverts the reservation
+	revertingUpdateFunc := func(resource ResourceUpdater) error {
+		c := resource.(*CgroupResourceUpdater)
+		if c.value != "4-5" {
+			return cgroupFileWrite(c.parentDir, c.file, "4")
+		}
+		return CgroupUpdateCPUSetFunc(resource)
+	}
+
+	tests := []struct {
+		name     string
+		fromCPUs string
+		toCPUs   string
+		cpus     cpuset.CPUSet
+		reverted bool
+		wantErr  bool
+		wantFrom string
+		wantTo   string
+	}{
+		{
+			name:     "handoff successfully",
+			fromCPUs: "0-3",
+			toCPUs:   "4-5",
+			cpus:     cpuset.NewCPUSet(2, 3),
+			wantFrom: "0-1",
+			wantTo:   "2-5",
+		},
+		{
+			name:     "roll back when the verify fails",
+			fromCPUs: "0-3",
+			toCPUs:   "4-5",
+			cpus:     cpuset.NewCPUSet(2, 3),
+			reverted: true,
+			wantErr:  true,
+			wantFrom: "0-3",
+			wantTo:   "4-5",
+		},
+		{
+			name:     "roll back when the release fails",
+			fromCPUs: "2-3",
+			toCPUs:   "4-5",
+			cpus:     cpuset.NewCPUSet(2, 3),
+			wantErr:  true,
+			wantFrom: "2-3",
+			wantTo:   "4-5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.WriteCgroupFileContents(fromDir, sysutil.CPUSet, tt.fromCPUs)
+			helper.WriteCgroupFileContents(toDir, sysutil.CPUSet, tt.toCPUs)
+			to := newUpdater(toDir)
+			if tt.reverted {
+				to.WithUpdateFunc(revertingUpdateFunc)
+			}
+
+			err := HandoffCPUs(newUpdater(fromDir), to, tt.cpus)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantFrom, helper.ReadCgroupFileContents(fromDir, sysutil.CPUSet))
+			assert.Equal(t, tt.wantTo, helper.ReadCgroupFileContents(toDir, sysutil.CPUSet))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+type debouncedUpdate struct {
+	updater    ResourceUpdater
+	lastSubmit time.Time
+}
+
+// DebounceExecutor collapses the bursts of updates to the same resource. When the updaters of the same key are
+// submitted within the window, only the last one is written after the key quiesces for the window. The debounce is
+// disabled if the window <= 0, where the updaters are written immediately on submit.
+type DebounceExecutor struct {
+	window  time.Duration
+	lock    sync.Mutex
+	pending map[string]*debouncedUpdate
+
+	now func() time.Time
+}
+
+func NewDebounceExecutor(window time.Duration) *DebounceExecutor {
+	return &DebounceExecutor{
+		window:  window,
+		pending: map[string]*debouncedUpdate{},
+		now:     time.Now,
+	}
+}
+
+// Submit adds the updater to write after the window quiesces. It replaces the pending updater of the same CacheKey.
+func (d *DebounceExecutor) Submit(updater ResourceUpdater) {
+	if d.window <= 0 {
+		d.update(updater, d.now())
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.pending[CacheKey(updater)] = &debouncedUpdate{
+		updater:    updater,
+		lastSubmit: d.now(),
+	}
+}
+
+// Run flushes the quiesced updaters periodically until the stopCh is closed. The pending updaters are all flushed
+// before it returns.
+func (d *DebounceExecutor) Run(stopCh <-chan struct{}) {
+	if d.window <= 0 {
+		<-stopCh
+		klog.V(4).Info("DebounceExecutor stopped")
+		return
+	}
+	interval := d.window / 2
+	if interval <= 0 {
+		interval = d.window
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			d.flush(true)
+			klog.V(4).Info("DebounceExecutor stopped, pending updates are flushed")
+			return
+		case <-ticker.C:
+			d.flush(false)
+		}
+	}
+}
+
+func (d *DebounceExecutor) flush(force bool) {
+	d.lock.Lock()
+	now := d.now()
+	var updaters []ResourceUpdater
+	for key, p := range d.pending {
+		if force || now.Sub(p.lastSubmit) >= d.window {
+			updaters = append(updaters, p.updater)
+			delete(d.pending, key)
+		}
+	}
+	d.lock.Unlock()
+
+	for _, updater := range updaters {
+		d.update(updater, now)
+	}
+}
+
+func (d *DebounceExecutor) update(updater ResourceUpdater, now time.Time) {
+	if err := updater.update(); err != nil {
+		klog.V(4).Infof("failed to debounce update resource %s to %v, err: %v", updater.Key(), updater.Value(), err)
+		return
+	}
+	updater.UpdateLastUpdateTimestamp(now)
+	klog.V(6).Infof("successfully debounce update resource %s to %v", updater.Key(), updater.Value())
+}
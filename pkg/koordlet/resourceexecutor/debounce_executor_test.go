@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebounceExecutor(t *testing.T) {
+	var written []string
+	newUpdater := func(key, value string) ResourceUpdater {
+		u, _ := NewCommonDefaultUpdaterWithUpdateFunc(key, key, value, func(resource ResourceUpdater) error {
+			written = append(written, resource.Key()+"="+resource.Value())
+			return nil
+		}, nil)
+		return u
+	}
+
+	now := time.Now()
+	d := NewDebounceExecutor(time.Second)
+	d.now = func() time.Time { return now }
+
+	// rapid submissions within the window only write the final value
+	for i := 1; i <= 5; i++ {
+		d.Submit(newUpdater("/test_file_a", strconv.Itoa(i)))
+		now = now.Add(100 * time.Millisecond)
+		d.flush(false)
+	}
+	assert.Empty(t, written)
+	now = now.Add(time.Second)
+	d.flush(false)
+	assert.Equal(t, []string{"/test_file_a=5"}, written)
+
+	// shutdown flushes the pending updates
+	written = nil
+	d.Submit(newUpdater("/test_file_b", "1"))
+	stopCh := make(chan struct{})
+	close(stopCh)
+	d.Run(stopCh)
+	assert.Equal(t, []string{"/test_file_b=1"}, written)
+}
+
+func TestDebounceExecutor_NonPositiveWindow(t *testing.T) {
+	var written []string
+	u, err := NewCommonDefaultUpdaterWithUpdateFunc("/test_file_a", "/test_file_a", "1", func(resource ResourceUpdater) error {
+		written = append(written, resource.Key()+"="+resource.Value())
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+
+	d := NewDebounceExecutor(0)
+	// the updater is written immediately without the debounce
+	d.Submit(u)
+	assert.Equal(t, []string{"/test_file_a=1"}, written)
+	// run does not panic
+	stopCh := make(chan struct{})
+	close(stopCh)
+	d.Run(stopCh)
+	assert.Equal(t, []string{"/test_file_a=1"}, written)
+}
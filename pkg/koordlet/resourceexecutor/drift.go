@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// DriftEntry is a resource whose on-disk value differs from the desired value of its updater, e.g. it is modified by
+// an external writer after the apply.
+type DriftEntry struct {
+	Updater ResourceUpdater
+	// Current is the on-disk value when detected.
+	Current string
+}
+
+// DetectDrift reads the current values of the updaters and returns the ones differing from the desired values. The
+// desired values are converted as written by the updateFuncs, e.g. the `cpu.shares` into the `cpu.weight` on the
+// cgroups-v2, and compared by the resource semantics with ValuesEqual, so `max` and the unlimited number are not a
+// drift.
+// The updaters failed to read are not reported as drifted, and their errors are aggregated.
+func DetectDrift(updaters []ResourceUpdater) ([]DriftEntry, error) {
+	var drifts []DriftEntry
+	var errs []error
+	for _, u := range updaters {
+		current, err := readCurrentValue(u)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		desired := desiredValue(u)
+		if ValuesEqual(u.ResourceType(), desired, current) {
+			continue
+		}
+		klog.V(5).Infof("detect drift of %s, desired %v, current %v", u.Path(), desired, current)
+		drifts = append(drifts, DriftEntry{Updater: u, Current: current})
+	}
+	return drifts, utilerrors.NewAggregate(errs)
+}
+
+// ScanDrift runs a drift scan pass over the expected updaters without writing, and records the number of the drifted
+// paths of each resource type into the ResourceDriftedPaths. The resource types of the expected updaters are recorded
+// even if no path drifts, so the gauge is reset after the drift is repaired. It helps to observe the drift before
+// enabling RepairDrift.
+func ScanDrift(expected []ResourceUpdater) ([]DriftEntry, error) {
+	drifts, err := DetectDrift(expected)
+	counts := map[sysutil.ResourceType]int{}
+	for _, u := range expected {
+		counts[u.ResourceType()] = 0
+	}
+	for _, d := range drifts {
+		counts[d.Updater.ResourceType()]++
+	}
+	for resourceType, count := range counts {
+		ResourceDriftedPaths.WithLabelValues(string(resourceType)).Set(float64(count))
+	}
+	return drifts, err
+}
+
+// RepairDrift rewrites the desired values of the drifted resources, which are converted by the updateFuncs as the
+// DetectDrift compares. It continues on failures and returns the aggregated error.
+func RepairDrift(drifts []DriftEntry) error {
+	var errs []error
+	for _, d := range drifts {
+		desired := desiredValue(d.Updater)
+		if err := d.Updater.update(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to repair drift of %s from %v to %v, err: %w",
+				d.Updater.Path(), d.Current, desired, err))
+			continue
+		}
+		klog.V(5).Infof("repair drift of %s from %v to %v", d.Updater.Path(), d.Current, desired)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// desiredValue returns the value of the updater in the form written into the file, see ConvertCgroupValue.
+func desiredValue(u ResourceUpdater) string {
+	c, ok := u.(*CgroupResourceUpdater)
+	if !ok {
+		return u.Value()
+	}
+	if desired, err := ConvertCgroupValue(c.file, c.value); err == nil {
+		return desired
+	}
+	return c.value
+}
+
+// readCurrentValue reads the current value of the resource of the updater. The cgroup resource is validated before
+// reading.
+func readCurrentValue(u ResourceUpdater) (string, error) {
+	var value string
+	var err error
+	switch c := u.(type) {
+	case *CgroupResourceUpdater:
+		if supported, msg := c.file.IsSupported(c.parentDir); !supported {
+			return "", fmt.Errorf("resource %s is not supported, msg: %s", c.Path(), msg)
+		}
+		value, err = c.ReadCurrent()
+	default:
+		value, err = sysutil.CommonFileRead(u.Path())
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s, err: %w", u.Path(), err)
+	}
+	return value, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestDetectAndRepairDrift(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	helper.WriteCgroupFileContents("/kubepods.slice", sysutil.CPUShares, "1024")
+	helper.WriteCgroupFileContents("/kubepods.slice", sysutil.CPUCFSQuota, "-1")
+	helper.WriteCgroupFileContents("/kubepods.slice/kubepods-besteffort.slice", sysutil.CPUShares, "2")
+
+	shares, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice", "1024", nil)
+	assert.NoError(t, err)
+	quota, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice", "-1", nil)
+	assert.NoError(t, err)
+	beShares, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice/kubepods-besteffort.slice", "2", nil)
+	assert.NoError(t, err)
+	missing, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice/not-exist.slice", "2", nil)
+	assert.NoError(t, err)
+	updaters := []ResourceUpdater{shares, quota, beShares}
+
+	drifts, err := DetectDrift(updaters)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// external writes
+	helper.WriteCgroupFileContents("/kubepods.slice/kubepods-besteffort.slice", sysutil.CPUShares, "1024")
+
+	drifts, err = DetectDrift(append(updaters, missing))
+	assert.Error(t, err)
+	assert.Equal(t, []DriftEntry{{Updater: beShares, Current: "1024"}}, drifts)
+
+	assert.NoError(t, RepairDrift(drifts))
+	assert.Equal(t, "2", helper.ReadCgroupFileContents("/kubepods.slice/kubepods-besteffort.slice", sysutil.CPUShares))
+	drifts, err = DetectDrift(updaters)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestDetectAndRepairDrift_CgroupsV2(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	helper.WriteCgroupFileContents("/kubepods.slice", sysutil.CPUSharesV2, "39")
+	helper.WriteCgroupFileContents("/kubepods.slice", sysutil.CPUCFSQuotaV2, "max 100000")
+	helper.WriteCgroupFileContents("/kubepods.slice/kubepods-besteffort.slice", sysutil.CPUSharesV2, "1")
+
+	shares, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice", "1024", nil)
+	assert.NoError(t, err)
+	quota, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice", "-1", nil)
+	assert.NoError(t, err)
+	beShares, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice/kubepods-besteffort.slice", "2", nil)
+	assert.NoError(t, err)
+	updaters := []ResourceUpdater{shares, quota, beShares}
+
+	// the `cpu.weight` converted from the `cpu.shares` and the unlimited `cpu.max` are not drifted
+	drifts, err := DetectDrift(updaters)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// external writes
+	helper.WriteCgroupFileContents("/kubepods.slice/kubepods-besteffort.slice", sysutil.CPUSharesV2, "100")
+
+	drifts, err = DetectDrift(updaters)
+	assert.NoError(t, err)
+	assert.Equal(t, []DriftEntry{{Updater: beShares, Current: "100"}}, drifts)
+
+	assert.NoError(t, RepairDrift(drifts))
+	assert.Equal(t, "1", helper.ReadCgroupFileContents("/kubepods.slice/kubepods-besteffort.slice", sysutil.CPUSharesV2))
+	drifts, err = DetectDrift(updaters)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestScanDrift(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDirs := []string{
+		"/kubepods.slice/kubepods-pod1.slice",
+		"/kubepods.slice/kubepods-pod2.slice",
+		"/kubepods.slice/kubepods-pod3.slice",
+	}
+	var expected []ResourceUpdater
+	for _, parentDir := range parentDirs {
+		helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+		helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimit, "1048576")
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+		assert.NoError(t, err)
+		expected = append(expected, u)
+		u, err = DefaultCgroupUpdaterFactory.New(sysutil.MemoryLimitName, parentDir, "1048576", nil)
+		assert.NoError(t, err)
+		expected = append(expected, u)
+	}
+	gotDrifted := func(resourceType sysutil.ResourceType) float64 {
+		return testutil.ToFloat64(ResourceDriftedPaths.WithLabelValues(string(resourceType)))
+	}
+
+	drifts, err := ScanDrift(expected)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+	assert.Equal(t, float64(0), gotDrifted(sysutil.CPUSharesName))
+	assert.Equal(t, float64(0), gotDrifted(sysutil.MemoryLimitName))
+
+	// external writes
+	helper.WriteCgroupFileContents(parentDirs[0], sysutil.CPUShares, "2")
+	helper.WriteCgroupFileContents(parentDirs[2], sysutil.CPUShares, "2")
+	helper.WriteCgroupFileContents(parentDirs[1], sysutil.MemoryLimit, "2097152")
+
+	drifts, err = ScanDrift(expected)
+	assert.NoError(t, err)
+	assert.Len(t, drifts, 3)
+	assert.Equal(t, float64(2), gotDrifted(sysutil.CPUSharesName))
+	assert.Equal(t, float64(1), gotDrifted(sysutil.MemoryLimitName))
+	// the scan never writes
+	assert.Equal(t, "2", helper.ReadCgroupFileContents(parentDirs[0], sysutil.CPUShares))
+
+	// reset after repaired
+	assert.NoError(t, RepairDrift(drifts))
+	_, err = ScanDrift(expected)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), gotDrifted(sysutil.CPUSharesName))
+	assert.Equal(t, float64(0), gotDrifted(sysutil.MemoryLimitName))
+}
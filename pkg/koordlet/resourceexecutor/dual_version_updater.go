@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+var _ ResourceUpdater = &DualVersionResourceUpdater{}
+
+// DualVersionResourceUpdater writes both the cgroups-v1 and cgroups-v2 representations of a resource, which keeps the
+// hierarchies consistent while a node migrates from v1 to v2 and both transiently exist. The value is converted for
+// each version by the registered update func, e.g. `cpu.shares` into `cpu.weight`. The version whose file is absent
+// is skipped, and it fails only if neither exists.
+type DualVersionResourceUpdater struct {
+	v1                  *CgroupResourceUpdater
+	v2                  *CgroupResourceUpdater
+	lastUpdateTimestamp time.Time
+}
+
+// NewDualVersionUpdater returns a DualVersionResourceUpdater of the resource type under the parent dir. The resource
+// should be registered in both versions.
+func NewDualVersionUpdater(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (*DualVersionResourceUpdater, error) {
+	u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, e)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := u.(*CgroupResourceUpdater)
+	if !ok {
+		return nil, fmt.Errorf("updater %T of %s is not a cgroup updater", u, resourceType)
+	}
+	d := &DualVersionResourceUpdater{}
+	for _, version := range []sysutil.CgroupVersion{sysutil.CgroupVersionV1, sysutil.CgroupVersionV2} {
+		r, ok := sysutil.DefaultRegistry.Get(version, resourceType)
+		if !ok {
+			return nil, sysutil.NewUnsupportedResourceErr(resourceType, version,
+				fmt.Sprintf("%s not found in cgroup registry of version %d", resourceType, version))
+		}
+		versioned := cloneCgroupUpdaterWithValue(c, value, e)
+		versioned.file = r
+		if version == sysutil.CgroupVersionV1 {
+			d.v1 = versioned
+		} else {
+			d.v2 = versioned
+		}
+	}
+	return d, nil
+}
+
+func (u *DualVersionResourceUpdater) ResourceType() sysutil.ResourceType {
+	return u.v1.ResourceType()
+}
+
+func (u *DualVersionResourceUpdater) Key() string {
+	return u.v1.Key() + "," + u.v2.Key()
+}
+
+func (u *DualVersionResourceUpdater) Path() string {
+	return u.v1.Path() + "," + u.v2.Path()
+}
+
+// Paths returns the paths of both versions.
+func (u *DualVersionResourceUpdater) Paths() []string {
+	return []string{u.v1.Path(), u.v2.Path()}
+}
+
+func (u *DualVersionResourceUpdater) Value() string {
+	return u.v1.Value()
+}
+
+func (u *DualVersionResourceUpdater) IsMergeable() bool {
+	return false
+}
+
+func (u *DualVersionResourceUpdater) MergeUpdate() (ResourceUpdater, error) {
+	return nil, u.update()
+}
+
+func (u *DualVersionResourceUpdater) Clone() ResourceUpdater {
+	return &DualVersionResourceUpdater{
+		v1:                  u.v1.Clone().(*CgroupResourceUpdater),
+		v2:                  u.v2.Clone().(*CgroupResourceUpdater),
+		lastUpdateTimestamp: u.lastUpdateTimestamp,
+	}
+}
+
+func (u *DualVersionResourceUpdater) GetLastUpdateTimestamp() time.Time {
+	return u.lastUpdateTimestamp
+}
+
+func (u *DualVersionResourceUpdater) UpdateLastUpdateTimestamp(time time.Time) {
+	u.lastUpdateTimestamp = time
+}
+
+func (u *DualVersionResourceUpdater) update() error {
+	var errs []error
+	written := 0
+	for _, c := range []*CgroupResourceUpdater{u.v1, u.v2} {
+		if !sysutil.FileExists(c.Path()) {
+			klog.V(6).Infof("skip updating %s to %v, the file is absent", c.Path(), c.Value())
+			continue
+		}
+		// clone since the update func may convert the value in place
+		if err := c.Clone().update(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update %s, err: %w", c.Path(), err))
+			continue
+		}
+		written++
+	}
+	if written == 0 && len(errs) == 0 {
+		return fmt.Errorf("neither %s nor %s exists", u.v1.Path(), u.v2.Path())
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestDualVersionResourceUpdater(t *testing.T) {
+	tests := []struct {
+		name       string
+		existsV1   bool
+		existsV2   bool
+		value      string
+		wantErr    bool
+		wantV1     string
+		wantWeight string
+	}{
+		{
+			name:       "write both versions",
+			existsV1:   true,
+			existsV2:   true,
+			value:      "2048",
+			wantV1:     "2048",
+			wantWeight: "79",
+		},
+		{
+			name:       "tolerate the v1 absent",
+			existsV2:   true,
+			value:      "2048",
+			wantWeight: "79",
+		},
+		{
+			name:     "tolerate the v2 absent",
+			existsV1: true,
+			value:    "2048",
+			wantV1:   "2048",
+		},
+		{
+			name:    "neither exists",
+			value:   "2048",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			if tt.existsV1 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+			}
+			if tt.existsV2 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "100")
+			}
+
+			u, err := NewDualVersionUpdater(sysutil.CPUSharesName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.existsV1 {
+				assert.Equal(t, tt.wantV1, helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+			}
+			if tt.existsV2 {
+				assert.Equal(t, tt.wantWeight, helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+			}
+			assert.Equal(t, tt.value, u.Value())
+		})
+	}
+}
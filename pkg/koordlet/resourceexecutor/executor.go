@@ -195,7 +195,9 @@ func (e *ResourceUpdateExecutorImpl) Run(stopCh <-chan struct{}) {
 }
 
 func (e *ResourceUpdateExecutorImpl) run(stopCh <-chan struct{}) {
+	SetWriteHistorySize(e.Config.WriteHistorySize)
 	_ = e.ResourceCache.Run(stopCh)
+	runAuditCoalescer(stopCh)
 	klog.V(4).Info("starting ResourceUpdateExecutor successfully")
 	e.gcStarted = true
 }
@@ -269,6 +271,10 @@ func (e *ResourceUpdateExecutorImpl) isUpdateErrIgnored(err error) bool {
 		klog.V(6).Infof("update resource failed, ignored cgroup not exist err: %v", err)
 		return true
 	}
+	if IsUpdateDisabledErr(err) {
+		klog.V(6).Infof("update resource failed, ignored disabled err: %v", err)
+		return true
+	}
 	return false
 }
 
@@ -17,7 +17,9 @@ limitations under the License.
 package resourceexecutor
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -46,6 +48,60 @@ func TestNewResourceUpdateExecutor_Run(t *testing.T) {
 
 		e.Run(stop)
 	})
+	t.Run("flush the coalesced audit events", func(t *testing.T) {
+		c := NewAuditCoalescer(time.Millisecond)
+		emitted := make(chan string, 1)
+		c.emitSummary = func(s *auditSummary) error {
+			emitted <- fmt.Sprintf("%d writes of value %v to %v", s.count, s.value, s.path)
+			return nil
+		}
+		SetAuditCoalescer(c)
+		defer SetAuditCoalescer(nil)
+		e := &ResourceUpdateExecutorImpl{
+			ResourceCache: cache.NewCacheDefault(),
+			Config:        NewDefaultConfig(),
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+
+		e.Run(stop)
+		for i := 0; i < 2; i++ {
+			c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", nil, "10000", nil, func() error { return nil })
+		}
+		select {
+		case got := <-emitted:
+			assert.Equal(t, "2 writes of value 10000 to /cpu.cfs_quota_us", got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("coalesced audit events are not flushed")
+		}
+	})
+	t.Run("flush the audit events of the coalescer set after running", func(t *testing.T) {
+		e := &ResourceUpdateExecutorImpl{
+			ResourceCache: cache.NewCacheDefault(),
+			Config:        NewDefaultConfig(),
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+		e.Run(stop)
+
+		c := NewAuditCoalescer(time.Millisecond)
+		emitted := make(chan string, 1)
+		c.emitSummary = func(s *auditSummary) error {
+			emitted <- fmt.Sprintf("%d writes of value %v to %v", s.count, s.value, s.path)
+			return nil
+		}
+		SetAuditCoalescer(c)
+		defer SetAuditCoalescer(nil)
+		for i := 0; i < 2; i++ {
+			c.Do(ReasonUpdateCgroups, "/cpu.cfs_quota_us", nil, "10000", nil, func() error { return nil })
+		}
+		select {
+		case got := <-emitted:
+			assert.Equal(t, "2 writes of value 10000 to /cpu.cfs_quota_us", got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("coalesced audit events are not flushed")
+		}
+	})
 }
 
 func TestResourceUpdateExecutor_Update(t *testing.T) {
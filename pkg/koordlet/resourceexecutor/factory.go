@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+var _ ResourceUpdaterFactory = &CachingUpdaterFactory{}
+var _ ResourceUpdaterFactory = &RecordingUpdaterFactory{}
+var _ ResourceUpdaterFactory = &ContextUpdaterFactory{}
+var _ ResourceUpdaterFactory = &HybridUpdaterFactory{}
+
+type updaterCacheKey struct {
+	resourceType sysutil.ResourceType
+	parentDir    string
+}
+
+// CachingUpdaterFactory wraps a ResourceUpdaterFactory and caches the constructed updaters by the resource type and
+// the parent dir. The reconcilers can call New in each round without reconstructing the updaters, since a cache hit
+// only clones the cached updater and replaces the value.
+type CachingUpdaterFactory struct {
+	inner ResourceUpdaterFactory
+	lock  sync.RWMutex
+	cache map[updaterCacheKey]*CgroupResourceUpdater
+}
+
+func NewCachingFactory(inner ResourceUpdaterFactory) *CachingUpdaterFactory {
+	return &CachingUpdaterFactory{
+		inner: inner,
+		cache: map[updaterCacheKey]*CgroupResourceUpdater{},
+	}
+}
+
+func (f *CachingUpdaterFactory) Register(g NewResourceUpdaterFunc, resourceTypes ...sysutil.ResourceType) {
+	f.inner.Register(g, resourceTypes...)
+}
+
+func (f *CachingUpdaterFactory) New(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	key := updaterCacheKey{resourceType: resourceType, parentDir: parentDir}
+	f.lock.RLock()
+	cached, ok := f.cache[key]
+	f.lock.RUnlock()
+	if ok {
+		return cloneCgroupUpdaterWithValue(cached, value, e), nil
+	}
+
+	u, err := f.inner.New(resourceType, parentDir, value, e)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := u.(*CgroupResourceUpdater)
+	if !ok { // only the cgroup updaters are cacheable
+		return u, nil
+	}
+	f.lock.Lock()
+	f.cache[key] = c.Clone().(*CgroupResourceUpdater)
+	f.lock.Unlock()
+	return u, nil
+}
+
+// Forget drops the cached updaters of the given parent dir and its sub-directories, e.g. when a pod is deleted.
+func (f *CachingUpdaterFactory) Forget(parentDir string) {
+	dir := filepath.Clean(parentDir)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for key := range f.cache {
+		keyDir := filepath.Clean(key.parentDir)
+		if keyDir == dir || strings.HasPrefix(keyDir, dir+"/") {
+			delete(f.cache, key)
+		}
+	}
+}
+
+func cloneCgroupUpdaterWithValue(u *CgroupResourceUpdater, value string, e *audit.EventHelper) *CgroupResourceUpdater {
+	c := u.Clone().(*CgroupResourceUpdater)
+	c.value = value
+	c.eventHelper = e
+	c.lastUpdateTimestamp = time.Time{}
+	return c
+}
+
+// RecordedCall is a New call recorded by the RecordingUpdaterFactory.
+type RecordedCall struct {
+	ResourceType sysutil.ResourceType
+	ParentDir    string
+	Value        string
+}
+
+// RecordingUpdaterFactory wraps a ResourceUpdaterFactory and records every New call, which helps to assert which
+// resources a reconciler attempted to update in tests. If useFakes is true, the returned updaters never write.
+type RecordingUpdaterFactory struct {
+	inner    ResourceUpdaterFactory
+	useFakes bool
+	lock     sync.Mutex
+	calls    []RecordedCall
+}
+
+func NewRecordingFactory(inner ResourceUpdaterFactory, useFakes bool) *RecordingUpdaterFactory {
+	return &RecordingUpdaterFactory{
+		inner:    inner,
+		useFakes: useFakes,
+	}
+}
+
+func (f *RecordingUpdaterFactory) Register(g NewResourceUpdaterFunc, resourceTypes ...sysutil.ResourceType) {
+	f.inner.Register(g, resourceTypes...)
+}
+
+func (f *RecordingUpdaterFactory) New(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	f.lock.Lock()
+	f.calls = append(f.calls, RecordedCall{ResourceType: resourceType, ParentDir: parentDir, Value: value})
+	f.lock.Unlock()
+
+	u, err := f.inner.New(resourceType, parentDir, value, e)
+	if err != nil || !f.useFakes {
+		return u, err
+	}
+	switch c := u.(type) {
+	case *CgroupResourceUpdater:
+		c.WithUpdateFunc(fakeUpdateFunc).WithMergeUpdateFunc(nil)
+	case *DefaultResourceUpdater:
+		c.updateFunc = fakeUpdateFunc
+	}
+	return u, nil
+}
+
+// Calls returns a copy of the recorded New calls in order.
+func (f *RecordingUpdaterFactory) Calls() []RecordedCall {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	calls := make([]RecordedCall, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+func fakeUpdateFunc(resource ResourceUpdater) error {
+	klog.V(6).Infof("fake update resource %s to %v", resource.Key(), resource.Value())
+	return nil
+}
+
+// ContextUpdaterFactory wraps a ResourceUpdaterFactory and attaches the audit tags to all updaters it produces, so the
+// metadata of a reconcile (e.g. pod UID, QoS class) are set once instead of threading through every call.
+type ContextUpdaterFactory struct {
+	parent ResourceUpdaterFactory
+	tags   map[string]string
+}
+
+func NewContextFactory(parent ResourceUpdaterFactory, tags map[string]string) *ContextUpdaterFactory {
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return &ContextUpdaterFactory{
+		parent: parent,
+		tags:   copied,
+	}
+}
+
+func (f *ContextUpdaterFactory) Register(g NewResourceUpdaterFunc, resourceTypes ...sysutil.ResourceType) {
+	f.parent.Register(g, resourceTypes...)
+}
+
+func (f *ContextUpdaterFactory) New(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	u, err := f.parent.New(resourceType, parentDir, value, e)
+	if err != nil {
+		return nil, err
+	}
+	switch c := u.(type) {
+	case *CgroupResourceUpdater:
+		c.auditTags = f.tags
+	case *DefaultResourceUpdater:
+		c.auditTags = f.tags
+	}
+	return u, nil
+}
+
+// HybridUpdaterFactory builds the cgroup updaters on the cgroups-v1/v2 hybrid nodes, where a node may have some
+// controllers on the v2 hierarchy (e.g. cpu) but others on the v1 (e.g. blkio). Instead of relying solely on the
+// current cgroup version, it probes which version's file of the resource exists and builds the updater accordingly.
+type HybridUpdaterFactory struct {
+	inner ResourceUpdaterFactory
+}
+
+// NewHybridFactory returns a HybridUpdaterFactory wrapping the DefaultCgroupUpdaterFactory.
+func NewHybridFactory() *HybridUpdaterFactory {
+	return &HybridUpdaterFactory{inner: DefaultCgroupUpdaterFactory}
+}
+
+func (f *HybridUpdaterFactory) Register(g NewResourceUpdaterFunc, resourceTypes ...sysutil.ResourceType) {
+	f.inner.Register(g, resourceTypes...)
+}
+
+func (f *HybridUpdaterFactory) New(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	u, err := f.inner.New(resourceType, parentDir, value, e)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := u.(*CgroupResourceUpdater)
+	if !ok {
+		return u, nil
+	}
+	if r := probeCgroupResource(c.ResourceType(), parentDir, c.file); r != c.file {
+		klog.V(6).Infof("resource %s resolved to %s on hybrid node", c.ResourceType(), r.Path(parentDir))
+		c.file = r
+	}
+	return u, nil
+}
+
+// probeCgroupResource returns the resource of the version whose file exists. The given resource is preferred, and it
+// is returned if neither exists.
+func probeCgroupResource(resourceType sysutil.ResourceType, parentDir string, r sysutil.Resource) sysutil.Resource {
+	if sysutil.FileExists(r.Path(parentDir)) {
+		return r
+	}
+	otherVersion := sysutil.CgroupVersionV2
+	if sysutil.IsCgroupV2Resource(r) {
+		otherVersion = sysutil.CgroupVersionV1
+	}
+	other, ok := sysutil.DefaultRegistry.Get(otherVersion, resourceType)
+	if ok && sysutil.FileExists(other.Path(parentDir)) {
+		return other
+	}
+	return r
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestCachingUpdaterFactory(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	constructed := 0
+	inner := NewCgroupUpdaterFactory()
+	inner.Register(func(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+		constructed++
+		return NewCommonCgroupUpdater(resourceType, parentDir, value, e)
+	}, sysutil.CPUCFSQuotaName)
+	f := NewCachingFactory(inner)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	u, err := f.New(sysutil.CPUCFSQuotaName, parentDir, "10000", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10000", u.Value())
+	assert.Equal(t, 1, constructed)
+
+	u1, err := f.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "20000", u1.Value())
+	assert.Equal(t, u.Path(), u1.Path())
+	assert.Equal(t, "10000", u.Value(), "the cache hit should return a clone")
+	assert.Equal(t, 1, constructed)
+
+	// sub-directories are forgotten with the pod dir
+	_, err = f.New(sysutil.CPUCFSQuotaName, parentDir+"/cri-containerd-xxx.scope", "-1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, constructed)
+	f.Forget(parentDir)
+	_, err = f.New(sysutil.CPUCFSQuotaName, parentDir, "30000", nil)
+	assert.NoError(t, err)
+	_, err = f.New(sysutil.CPUCFSQuotaName, parentDir+"/cri-containerd-xxx.scope", "-1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, constructed)
+
+	_, err = f.New(sysutil.ResourceType("UnknownResource"), parentDir, "1", nil)
+	assert.Error(t, err)
+}
+
+func TestRecordingUpdaterFactory(t *testing.T) {
+	tests := []struct {
+		name     string
+		useFakes bool
+		want     string
+	}{
+		{
+			name:     "record and write with the real updaters",
+			useFakes: false,
+			want:     "20000",
+		},
+		{
+			name:     "record and return the fake updaters",
+			useFakes: true,
+			want:     "10000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+			f := NewRecordingFactory(DefaultCgroupUpdaterFactory, tt.useFakes)
+
+			u, err := f.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			_, err = u.MergeUpdate()
+			assert.NoError(t, err)
+			assert.NoError(t, u.update())
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+			_, err = f.New(sysutil.ResourceType("UnknownResource"), parentDir, "1", nil)
+			assert.Error(t, err)
+			assert.Equal(t, []RecordedCall{
+				{ResourceType: sysutil.CPUCFSQuotaName, ParentDir: parentDir, Value: "20000"},
+				{ResourceType: "UnknownResource", ParentDir: parentDir, Value: "1"},
+			}, f.Calls())
+		})
+	}
+}
+
+func TestContextUpdaterFactory(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	oldAuditor := audit.Default
+	defer func() {
+		audit.Default = oldAuditor
+	}()
+	auditDir := t.TempDir()
+	audit.Default = audit.NewAuditor(&audit.Config{LogDir: auditDir, Verbose: 3, MaxDiskSpaceMB: 16})
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	f := NewContextFactory(DefaultCgroupUpdaterFactory, map[string]string{"pod": "uid-1", "qos": "LS"})
+
+	u, err := f.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	u, err = f.New(sysutil.CPUSharesName, parentDir, "2048", audit.V(3).Group("pod1").Reason("test").Message("update shares"))
+	assert.NoError(t, err)
+	assert.NoError(t, u.Clone().update())
+	assert.NoError(t, audit.Default.LoggerWriter().Flush())
+
+	iter := audit.NewEventReader(auditDir).NewReverseInterator()
+	defer iter.Close()
+	event, err := iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "update shares, tags: pod=uid-1,qos=LS", event.Message)
+	event, err = iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, ReasonUpdateCgroups, event.Reason)
+	assert.Contains(t, event.Message, "cpu.cfs_quota_us to 20000, tags: pod=uid-1,qos=LS")
+}
+
+func TestHybridUpdaterFactory(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	// cpu is on the v2 hierarchy while blkio is on the v1
+	helper.WriteFileContents(sysutil.CPUSharesV2.Path(parentDir), "100")
+	helper.WriteFileContents(sysutil.BlkioWeight.Path(parentDir), "100")
+	f := NewHybridFactory()
+
+	u, err := f.New(sysutil.BlkioWeightName, parentDir, "500", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, sysutil.BlkioWeight, u.(*CgroupResourceUpdater).file)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "500", helper.ReadFileContents(sysutil.BlkioWeight.Path(parentDir)))
+
+	u, err = f.New(sysutil.CPUSharesName, parentDir, "2048", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, sysutil.CPUSharesV2, u.(*CgroupResourceUpdater).file)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "79", helper.ReadFileContents(sysutil.CPUSharesV2.Path(parentDir)))
+
+	// keep the current version if neither exists
+	u, err = f.New(sysutil.MemoryLimitName, parentDir, "1048576", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, sysutil.MemoryLimitV2, u.(*CgroupResourceUpdater).file)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// MemoryPressureLevel is the level of the memory pressure notification, which is the same as the levels of the
+// cgroups-v1 `memory.pressure_level`.
+type MemoryPressureLevel string
+
+const (
+	MemoryPressureLow      MemoryPressureLevel = "low"
+	MemoryPressureMedium   MemoryPressureLevel = "medium"
+	MemoryPressureCritical MemoryPressureLevel = "critical"
+)
+
+// memoryPressurePSITriggers are the PSI triggers of the cgroups-v2 `memory.pressure` for the levels, in the format of
+// "<some|full> <stall threshold us> <time window us>".
+var memoryPressurePSITriggers = map[MemoryPressureLevel]string{
+	MemoryPressureLow:      "some 100000 1000000",
+	MemoryPressureMedium:   "some 300000 1000000",
+	MemoryPressureCritical: "full 500000 1000000",
+}
+
+func ParseMemoryPressureLevel(level string) (MemoryPressureLevel, error) {
+	switch l := MemoryPressureLevel(level); l {
+	case MemoryPressureLow, MemoryPressureMedium, MemoryPressureCritical:
+		return l, nil
+	}
+	return "", fmt.Errorf("unknown memory pressure level %q", level)
+}
+
+// formatEventControl formats the line written into the cgroups-v1 `cgroup.event_control` to register the eventfd on
+// the `memory.pressure_level`, i.e. "<event_fd> <fd of memory.pressure_level> <level>".
+func formatEventControl(eventFD, pressureFD int, level MemoryPressureLevel) string {
+	return fmt.Sprintf("%d %d %s", eventFD, pressureFD, level)
+}
+
+// pressureEventSource is the source of the memory pressure events, e.g. an eventfd registered on the cgroup.
+type pressureEventSource interface {
+	// Wait blocks until an event arrives or the timeout expires. It returns false on the timeout.
+	Wait(timeout time.Duration) (bool, error)
+	// Close releases the fds of the source.
+	Close() error
+}
+
+// newPressureEventSource registers the event source of the memory pressure of the cgroup.
+var newPressureEventSource = newMemoryPressureEventSource
+
+// pressureEventPollInterval is the max interval to check whether the notifier is stopped.
+var pressureEventPollInterval = time.Second
+
+// MemoryPressureNotifier invokes the handler on the memory pressure events of a cgroup until it is stopped.
+type MemoryPressureNotifier struct {
+	parentDir string
+	level     MemoryPressureLevel
+	handler   func()
+	source    pressureEventSource
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// RegisterMemoryPressureNotifier registers the handler on the memory pressure of the given level in the cgroup dir,
+// e.g. "/kubepods.slice/kubepods-besteffort.slice". On cgroups-v1, it registers an eventfd via the
+// `cgroup.event_control` on the `memory.pressure_level`. On cgroups-v2, it sets up a PSI trigger on the
+// `memory.pressure`. The fds are cleaned up on Stop().
+func RegisterMemoryPressureNotifier(parentDir string, level MemoryPressureLevel, handler func()) (*MemoryPressureNotifier, error) {
+	if _, err := ParseMemoryPressureLevel(string(level)); err != nil {
+		return nil, err
+	}
+	source, err := newPressureEventSource(parentDir, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register memory pressure notifier on cgroup %s, level %s, err: %w", parentDir, level, err)
+	}
+	n := &MemoryPressureNotifier{
+		parentDir: parentDir,
+		level:     level,
+		handler:   handler,
+		source:    source,
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+func (n *MemoryPressureNotifier) run() {
+	defer close(n.doneCh)
+	defer func() {
+		if err := n.source.Close(); err != nil {
+			klog.V(4).Infof("failed to close memory pressure notifier on cgroup %s, err: %v", n.parentDir, err)
+		}
+	}()
+	for {
+		select {
+		case <-n.stopCh:
+			return
+		default:
+		}
+		ok, err := n.source.Wait(pressureEventPollInterval)
+		if err != nil {
+			klog.Warningf("memory pressure notifier on cgroup %s, level %s exits, err: %v", n.parentDir, n.level, err)
+			return
+		}
+		if ok {
+			klog.V(5).Infof("memory pressure notified on cgroup %s, level %s", n.parentDir, n.level)
+			n.handler()
+		}
+	}
+}
+
+// Stop stops the notifier and cleans up the fds. It blocks until the handler returns.
+func (n *MemoryPressureNotifier) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.stopCh)
+	})
+	<-n.doneCh
+}
@@ -0,0 +1,149 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+const (
+	memoryPressureLevelFileName = "memory.pressure_level" // cgroups-v1 only
+	cgroupEventControlFileName  = "cgroup.event_control"  // cgroups-v1 only
+	memoryPressureFileName      = "memory.pressure"       // cgroups-v2 only
+)
+
+func newMemoryPressureEventSource(parentDir string, level MemoryPressureLevel) (pressureEventSource, error) {
+	r, err := sysutil.GetCgroupResource(sysutil.MemoryStatName)
+	if err != nil {
+		return nil, err
+	}
+	cgroupDir := filepath.Dir(r.Path(parentDir))
+	if sysutil.GetCurrentCgroupVersion() == sysutil.CgroupVersionV2 {
+		return newPSITriggerSource(filepath.Join(cgroupDir, memoryPressureFileName), memoryPressurePSITriggers[level])
+	}
+	return newEventFDSource(cgroupDir, level)
+}
+
+// eventFDSource is an eventfd registered on the cgroups-v1 `memory.pressure_level`. The kernel unregisters the event
+// when the eventfd is closed.
+type eventFDSource struct {
+	eventFD      int
+	pressureFile *os.File
+}
+
+func newEventFDSource(cgroupDir string, level MemoryPressureLevel) (*eventFDSource, error) {
+	pressureFile, err := os.Open(filepath.Join(cgroupDir, memoryPressureLevelFileName))
+	if err != nil {
+		return nil, err
+	}
+	eventFD, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		_ = pressureFile.Close()
+		return nil, fmt.Errorf("failed to create eventfd, err: %w", err)
+	}
+	s := &eventFDSource{eventFD: eventFD, pressureFile: pressureFile}
+	line := formatEventControl(eventFD, int(pressureFile.Fd()), level)
+	if err = os.WriteFile(filepath.Join(cgroupDir, cgroupEventControlFileName), []byte(line), 0644); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("failed to write %s, err: %w", cgroupEventControlFileName, err)
+	}
+	return s, nil
+}
+
+func (s *eventFDSource) Wait(timeout time.Duration) (bool, error) {
+	revents, err := pollFD(s.eventFD, unix.POLLIN, timeout)
+	if err != nil || revents&unix.POLLIN == 0 {
+		return false, err
+	}
+	// read the 8-byte counter to reset the eventfd, which is always positive for a successful read
+	buf := make([]byte, 8)
+	if _, err = unix.Read(s.eventFD, buf); err != nil {
+		if errors.Is(err, unix.EAGAIN) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read eventfd, err: %w", err)
+	}
+	return true, nil
+}
+
+func (s *eventFDSource) Close() error {
+	err := unix.Close(s.eventFD)
+	if closeErr := s.pressureFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// psiTriggerSource is a PSI trigger on the cgroups-v2 `memory.pressure`. The kernel destroys the trigger when the file
+// is closed.
+type psiTriggerSource struct {
+	file *os.File
+}
+
+func newPSITriggerSource(path string, trigger string) (*psiTriggerSource, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Write([]byte(trigger)); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write PSI trigger %q, err: %w", trigger, err)
+	}
+	return &psiTriggerSource{file: file}, nil
+}
+
+func (s *psiTriggerSource) Wait(timeout time.Duration) (bool, error) {
+	revents, err := pollFD(int(s.file.Fd()), unix.POLLPRI, timeout)
+	if err != nil {
+		return false, err
+	}
+	if revents&unix.POLLERR != 0 {
+		return false, fmt.Errorf("PSI trigger on %s is destroyed", s.file.Name())
+	}
+	return revents&unix.POLLPRI != 0, nil
+}
+
+func (s *psiTriggerSource) Close() error {
+	return s.file.Close()
+}
+
+// pollFD polls the events of the fd until the timeout, and returns the returned events.
+func pollFD(fd int, events int16, timeout time.Duration) (int16, error) {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: events}}
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err != nil {
+		if errors.Is(err, unix.EINTR) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to poll fd %d, err: %w", fd, err)
+	}
+	if n <= 0 {
+		return 0, nil
+	}
+	return fds[0].Revents, nil
+}
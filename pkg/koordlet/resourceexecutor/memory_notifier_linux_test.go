@@ -0,0 +1,63 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestEventFDSource(t *testing.T) {
+	cgroupDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cgroupDir, memoryPressureLevelFileName), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(cgroupDir, cgroupEventControlFileName), nil, 0644))
+
+	s, err := newEventFDSource(cgroupDir, MemoryPressureLow)
+	assert.NoError(t, err)
+	defer s.Close()
+	content, err := os.ReadFile(filepath.Join(cgroupDir, cgroupEventControlFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d %d low", s.eventFD, s.pressureFile.Fd()), string(content))
+
+	// no event
+	ok, err := s.Wait(10 * time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	// signal the eventfd as the kernel does
+	_, err = unix.Write(s.eventFD, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	assert.NoError(t, err)
+	ok, err = s.Wait(time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// the counter is reset after the read
+	ok, err = s.Wait(10 * time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the pressure level file not exist
+	_, err = newEventFDSource(t.TempDir(), MemoryPressureLow)
+	assert.Error(t, err)
+}
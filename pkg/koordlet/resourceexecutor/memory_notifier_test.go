@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePressureEventSource struct {
+	events chan error
+	closed chan struct{}
+}
+
+func (f *fakePressureEventSource) Wait(timeout time.Duration) (bool, error) {
+	select {
+	case err := <-f.events:
+		return err == nil, err
+	case <-time.After(timeout):
+		return false, nil
+	}
+}
+
+func (f *fakePressureEventSource) Close() error {
+	close(f.closed)
+	return nil
+}
+
+func TestRegisterMemoryPressureNotifier(t *testing.T) {
+	oldNewSource, oldInterval := newPressureEventSource, pressureEventPollInterval
+	defer func() {
+		newPressureEventSource, pressureEventPollInterval = oldNewSource, oldInterval
+	}()
+	pressureEventPollInterval = 10 * time.Millisecond
+	var registered []string
+	var source *fakePressureEventSource
+	newPressureEventSource = func(parentDir string, level MemoryPressureLevel) (pressureEventSource, error) {
+		registered = append(registered, parentDir+" "+string(level))
+		source = &fakePressureEventSource{events: make(chan error), closed: make(chan struct{})}
+		return source, nil
+	}
+	parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+
+	_, err := RegisterMemoryPressureNotifier(parentDir, MemoryPressureLevel("unknown"), func() {})
+	assert.Error(t, err)
+	assert.Empty(t, registered)
+
+	// the handler is invoked on the events
+	notified := make(chan struct{}, 2)
+	n, err := RegisterMemoryPressureNotifier(parentDir, MemoryPressureMedium, func() {
+		notified <- struct{}{}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{parentDir + " medium"}, registered)
+	source.events <- nil
+	source.events <- nil
+	<-notified
+	<-notified
+	// the fds are cleaned up on Stop
+	n.Stop()
+	_, ok := <-source.closed
+	assert.False(t, ok)
+	n.Stop()
+
+	// the notifier exits on the error and cleans up
+	n, err = RegisterMemoryPressureNotifier(parentDir, MemoryPressureCritical, func() {
+		t.Error("unexpected notification")
+	})
+	assert.NoError(t, err)
+	source.events <- errors.New("cgroup removed")
+	<-source.closed
+	n.Stop()
+
+	// the registration fails
+	newPressureEventSource = func(parentDir string, level MemoryPressureLevel) (pressureEventSource, error) {
+		return nil, errors.New("file not exist")
+	}
+	_, err = RegisterMemoryPressureNotifier(parentDir, MemoryPressureLow, func() {})
+	assert.Error(t, err)
+}
+
+func TestParseMemoryPressureLevel(t *testing.T) {
+	for _, level := range []string{"low", "medium", "critical"} {
+		got, err := ParseMemoryPressureLevel(level)
+		assert.NoError(t, err)
+		assert.Equal(t, MemoryPressureLevel(level), got)
+		assert.NotEmpty(t, memoryPressurePSITriggers[got])
+	}
+	_, err := ParseMemoryPressureLevel("high")
+	assert.Error(t, err)
+	assert.Equal(t, "5 7 critical", formatEventControl(5, 7, MemoryPressureCritical))
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// NewSiblingMemoryProtectionUpdaters returns the updaters of the `memory.min` or `memory.low` for the sibling cgroups,
+// where the desired values are keyed by the parent dirs of the siblings. The protections are effectively limited by
+// the parent's, so if the desired sum exceeds the parentLimit, the values are scaled down proportionally to fit in it.
+// The updaters are returned in the order of the dirs.
+func NewSiblingMemoryProtectionUpdaters(resourceType sysutil.ResourceType, parentLimit int64, desired map[string]int64, e *audit.EventHelper) ([]ResourceUpdater, error) {
+	if resourceType != sysutil.MemoryMinName && resourceType != sysutil.MemoryLowName {
+		return nil, fmt.Errorf("resource %s is not a memory protection", resourceType)
+	}
+	if parentLimit < 0 {
+		return nil, fmt.Errorf("invalid parent limit %d", parentLimit)
+	}
+
+	dirs := make([]string, 0, len(desired))
+	sum := big.NewInt(0)
+	for dir, v := range desired {
+		if v < 0 {
+			return nil, fmt.Errorf("invalid %s value %d of %s", resourceType, v, dir)
+		}
+		dirs = append(dirs, dir)
+		sum.Add(sum, big.NewInt(v))
+	}
+	sort.Strings(dirs)
+
+	limit := big.NewInt(parentLimit)
+	oversubscribed := sum.Cmp(limit) > 0
+	if oversubscribed {
+		klog.V(5).Infof("scale down sibling %s from the sum %v to the parent limit %d", resourceType, sum, parentLimit)
+	}
+
+	updaters := make([]ResourceUpdater, 0, len(dirs))
+	for _, dir := range dirs {
+		v := desired[dir]
+		if oversubscribed { // v * limit / sum, rounded down so the scaled sum does not exceed the limit
+			v = new(big.Int).Quo(new(big.Int).Mul(big.NewInt(v), limit), sum).Int64()
+		}
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, dir, strconv.FormatInt(v, 10), e)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s updater of %s, err: %w", resourceType, dir, err)
+		}
+		updaters = append(updaters, u)
+	}
+	return updaters, nil
+}
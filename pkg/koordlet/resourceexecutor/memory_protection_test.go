@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestNewSiblingMemoryProtectionUpdaters(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType sysutil.ResourceType
+		parentLimit  int64
+		desired      map[string]int64
+		want         map[string]string
+		wantErr      bool
+	}{
+		{
+			name:         "keep the values fitting in the parent",
+			resourceType: sysutil.MemoryMinName,
+			parentLimit:  1000,
+			desired:      map[string]int64{"/kubepods/pod1": 300, "/kubepods/pod2": 700},
+			want:         map[string]string{"/kubepods/pod1": "300", "/kubepods/pod2": "700"},
+		},
+		{
+			name:         "scale the over-subscribed values to fit the parent",
+			resourceType: sysutil.MemoryLowName,
+			parentLimit:  1000,
+			desired:      map[string]int64{"/kubepods/pod1": 1000, "/kubepods/pod2": 3000, "/kubepods/pod3": 1},
+			want:         map[string]string{"/kubepods/pod1": "249", "/kubepods/pod2": "749", "/kubepods/pod3": "0"},
+		},
+		{
+			name:         "scale the large values without overflow",
+			resourceType: sysutil.MemoryMinName,
+			parentLimit:  1 << 50,
+			desired:      map[string]int64{"/kubepods/pod1": 1 << 50, "/kubepods/pod2": 1 << 50},
+			want:         map[string]string{"/kubepods/pod1": "562949953421312", "/kubepods/pod2": "562949953421312"},
+		},
+		{
+			name:         "not a memory protection",
+			resourceType: sysutil.MemoryLimitName,
+			parentLimit:  1000,
+			desired:      map[string]int64{"/kubepods/pod1": 300},
+			wantErr:      true,
+		},
+		{
+			name:         "invalid value",
+			resourceType: sysutil.MemoryMinName,
+			parentLimit:  1000,
+			desired:      map[string]int64{"/kubepods/pod1": -1},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := NewSiblingMemoryProtectionUpdaters(tt.resourceType, tt.parentLimit, tt.desired, nil)
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.wantErr {
+				return
+			}
+			gotValues := map[string]string{}
+			for _, u := range got {
+				c := u.(*CgroupResourceUpdater)
+				assert.Equal(t, tt.resourceType, c.ResourceType())
+				gotValues[c.ParentDir()] = c.Value()
+			}
+			assert.Equal(t, tt.want, gotValues)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// memoryBytesResources are the memory resources valued in bytes, which can be wrapped by the MemoryUpdater.
+var memoryBytesResources = map[sysutil.ResourceType]struct{}{
+	sysutil.MemoryLimitName: {},
+	sysutil.MemoryHighName:  {},
+	sysutil.MemoryMinName:   {},
+	sysutil.MemoryLowName:   {},
+}
+
+var _ ResourceUpdater = &MemoryUpdater{}
+
+// MemoryUpdater is a typed wrapper of the CgroupResourceUpdater for the memory resources valued in bytes, e.g.
+// `memory.limit_in_bytes` and `memory.min`. It saves the callers from formatting and parsing the raw strings, where
+// the unlimited value ("max" or "-1") is represented as -1.
+type MemoryUpdater struct {
+	*CgroupResourceUpdater
+}
+
+// NewMemoryUpdater returns a MemoryUpdater of the memory resource with the value in bytes. A negative bytes means
+// unlimited.
+func NewMemoryUpdater(resourceType sysutil.ResourceType, parentDir string, bytes int64, e *audit.EventHelper) (*MemoryUpdater, error) {
+	if _, ok := memoryBytesResources[resourceType]; !ok {
+		return nil, fmt.Errorf("resource %s is not a memory resource valued in bytes", resourceType)
+	}
+	u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, strconv.FormatInt(bytes, 10), e)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := u.(*CgroupResourceUpdater)
+	if !ok {
+		return nil, fmt.Errorf("unexpected updater type %T of resource %s", u, resourceType)
+	}
+	m := &MemoryUpdater{CgroupResourceUpdater: c}
+	return m.SetBytes(bytes), nil
+}
+
+// SetBytes sets the value in bytes. A negative bytes is formatted as the unlimited value of the cgroup version.
+func (u *MemoryUpdater) SetBytes(bytes int64) *MemoryUpdater {
+	if bytes < 0 {
+		if sysutil.IsCgroupV2Resource(u.file) {
+			u.value = sysutil.CgroupMaxSymbolStr
+		} else {
+			u.value = sysutil.CgroupUnlimitedSymbolStr
+		}
+		return u
+	}
+	u.value = strconv.FormatInt(bytes, 10)
+	return u
+}
+
+// Bytes parses the value in bytes, where the unlimited value is returned as -1.
+func (u *MemoryUpdater) Bytes() (int64, error) {
+	v := NormalizeCgroupValue(u.ResourceType(), u.value)
+	bytes, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse bytes of %s, value %q, err: %w", u.ResourceType(), u.value, err)
+	}
+	if bytes < 0 {
+		return -1, nil
+	}
+	return bytes, nil
+}
+
+func (u *MemoryUpdater) Clone() ResourceUpdater {
+	return &MemoryUpdater{CgroupResourceUpdater: u.CgroupResourceUpdater.Clone().(*CgroupResourceUpdater)}
+}
+
+// CloneWithValue returns a typed clone of the updater with the given value, whose last update timestamp is reset.
+func (u *MemoryUpdater) CloneWithValue(value string) ResourceUpdater {
+	return &MemoryUpdater{CgroupResourceUpdater: cloneCgroupUpdaterWithValue(u.CgroupResourceUpdater, value, u.eventHelper)}
+}
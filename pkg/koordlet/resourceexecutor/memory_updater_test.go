@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestMemoryUpdater(t *testing.T) {
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		resourceType sysutil.ResourceType
+		bytes        int64
+		rawValue     string
+		wantValue    string
+		wantBytes    int64
+		wantErr      bool
+		wantParseErr bool
+	}{
+		{
+			name:         "round-trip the bytes",
+			resourceType: sysutil.MemoryLimitName,
+			bytes:        1 << 30,
+			wantValue:    "1073741824",
+			wantBytes:    1 << 30,
+		},
+		{
+			name:         "format unlimited as -1 on cgroups-v1",
+			resourceType: sysutil.MemoryLimitName,
+			bytes:        -1,
+			wantValue:    "-1",
+			wantBytes:    -1,
+		},
+		{
+			name:         "format unlimited as max on cgroups-v2",
+			useCgroupsV2: true,
+			resourceType: sysutil.MemoryHighName,
+			bytes:        -100,
+			wantValue:    "max",
+			wantBytes:    -1,
+		},
+		{
+			name:         "parse the v1 unlimited value as -1",
+			resourceType: sysutil.MemoryLimitName,
+			rawValue:     CgroupMemoryUnlimitedValueStr,
+			wantValue:    CgroupMemoryUnlimitedValueStr,
+			wantBytes:    -1,
+		},
+		{
+			name:         "failed to parse the invalid value",
+			resourceType: sysutil.MemoryMinName,
+			rawValue:     "1G",
+			wantValue:    "1G",
+			wantParseErr: true,
+		},
+		{
+			name:         "not a memory resource in bytes",
+			resourceType: sysutil.CPUSharesName,
+			bytes:        1024,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			u, gotErr := NewMemoryUpdater(tt.resourceType, "/kubepods.slice", tt.bytes, nil)
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.wantErr {
+				return
+			}
+			if tt.rawValue != "" {
+				u.value = tt.rawValue
+			}
+			assert.Equal(t, tt.wantValue, u.Value())
+			got, gotErr := u.Bytes()
+			assert.Equal(t, tt.wantParseErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantBytes, got)
+
+			c, ok := u.Clone().(*MemoryUpdater)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantValue, c.Value())
+		})
+	}
+}
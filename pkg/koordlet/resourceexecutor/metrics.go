@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func init() {
+	prometheus.MustRegister(ResourceUpdateCollectors...)
+}
+
+const (
+	// OwnerUnknown is the owner of the updaters which are not tagged by WithOwner.
+	OwnerUnknown = "unknown"
+
+	// NOTE: the metrics package imports the resourceexecutor, so the labels are defined here to avoid the cycle.
+	metricsSubsystem = "koordlet"
+	resourceKey      = "resource"
+	ownerKey         = "owner"
+	statusKey        = "status"
+	statusSucceed    = "succeeded"
+	statusFailed     = "failed"
+	statusSkipped    = "skipped"
+)
+
+var (
+	ResourceUpdateTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: metricsSubsystem,
+		Name:      "resource_update_total",
+		Help:      "The number of the resource updates by the resource type, the owner module and the status, where the updates issuing no write are skipped",
+	}, []string{resourceKey, ownerKey, statusKey})
+
+	ResourceUpdateDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: metricsSubsystem,
+		Name:      "resource_update_duration_seconds",
+		Help:      "The duration of the resource updates in seconds by the resource type and the owner module",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{resourceKey, ownerKey})
+
+	ResourceDriftedPaths = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricsSubsystem,
+		Name:      "resource_drifted_paths",
+		Help:      "The number of the paths whose current values differ from the expected by the resource type in the last drift scan",
+	}, []string{resourceKey})
+
+	ResourceLastWriteTimestampSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricsSubsystem,
+		Name:      "resource_last_write_timestamp_seconds",
+		Help:      "The unix time of the last successful write by the resource type, only recorded by the updaters with WithLastWriteTimestamp",
+	}, []string{resourceKey})
+
+	ResourceUpdateCollectors = []prometheus.Collector{
+		ResourceUpdateTotal,
+		ResourceUpdateDurationSeconds,
+		ResourceDriftedPaths,
+		ResourceLastWriteTimestampSeconds,
+	}
+)
+
+// recordResourceUpdate records the update of the resource, which is counted as skipped if it succeeds without issuing
+// any write, e.g. the value is unchanged or the write is skipped by the options.
+func recordResourceUpdate(resourceType sysutil.ResourceType, owner string, start time.Time, written bool, err error) {
+	if owner == "" {
+		owner = OwnerUnknown
+	}
+	status := statusSucceed
+	if err != nil {
+		status = statusFailed
+	} else if !written {
+		status = statusSkipped
+	}
+	ResourceUpdateTotal.WithLabelValues(string(resourceType), owner, status).Inc()
+	ResourceUpdateDurationSeconds.WithLabelValues(string(resourceType), owner).Observe(time.Since(start).Seconds())
+}
+
+func recordLastWriteTimestamp(resourceType sysutil.ResourceType, t time.Time) {
+	ResourceLastWriteTimestampSeconds.WithLabelValues(string(resourceType)).Set(float64(t.UnixNano()) / float64(time.Second))
+}
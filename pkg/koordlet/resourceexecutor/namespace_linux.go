@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+// runInNamespace runs the fn under the namespace of the nsPath, e.g. `/proc/<pid>/ns/mnt`. It is a no-op if the
+// current thread is already in the namespace.
+// The fn runs in a dedicated goroutine locked to its OS thread. Since the thread has unshared the fs attributes to enter
+// the mount namespace, it is never unlocked so the runtime terminates the thread when the goroutine exits, and the other
+// goroutines are never scheduled into the namespace.
+func runInNamespace(nsPath string, fn func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		errCh <- runInNamespaceLocked(nsPath, fn)
+	}()
+	return <-errCh
+}
+
+func runInNamespaceLocked(nsPath string, fn func() error) error {
+	currentNSPath := filepath.Join("/proc/thread-self/ns", filepath.Base(nsPath))
+	targetInfo, err := os.Stat(nsPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat namespace %s, err: %w", nsPath, err)
+	}
+	currentInfo, err := os.Stat(currentNSPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat namespace %s, err: %w", currentNSPath, err)
+	}
+	if os.SameFile(targetInfo, currentInfo) {
+		return fn()
+	}
+
+	currentNS, err := os.Open(currentNSPath)
+	if err != nil {
+		return fmt.Errorf("failed to open namespace %s, err: %w", currentNSPath, err)
+	}
+	defer currentNS.Close()
+	targetNS, err := os.Open(nsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open namespace %s, err: %w", nsPath, err)
+	}
+	defer targetNS.Close()
+
+	// a thread sharing the fs attributes with others is not allowed to enter a mount namespace
+	if err = unix.Unshare(unix.CLONE_FS); err != nil {
+		return fmt.Errorf("failed to unshare fs attributes, err: %w", err)
+	}
+	if err = unix.Setns(int(targetNS.Fd()), 0); err != nil {
+		return fmt.Errorf("failed to enter namespace %s, err: %w", nsPath, err)
+	}
+	defer func() {
+		if err := unix.Setns(int(currentNS.Fd()), 0); err != nil {
+			klog.Warningf("failed to restore namespace %s, err: %v", currentNSPath, err)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestCgroupResourceUpdater_WithMountNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		nsPath  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "write under the self namespace",
+			nsPath: "/proc/self/ns/mnt",
+			want:   "20000",
+		},
+		{
+			name:    "failed to write under a missing namespace",
+			nsPath:  "/proc/not-exist/ns/mnt",
+			want:    "10000",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			gotErr := u.(*CgroupResourceUpdater).WithMountNamespace(tt.nsPath).update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/containerd/nri/pkg/api"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// NRIClient submits the container updates to the runtime, which is implemented by the stub of the NRI plugin. It
+// returns the updates failed to apply.
+type NRIClient interface {
+	UpdateContainers(updates []*api.ContainerUpdate) ([]*api.ContainerUpdate, error)
+}
+
+// nriUpdateSetter sets the value of a resource type into the LinuxResources field of the NRI container update.
+type nriUpdateSetter func(update *api.ContainerUpdate, value string) error
+
+// nriUpdateSetters maps the resource types to the NRI LinuxResources fields. The values are normalized before set, so
+// the unlimited "max" is converted into -1.
+var nriUpdateSetters = map[sysutil.ResourceType]nriUpdateSetter{
+	sysutil.CPUSetCPUSName: func(update *api.ContainerUpdate, value string) error {
+		update.SetLinuxCPUSetCPUs(value)
+		return nil
+	},
+	sysutil.CPUCFSQuotaName: func(update *api.ContainerUpdate, value string) error {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		update.SetLinuxCPUQuota(v)
+		return nil
+	},
+	sysutil.CPUCFSPeriodName: func(update *api.ContainerUpdate, value string) error {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		update.SetLinuxCPUPeriod(v)
+		return nil
+	},
+	sysutil.CPUSharesName: func(update *api.ContainerUpdate, value string) error {
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		update.SetLinuxCPUShares(v)
+		return nil
+	},
+	sysutil.MemoryLimitName: func(update *api.ContainerUpdate, value string) error {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		update.SetLinuxMemoryLimit(v)
+		return nil
+	},
+	sysutil.MemorySwappinessName: func(update *api.ContainerUpdate, value string) error {
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		update.SetLinuxMemorySwappiness(v)
+		return nil
+	},
+}
+
+// NewNRIContainerUpdate translates the resource value into the NRI update of the container. It returns an error if the
+// resource type is not mapped to a LinuxResources field.
+func NewNRIContainerUpdate(containerID string, resourceType sysutil.ResourceType, value string) (*api.ContainerUpdate, error) {
+	setter, ok := nriUpdateSetters[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("resource type %s is not supported by nri", resourceType)
+	}
+	update := &api.ContainerUpdate{}
+	update.SetContainerId(containerID)
+	if err := setter(update, NormalizeCgroupValue(resourceType, value)); err != nil {
+		return nil, fmt.Errorf("failed to parse value %q of resource type %s for nri, err: %w", value, resourceType, err)
+	}
+	return update, nil
+}
+
+// NewNRIUpdateFunc returns the UpdateFunc submitting the resource value of the container via the NRI client instead of
+// writing the cgroupfs directly, so the runtime keeps its view of the container resources consistent, e.g.
+//
+//	u, err := NewCgroupUpdaterWithUpdateFunc(NewNRIUpdateFunc(client, containerID))(sysutil.CPUSharesName, dir, "1024", e)
+func NewNRIUpdateFunc(client NRIClient, containerID string) UpdateFunc {
+	return func(resource ResourceUpdater) error {
+		update, err := NewNRIContainerUpdate(containerID, resource.ResourceType(), resource.Value())
+		if err != nil {
+			return err
+		}
+		failed, err := client.UpdateContainers([]*api.ContainerUpdate{update})
+		if err != nil {
+			return fmt.Errorf("failed to update container %s via nri, resource %s, err: %w", containerID, resource.ResourceType(), err)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to update container %s via nri, resource %s, update rejected", containerID, resource.ResourceType())
+		}
+		klog.V(5).Infof("update container %s via nri, resource %s, value %v", containerID, resource.ResourceType(), resource.Value())
+		if c, ok := resource.(*CgroupResourceUpdater); ok {
+			auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+type fakeNRIClient struct {
+	updates []*api.ContainerUpdate
+	reject  bool
+	err     error
+}
+
+func (f *fakeNRIClient) UpdateContainers(updates []*api.ContainerUpdate) ([]*api.ContainerUpdate, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.updates = append(f.updates, updates...)
+	if f.reject {
+		return updates, nil
+	}
+	return nil, nil
+}
+
+func TestNewNRIUpdateFunc(t *testing.T) {
+	newUpdate := func(set func(u *api.ContainerUpdate)) *api.ContainerUpdate {
+		u := &api.ContainerUpdate{}
+		u.SetContainerId("abc")
+		set(u)
+		return u
+	}
+	tests := []struct {
+		name         string
+		resourceType sysutil.ResourceType
+		value        string
+		client       *fakeNRIClient
+		want         []*api.ContainerUpdate
+		wantErr      bool
+	}{
+		{
+			name:         "translate cpu.shares",
+			resourceType: sysutil.CPUSharesName,
+			value:        "1024",
+			client:       &fakeNRIClient{},
+			want:         []*api.ContainerUpdate{newUpdate(func(u *api.ContainerUpdate) { u.SetLinuxCPUShares(1024) })},
+		},
+		{
+			name:         "translate the unlimited cfs quota",
+			resourceType: sysutil.CPUCFSQuotaName,
+			value:        "max",
+			client:       &fakeNRIClient{},
+			want:         []*api.ContainerUpdate{newUpdate(func(u *api.ContainerUpdate) { u.SetLinuxCPUQuota(-1) })},
+		},
+		{
+			name:         "translate cpuset.cpus",
+			resourceType: sysutil.CPUSetCPUSName,
+			value:        "0,1,2,5",
+			client:       &fakeNRIClient{},
+			want:         []*api.ContainerUpdate{newUpdate(func(u *api.ContainerUpdate) { u.SetLinuxCPUSetCPUs("0-2,5") })},
+		},
+		{
+			name:         "translate the memory limit",
+			resourceType: sysutil.MemoryLimitName,
+			value:        "1048576",
+			client:       &fakeNRIClient{},
+			want:         []*api.ContainerUpdate{newUpdate(func(u *api.ContainerUpdate) { u.SetLinuxMemoryLimit(1048576) })},
+		},
+		{
+			name:         "resource type not mapped",
+			resourceType: sysutil.CPUBurstName,
+			value:        "10000",
+			client:       &fakeNRIClient{},
+			wantErr:      true,
+		},
+		{
+			name:         "invalid value",
+			resourceType: sysutil.CPUSharesName,
+			value:        "-2",
+			client:       &fakeNRIClient{},
+			wantErr:      true,
+		},
+		{
+			name:         "update rejected",
+			resourceType: sysutil.CPUSharesName,
+			value:        "1024",
+			client:       &fakeNRIClient{reject: true},
+			want:         []*api.ContainerUpdate{newUpdate(func(u *api.ContainerUpdate) { u.SetLinuxCPUShares(1024) })},
+			wantErr:      true,
+		},
+		{
+			name:         "client failed",
+			resourceType: sysutil.CPUSharesName,
+			value:        "1024",
+			client:       &fakeNRIClient{err: fmt.Errorf("expected error")},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := NewCgroupUpdaterWithUpdateFunc(NewNRIUpdateFunc(tt.client, "abc"))(tt.resourceType, "/kubepods.slice/kubepods-pod1.slice", tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, tt.client.updates)
+		})
+	}
+}
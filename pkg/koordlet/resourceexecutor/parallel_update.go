@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// MultiUpdateError aggregates the errors of the failed updaters in UpdateAllParallel.
+type MultiUpdateError struct {
+	// Errors are the errors keyed by the path of the failed updaters.
+	Errors map[string]error
+}
+
+func (e *MultiUpdateError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for path, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", path, err))
+	}
+	return fmt.Sprintf("failed to update %d paths: [%s]", len(e.Errors), strings.Join(msgs, ", "))
+}
+
+// controllerLocks are the locks of the controllers whose writes are serialized across the workers of the
+// UpdateAllParallel.
+var controllerLocks = struct {
+	lock  sync.Mutex
+	locks map[string]*sync.Mutex
+}{
+	// the cpuset writes are validated against the parent and the siblings, which can fail transiently when they run
+	// concurrently, e.g. shrinking a pod while expanding another
+	locks: map[string]*sync.Mutex{
+		ControllerCPUSet: {},
+	},
+}
+
+// RegisterControllerLock makes the writes of the controller serialized in the UpdateAllParallel, while the writes of
+// the other controllers still run in parallel.
+func RegisterControllerLock(controller string) {
+	controllerLocks.lock.Lock()
+	defer controllerLocks.lock.Unlock()
+	if _, ok := controllerLocks.locks[controller]; !ok {
+		controllerLocks.locks[controller] = &sync.Mutex{}
+	}
+}
+
+// lockControllers locks the registered locks of the controllers which the updater writes, and returns the unlock
+// func. The locks are taken in the order of the controller names to avoid the deadlocks.
+func lockControllers(u ResourceUpdater) func() {
+	controllerSet := map[string]struct{}{}
+	if controller := GetController(u.ResourceType()); controller != "" {
+		controllerSet[controller] = struct{}{}
+	}
+	for _, p := range updaterPaths(u) {
+		if controller := GetController(sysutil.ResourceType(p)); controller != "" {
+			controllerSet[controller] = struct{}{}
+		}
+	}
+	controllers := make([]string, 0, len(controllerSet))
+	for controller := range controllerSet {
+		controllers = append(controllers, controller)
+	}
+	sort.Strings(controllers)
+
+	var locks []*sync.Mutex
+	controllerLocks.lock.Lock()
+	for _, controller := range controllers {
+		if l, ok := controllerLocks.locks[controller]; ok {
+			locks = append(locks, l)
+		}
+	}
+	controllerLocks.lock.Unlock()
+
+	for _, l := range locks {
+		l.Lock()
+	}
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+	}
+}
+
+// groupUpdatersByPaths groups the updaters which write any same path, including the secondary paths of the composite
+// updaters, and keeps the given order in each group.
+func groupUpdatersByPaths(updaters []ResourceUpdater) [][]ResourceUpdater {
+	parents := make([]int, len(updaters))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	owners := map[string]int{}
+	for i, u := range updaters {
+		for _, p := range updaterPaths(u) {
+			j, ok := owners[p]
+			if !ok {
+				owners[p] = i
+				continue
+			}
+			if ri, rj := find(i), find(j); ri != rj {
+				parents[ri] = rj
+			}
+		}
+	}
+
+	var groups [][]ResourceUpdater
+	groupIndexes := map[int]int{}
+	for i, u := range updaters {
+		root := find(i)
+		idx, ok := groupIndexes[root]
+		if !ok {
+			idx = len(groups)
+			groupIndexes[root] = idx
+			groups = append(groups, nil)
+		}
+		groups[idx] = append(groups[idx], u)
+	}
+	return groups
+}
+
+// UpdateAllParallel updates the updaters with a pool of the concurrency workers, e.g. to apply thousands of updaters
+// on a big node. The updaters writing any same path are serialized in the given order, and the first failure stops
+// the remaining updaters of the same paths. The writes of the controllers registered by the RegisterControllerLock
+// are also serialized. The failures are aggregated into a *MultiUpdateError.
+func UpdateAllParallel(updaters []ResourceUpdater, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	// group the updaters by paths, so the writes to the same path never run concurrently
+	groups := groupUpdatersByPaths(updaters)
+	groupCh := make(chan []ResourceUpdater, len(groups))
+	for _, group := range groups {
+		groupCh <- group
+	}
+	close(groupCh)
+
+	var lock sync.Mutex
+	errs := map[string]error{}
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(groups); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range groupCh {
+				for _, u := range group {
+					unlock := lockControllers(u)
+					err := u.update()
+					unlock()
+					if err != nil {
+						lock.Lock()
+						errs[u.Path()] = err
+						lock.Unlock()
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &MultiUpdateError{Errors: errs}
+	}
+	return nil
+}
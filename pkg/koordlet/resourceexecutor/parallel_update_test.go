@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestUpdateAllParallel(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	podDirs := []string{"/kubepods.slice/kubepods-pod1.slice", "/kubepods.slice/kubepods-pod2.slice"}
+	for _, dir := range podDirs {
+		helper.WriteCgroupFileContents(dir, sysutil.CPUCFSQuota, "10000")
+	}
+
+	// the writes of the independent paths wait for each other, which only finishes if they run in parallel
+	var barrier sync.WaitGroup
+	barrier.Add(len(podDirs))
+	var running, maxRunningSamePath int32
+	var updaters []ResourceUpdater
+	for _, dir := range podDirs {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, dir, "20000", nil)
+		assert.NoError(t, err)
+		updaters = append(updaters, u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+			barrier.Done()
+			waitCh := make(chan struct{})
+			go func() {
+				barrier.Wait()
+				close(waitCh)
+			}()
+			select {
+			case <-waitCh:
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("timed out waiting for the parallel write")
+			}
+			return CommonCgroupUpdateFunc(resource)
+		}))
+	}
+	// the writes of the same path are serialized
+	for _, value := range []string{"30000", "40000", "50000"} {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, podDirs[0], value, nil)
+		assert.NoError(t, err)
+		updaters = append(updaters, u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			if n > atomic.LoadInt32(&maxRunningSamePath) {
+				atomic.StoreInt32(&maxRunningSamePath, n)
+			}
+			time.Sleep(10 * time.Millisecond)
+			return CommonCgroupUpdateFunc(resource)
+		}))
+	}
+
+	assert.NoError(t, UpdateAllParallel(updaters, 4))
+	assert.Equal(t, int32(1), maxRunningSamePath)
+	assert.Equal(t, "50000", helper.ReadCgroupFileContents(podDirs[0], sysutil.CPUCFSQuota))
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(podDirs[1], sysutil.CPUCFSQuota))
+
+	// the failures are aggregated
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice/kubepods-pod3.slice", "20000", nil)
+	assert.NoError(t, err)
+	gotErr := UpdateAllParallel([]ResourceUpdater{u}, 4)
+	var multiErr *MultiUpdateError
+	assert.True(t, errors.As(gotErr, &multiErr), gotErr)
+	assert.Contains(t, multiErr.Errors, u.Path())
+}
+
+func TestUpdateAllParallel_MultiPathAndControllerLock(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	podDirs := []string{"/kubepods.slice/kubepods-pod1.slice", "/kubepods.slice/kubepods-pod2.slice", "/kubepods.slice/kubepods-pod3.slice"}
+	for _, dir := range podDirs {
+		helper.WriteCgroupFileContents(dir, sysutil.CPUCFSQuota, "10000")
+		helper.WriteCgroupFileContents(dir, sysutil.MemoryLimit, "1048576")
+	}
+	newUpdater := func(resourceType sysutil.ResourceType, dir, value string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, dir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater)
+	}
+
+	// the secondary paths of the composite updaters are grouped
+	a := newUpdater(sysutil.CPUCFSQuotaName, podDirs[0], "20000")
+	b := newUpdater(sysutil.CPUCFSQuotaName, podDirs[1], "20000")
+	twoPhase := NewTwoPhaseUpdater(newUpdater(sysutil.CPUCFSQuotaName, podDirs[1], "30000"),
+		newUpdater(sysutil.CPUCFSQuotaName, podDirs[2], "30000"))
+	c := newUpdater(sysutil.CPUCFSQuotaName, podDirs[2], "40000")
+	assert.Equal(t, [][]ResourceUpdater{{a}, {b, twoPhase, c}}, groupUpdatersByPaths([]ResourceUpdater{a, b, twoPhase, c}))
+	assert.NoError(t, UpdateAllParallel([]ResourceUpdater{a, b, twoPhase, c}, 4))
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(podDirs[1], sysutil.CPUCFSQuota))
+	assert.Equal(t, "40000", helper.ReadCgroupFileContents(podDirs[2], sysutil.CPUCFSQuota))
+
+	// the writes of a locked controller are serialized across the paths
+	RegisterControllerLock(ControllerMemory)
+	defer func() {
+		controllerLocks.lock.Lock()
+		delete(controllerLocks.locks, ControllerMemory)
+		controllerLocks.lock.Unlock()
+	}()
+	var running, maxRunning int32
+	var updaters []ResourceUpdater
+	for _, dir := range podDirs {
+		updaters = append(updaters, newUpdater(sysutil.MemoryLimitName, dir, "2097152").WithUpdateFunc(func(resource ResourceUpdater) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			if n > atomic.LoadInt32(&maxRunning) {
+				atomic.StoreInt32(&maxRunning, n)
+			}
+			time.Sleep(10 * time.Millisecond)
+			return CommonCgroupUpdateFunc(resource)
+		}))
+	}
+	assert.NoError(t, UpdateAllParallel(updaters, 4))
+	assert.Equal(t, int32(1), maxRunning)
+	for _, dir := range podDirs {
+		assert.Equal(t, "2097152", helper.ReadCgroupFileContents(dir, sysutil.MemoryLimit))
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// openPersistentFD opens the file to write by the PersistentFDExecutor.
+var openPersistentFD = func(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY, 0644)
+}
+
+// PersistentFDExecutor writes the updaters with the file descriptors kept open between the writes to the same path,
+// which saves the open and close syscalls on the extremely hot paths. Each write is written at the offset 0 and
+// truncates the rest, so the consecutive writes never append. A failed fd is closed and reopened by the next write
+// of the path. The values are written without the update funcs, but converted as the update funcs do, e.g. the
+// `cpu.weight` of the `cpu.shares` on the cgroups-v2, see ConvertCgroupValue. The multi-line values of the resources
+// written line by line are rejected. The caller should Close the executor to release the fds when the session ends.
+type PersistentFDExecutor struct {
+	lock sync.Mutex
+	fds  map[string]*os.File
+}
+
+func NewPersistentFDExecutor() *PersistentFDExecutor {
+	return &PersistentFDExecutor{
+		fds: map[string]*os.File{},
+	}
+}
+
+// Write writes the value of the updater into its path with the kept-open fd.
+func (e *PersistentFDExecutor) Write(updater ResourceUpdater) error {
+	value := updater.Value()
+	if c, ok := updater.(*CgroupResourceUpdater); ok {
+		var err error
+		if value, err = ConvertCgroupValue(c.file, c.value); err != nil {
+			return fmt.Errorf("write cgroup %s failed, convert value[%v] err: %w", c.ResourceType(), c.value, err)
+		}
+		if valid, msg := c.file.IsValid(value); !valid {
+			return fmt.Errorf("write cgroup %s failed, value[%v] not valid, msg: %s", c.ResourceType(), value, msg)
+		}
+		// the kernel parses one line per write of these resources, see CgroupLineWriteFunc
+		if (appendOnlyResources[c.ResourceType()] || perDeviceResources[c.ResourceType()]) && len(splitValueLines(value)) > 1 {
+			return fmt.Errorf("write cgroup %s failed, multi-line value[%v] is not supported with persistent fd",
+				c.ResourceType(), value)
+		}
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	start := time.Now()
+	err := e.write(updater.Path(), value)
+	owner := ""
+	if c, ok := updater.(*CgroupResourceUpdater); ok {
+		owner = c.owner
+	}
+	recordResourceUpdate(updater.ResourceType(), owner, start, true, err)
+	return err
+}
+
+func (e *PersistentFDExecutor) write(path, value string) error {
+	f, ok := e.fds[path]
+	if !ok {
+		var err error
+		f, err = openPersistentFD(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s, err: %w", path, err)
+		}
+		e.fds[path] = f
+	}
+
+	if _, err := f.WriteAt([]byte(value), 0); err != nil {
+		e.release(path, f)
+		return fmt.Errorf("failed to write %s to %v, err: %w", path, value, err)
+	}
+	// the cgroup files ignore the offset and the size, while the regular files need the truncation to drop the rest
+	// of a longer previous value
+	if err := f.Truncate(int64(len(value))); err != nil {
+		klog.V(6).Infof("failed to truncate %s after writing, err: %v", path, err)
+	}
+	klog.V(5).Infof("write %s [%s] with persistent fd", path, value)
+	return nil
+}
+
+func (e *PersistentFDExecutor) release(path string, f *os.File) {
+	delete(e.fds, path)
+	if err := f.Close(); err != nil {
+		klog.V(5).Infof("failed to close fd of %s, err: %v", path, err)
+	}
+}
+
+// Close closes all kept-open fds. The executor can be reused after Close, which reopens the fds.
+func (e *PersistentFDExecutor) Close() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var errs []error
+	for path, f := range e.fds {
+		if err := f.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close fd of %s, err: %w", path, err))
+		}
+		delete(e.fds, path)
+	}
+	return utilerrors.NewAggregate(errs)
+}
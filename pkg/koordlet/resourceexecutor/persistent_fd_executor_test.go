@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestPersistentFDExecutor(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	oldOpen := openPersistentFD
+	defer func() {
+		openPersistentFD = oldOpen
+	}()
+	var opened []*os.File
+	openPersistentFD = func(path string) (*os.File, error) {
+		f, err := oldOpen(path)
+		if err == nil {
+			opened = append(opened, f)
+		}
+		return f, err
+	}
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	newUpdater := func(value string) ResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u
+	}
+
+	e := NewPersistentFDExecutor()
+	// the writes reuse one fd and never append
+	assert.NoError(t, e.Write(newUpdater("262144")))
+	assert.Equal(t, "262144", helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+	assert.NoError(t, e.Write(newUpdater("2")))
+	assert.Equal(t, "2", helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+	assert.NoError(t, e.Write(newUpdater("4096")))
+	assert.Equal(t, "4096", helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+	assert.Len(t, opened, 1)
+	// the invalid value is rejected before writing
+	assert.Error(t, e.Write(newUpdater("1")))
+	assert.Equal(t, "4096", helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+
+	// Close releases the fd
+	assert.NoError(t, e.Close())
+	_, err := opened[0].Write([]byte("1"))
+	assert.True(t, errors.Is(err, os.ErrClosed), err)
+	// reopen after Close
+	assert.NoError(t, e.Write(newUpdater("2048")))
+	assert.Equal(t, "2048", helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+	assert.Len(t, opened, 2)
+	assert.NoError(t, e.Close())
+
+	// the file not exist
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice/not-exist.slice", "10000", nil)
+	assert.NoError(t, err)
+	assert.Error(t, e.Write(u))
+	assert.Len(t, opened, 2)
+}
+
+func TestPersistentFDExecutor_CgroupsV2(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "100")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2, "20000 100000")
+	e := NewPersistentFDExecutor()
+	defer e.Close()
+
+	// the values are converted as the update funcs do
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, e.Write(u))
+	assert.Equal(t, "39", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "-1", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, e.Write(u))
+	assert.Equal(t, "max", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2))
+
+	// the multi-line value written line by line is rejected
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.IOMaxName, parentDir, "8:0 rbps=1000\n8:16 rbps=1000", nil)
+	assert.NoError(t, err)
+	gotErr := e.Write(u)
+	assert.Error(t, gotErr)
+	assert.Contains(t, gotErr.Error(), "multi-line")
+}
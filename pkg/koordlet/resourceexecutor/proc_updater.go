@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+const (
+	OOMScoreAdjFileName = "oom_score_adj"
+
+	OOMScoreAdjMinValue int64 = -1000
+	OOMScoreAdjMaxValue int64 = 1000
+)
+
+// NewOOMScoreAdjUpdater returns an updater which writes the `/proc/<pid>/oom_score_adj` of the process. The value
+// should be in [-1000, 1000]. The process vanished before the write is considered benign, so the write is skipped.
+func NewOOMScoreAdjUpdater(pid int, value string) (ResourceUpdater, error) {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oom_score_adj value %q of pid %d, err: %v", value, pid, err)
+	}
+	if v < OOMScoreAdjMinValue || v > OOMScoreAdjMaxValue {
+		return nil, fmt.Errorf("invalid oom_score_adj value %d of pid %d, not in [min:%d, max:%d]",
+			v, pid, OOMScoreAdjMinValue, OOMScoreAdjMaxValue)
+	}
+	file := filepath.Join(sysutil.Conf.ProcRootDir, strconv.Itoa(pid), OOMScoreAdjFileName)
+	return NewCommonDefaultUpdaterWithUpdateFunc(file, file, value, OOMScoreAdjUpdateFunc, nil)
+}
+
+// OOMScoreAdjUpdateFunc writes the per-process `oom_score_adj`, and ignores the benign errors like the vanished pid.
+func OOMScoreAdjUpdateFunc(resource ResourceUpdater) error {
+	err := CommonDefaultUpdateFunc(resource)
+	if err != nil && ClassifyWriteError(err) == ErrorClassBenign {
+		klog.V(5).Infof("skip updating %s to %v, the process has exited, err: %v", resource.Path(), resource.Value(), err)
+		return nil
+	}
+	return err
+}
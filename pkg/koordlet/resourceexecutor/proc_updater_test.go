@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestNewOOMScoreAdjUpdater(t *testing.T) {
+	tests := []struct {
+		name       string
+		pid        int
+		pidExist   bool
+		value      string
+		want       string
+		wantNewErr bool
+	}{
+		{
+			name:     "write the oom_score_adj of the process",
+			pid:      1234,
+			pidExist: true,
+			value:    "-999",
+			want:     "-999",
+		},
+		{
+			name:       "reject the value out of range",
+			pid:        1234,
+			pidExist:   true,
+			value:      "1001",
+			want:       "0",
+			wantNewErr: true,
+		},
+		{
+			name:       "reject the non-integer value",
+			pid:        1234,
+			pidExist:   true,
+			value:      "high",
+			want:       "0",
+			wantNewErr: true,
+		},
+		{
+			name:  "skip the vanished process",
+			pid:   1234,
+			value: "1000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			file := filepath.Join(sysutil.Conf.ProcRootDir, "1234", OOMScoreAdjFileName)
+			if tt.pidExist {
+				helper.WriteFileContents(file, "0")
+			}
+
+			u, gotErr := NewOOMScoreAdjUpdater(tt.pid, tt.value)
+			assert.Equal(t, tt.wantNewErr, gotErr != nil, gotErr)
+			if !tt.wantNewErr {
+				assert.NoError(t, u.update())
+			}
+			if tt.pidExist {
+				assert.Equal(t, tt.want, helper.ReadFileContents(file))
+			} else {
+				assert.False(t, sysutil.FileExists(file))
+			}
+		})
+	}
+}
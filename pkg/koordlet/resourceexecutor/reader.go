@@ -413,3 +413,15 @@ func NewCgroupReader() CgroupReader {
 	}
 	return &CgroupV1Reader{}
 }
+
+// ReadCPUStat reads the cpu.stat of the cgroup into the structured type with the reader of the current cgroup version,
+// e.g. for the controllers acting on the throttling. The v2 durations in usec are converted into nanoseconds as v1.
+func ReadCPUStat(parentDir string) (*sysutil.CPUStatRaw, error) {
+	return NewCgroupReader().ReadCPUStat(parentDir)
+}
+
+// ReadMemoryStat reads the memory.stat of the cgroup into the structured type with the reader of the current cgroup
+// version. The v1 hierarchical `total_*` fields and the corresponding v2 fields are parsed into the same fields.
+func ReadMemoryStat(parentDir string) (*sysutil.MemoryStatRaw, error) {
+	return NewCgroupReader().ReadMemoryStat(parentDir)
+}
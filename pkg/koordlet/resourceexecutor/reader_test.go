@@ -1446,3 +1446,96 @@ func TestCgroupReader_ReadColdPageUsage(t *testing.T) {
 		})
 	}
 }
+
+func TestReadCPUStatAndMemoryStat(t *testing.T) {
+	tests := []struct {
+		name            string
+		useCgroupsV2    bool
+		cpuStatValue    string
+		memoryStatValue string
+		wantCPUStat     *sysutil.CPUStatRaw
+		wantMemoryStat  *sysutil.MemoryStatRaw
+	}{
+		{
+			name: "read v1 stats",
+			cpuStatValue: `nr_periods 100
+nr_throttled 20
+throttled_time 3000000`,
+			memoryStatValue: `cache 1
+rss 2
+total_cache 104857600
+total_rss 209715200
+total_inactive_file 52428800
+total_active_file 52428800
+total_inactive_anon 104857600
+total_active_anon 104857600
+total_unevictable 0`,
+			wantCPUStat: &sysutil.CPUStatRaw{
+				NrPeriods:            100,
+				NrThrottled:          20,
+				ThrottledNanoSeconds: 3000000,
+			},
+			wantMemoryStat: &sysutil.MemoryStatRaw{
+				Cache:        104857600,
+				RSS:          209715200,
+				InactiveFile: 52428800,
+				ActiveFile:   52428800,
+				InactiveAnon: 104857600,
+				ActiveAnon:   104857600,
+			},
+		},
+		{
+			name:         "read v2 stats",
+			useCgroupsV2: true,
+			cpuStatValue: `usage_usec 1000000
+user_usec 600000
+system_usec 400000
+nr_periods 100
+nr_throttled 20
+throttled_usec 3000`,
+			memoryStatValue: `anon 209715200
+file 104857600
+inactive_anon 104857600
+active_anon 104857600
+inactive_file 52428800
+active_file 52428800
+unevictable 0`,
+			wantCPUStat: &sysutil.CPUStatRaw{
+				NrPeriods:            100,
+				NrThrottled:          20,
+				ThrottledNanoSeconds: 3000000,
+			},
+			wantMemoryStat: &sysutil.MemoryStatRaw{
+				Cache:        104857600,
+				RSS:          209715200,
+				InactiveFile: 52428800,
+				ActiveFile:   52428800,
+				InactiveAnon: 104857600,
+				ActiveAnon:   104857600,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice"
+			if tt.useCgroupsV2 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CPUStatV2, tt.cpuStatValue)
+				helper.WriteCgroupFileContents(parentDir, sysutil.MemoryStatV2, tt.memoryStatValue)
+			} else {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CPUStat, tt.cpuStatValue)
+				helper.WriteCgroupFileContents(parentDir, sysutil.MemoryStat, tt.memoryStatValue)
+			}
+
+			gotCPUStat, err := ReadCPUStat(parentDir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCPUStat, gotCPUStat)
+			gotMemoryStat, err := ReadMemoryStat(parentDir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMemoryStat, gotMemoryStat)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"time"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+var _ ResourceUpdater = &ReadOnlyResourceUpdater{}
+
+// ReadOnlyResourceUpdater wraps a ResourceUpdater to observe the resource without writing, which helps the monitoring
+// pipelines to reuse the updater abstraction with no risk of the accidental writes. Its Update reads and caches the
+// current value, and its Value returns the last read.
+type ReadOnlyResourceUpdater struct {
+	inner               ResourceUpdater
+	value               string
+	lastUpdateTimestamp time.Time
+}
+
+// NewReadOnlyUpdater returns a read-only updater of the inner updater, whose value is ignored.
+func NewReadOnlyUpdater(inner ResourceUpdater) *ReadOnlyResourceUpdater {
+	return &ReadOnlyResourceUpdater{inner: inner}
+}
+
+func (u *ReadOnlyResourceUpdater) ResourceType() sysutil.ResourceType {
+	return u.inner.ResourceType()
+}
+
+func (u *ReadOnlyResourceUpdater) Key() string {
+	return u.inner.Key()
+}
+
+func (u *ReadOnlyResourceUpdater) Path() string {
+	return u.inner.Path()
+}
+
+// Paths returns no path since the updater never writes.
+func (u *ReadOnlyResourceUpdater) Paths() []string {
+	return nil
+}
+
+// Value returns the last read value, which is empty if never read.
+func (u *ReadOnlyResourceUpdater) Value() string {
+	return u.value
+}
+
+func (u *ReadOnlyResourceUpdater) IsMergeable() bool {
+	return false
+}
+
+func (u *ReadOnlyResourceUpdater) MergeUpdate() (ResourceUpdater, error) {
+	return nil, u.update()
+}
+
+func (u *ReadOnlyResourceUpdater) Clone() ResourceUpdater {
+	return &ReadOnlyResourceUpdater{
+		inner:               u.inner.Clone(),
+		value:               u.value,
+		lastUpdateTimestamp: u.lastUpdateTimestamp,
+	}
+}
+
+func (u *ReadOnlyResourceUpdater) GetLastUpdateTimestamp() time.Time {
+	return u.lastUpdateTimestamp
+}
+
+func (u *ReadOnlyResourceUpdater) UpdateLastUpdateTimestamp(time time.Time) {
+	u.lastUpdateTimestamp = time
+}
+
+// update validates the resource and reads its current value. It never writes.
+func (u *ReadOnlyResourceUpdater) update() error {
+	value, err := readCurrentValue(u.inner)
+	if err != nil {
+		return err
+	}
+	u.value = value
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestReadOnlyResourceUpdater(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	inner, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+
+	u := NewReadOnlyUpdater(inner)
+	assert.Equal(t, inner.Path(), u.Path())
+	assert.Equal(t, "", u.Value())
+	assert.NoError(t, u.update())
+	assert.Equal(t, "10000", u.Value())
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// reflect the last read
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "30000")
+	c := u.Clone()
+	assert.Equal(t, "10000", c.Value())
+	assert.NoError(t, c.update())
+	assert.Equal(t, "30000", c.Value())
+	assert.Equal(t, "10000", u.Value())
+
+	// the default updater
+	file := filepath.Join(helper.TempDir, "test_file")
+	helper.WriteFileContents("test_file", "1")
+	inner, err = NewCommonDefaultUpdater(file, file, "2", nil)
+	assert.NoError(t, err)
+	u = NewReadOnlyUpdater(inner)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "1", u.Value())
+	assert.Equal(t, "1", helper.ReadFileContents("test_file"))
+
+	// failed to read
+	inner, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice/not-exist", "20000", nil)
+	assert.NoError(t, err)
+	assert.Error(t, NewReadOnlyUpdater(inner).update())
+}
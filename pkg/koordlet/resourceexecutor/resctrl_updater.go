@@ -184,5 +184,7 @@ func UpdateResctrlTasksFunc(resource ResourceUpdater) error {
 	klog.V(5).Infof("write Cat L3 task ids for dir %s finished: %v succeed, %v total",
 		resource.Key(), success, total)
 
-	return f.Close()
+	err = f.Close()
+	c.addIssuedWrite(resource.Value(), err)
+	return err
 }
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+var defaultValues = struct {
+	lock   sync.RWMutex
+	values map[sysutil.ResourceType]string
+}{
+	values: map[sysutil.ResourceType]string{},
+}
+
+func init() {
+	// register the default values of the resources which are reset when the managed cgroups are cleaned up
+	RegisterDefault(sysutil.CPUCFSQuotaName, sysutil.CgroupUnlimitedSymbolStr)
+	RegisterDefault(sysutil.CPUBurstName, "0")
+	RegisterDefault(sysutil.MemoryLimitName, sysutil.CgroupUnlimitedSymbolStr)
+	RegisterDefault(sysutil.MemoryMinName, "0")
+	RegisterDefault(sysutil.MemoryLowName, "0")
+	RegisterDefault(sysutil.MemoryHighName, sysutil.CgroupMaxSymbolStr)
+	RegisterDefault(sysutil.PidsMaxName, sysutil.CgroupMaxSymbolStr)
+}
+
+// RegisterDefault registers the default value of the resource type which is used to reset the resource.
+func RegisterDefault(resourceType sysutil.ResourceType, value string) {
+	defaultValues.lock.Lock()
+	defer defaultValues.lock.Unlock()
+	defaultValues.values[resourceType] = value
+}
+
+// GetDefault returns the registered default value of the resource type.
+func GetDefault(resourceType sysutil.ResourceType) (string, bool) {
+	defaultValues.lock.RLock()
+	defer defaultValues.lock.RUnlock()
+	v, ok := defaultValues.values[resourceType]
+	return v, ok
+}
+
+// listDefaultResourceTypes returns the resource types with the registered default values in the sorted order.
+func listDefaultResourceTypes() []sysutil.ResourceType {
+	defaultValues.lock.RLock()
+	defer defaultValues.lock.RUnlock()
+	resourceTypes := make([]sysutil.ResourceType, 0, len(defaultValues.values))
+	for t := range defaultValues.values {
+		resourceTypes = append(resourceTypes, t)
+	}
+	sort.Slice(resourceTypes, func(i, j int) bool {
+		return resourceTypes[i] < resourceTypes[j]
+	})
+	return resourceTypes
+}
+
+// NewResetUpdater returns an updater which resets the resource of the parentDir to the registered default value.
+func NewResetUpdater(resourceType sysutil.ResourceType, parentDir string) (ResourceUpdater, error) {
+	v, ok := GetDefault(resourceType)
+	if !ok {
+		return nil, fmt.Errorf("default value of resource type %s not registered", resourceType)
+	}
+	return DefaultCgroupUpdaterFactory.New(resourceType, parentDir, v, nil)
+}
+
+// DefaultUpdaterFor returns the updater which restores the registered default value for the resource and the path of
+// the given applied updater, e.g. for the teardown. The returned updater is a clone of the applied one, so the custom
+// update funcs are kept. It returns an error if no default value is registered for the resource type.
+func DefaultUpdaterFor(u ResourceUpdater) (ResourceUpdater, error) {
+	v, ok := GetDefault(u.ResourceType())
+	if !ok {
+		return nil, fmt.Errorf("default value of resource type %s not registered", u.ResourceType())
+	}
+	switch applied := u.(type) {
+	case *CgroupResourceUpdater:
+		return cloneCgroupUpdaterWithValue(applied, v, applied.eventHelper), nil
+	case *DefaultResourceUpdater:
+		c := applied.Clone().(*DefaultResourceUpdater)
+		c.value = v
+		c.lastUpdateTimestamp = time.Time{}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unsupported updater type %T for resource type %s", u, u.ResourceType())
+	}
+}
+
+// ResetSubtree resets the given resources to the registered default values for the parentDir and all its
+// sub-directories, e.g. when a pod is deleted. The directories removed during the walk are ignored.
+func ResetSubtree(parentDir string, resourceTypes []sysutil.ResourceType) error {
+	var errs []error
+	for _, t := range resourceTypes {
+		r, err := sysutil.GetCgroupResource(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, dir := range listSubtreeDirs(parentDir, r) {
+			if err = resetResource(t, dir); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ApplyBaseline writes the registered default values of all the registered resource types into the given directories,
+// e.g. to bring the cgroups of a node to a known baseline on the bootstrap. The resources unsupported or absent are
+// skipped. Unlike the ResetSubtree, the sub-directories are not walked.
+func ApplyBaseline(parentDirs []string) error {
+	resourceTypes := listDefaultResourceTypes()
+	var errs []error
+	for _, dir := range parentDirs {
+		for _, t := range resourceTypes {
+			if err := resetResource(t, dir); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// resetResource resets the resource of the dir to the registered default value, where the unsupported resource and
+// the missing dir are skipped.
+func resetResource(resourceType sysutil.ResourceType, dir string) error {
+	u, err := NewResetUpdater(resourceType, dir)
+	if err != nil {
+		return err
+	}
+	err = u.update()
+	if err != nil && (sysutil.IsResourceUnsupportedErr(err) || IsCgroupDirErr(err)) {
+		klog.V(6).Infof("skip reset resource %s, err: %v", u.Path(), err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reset resource %s, err: %w", u.Path(), err)
+	}
+	klog.V(6).Infof("successfully reset resource %s to %v", u.Path(), u.Value())
+	return nil
+}
+
+// listSubtreeDirs lists the parentDir and its sub-directories in the pre-order for the cgroup resource.
+func listSubtreeDirs(parentDir string, r sysutil.Resource) []string {
+	rootDir := filepath.Dir(r.Path(parentDir))
+	var dirs []string
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) { // the directory disappears during the walk
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, filepath.Join(parentDir, rel))
+		return nil
+	})
+	if err != nil {
+		klog.V(5).Infof("failed to walk cgroup dir %s, err: %v", rootDir, err)
+	}
+	return dirs
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestResetSubtree(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	podDir := "/kubepods.slice/kubepods-pod1.slice"
+	containerDir := podDir + "/cri-containerd-xxx.scope"
+	helper.WriteCgroupFileContents(podDir, sysutil.CPUCFSQuota, "20000")
+	helper.WriteCgroupFileContents(containerDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(podDir, sysutil.MemoryLimit, "2097152")
+	helper.WriteCgroupFileContents(containerDir, sysutil.MemoryLimit, "1048576")
+	// the sibling pod is untouched
+	helper.WriteCgroupFileContents("/kubepods.slice/kubepods-pod2.slice", sysutil.CPUCFSQuota, "30000")
+
+	err := ResetSubtree(podDir, []sysutil.ResourceType{sysutil.CPUCFSQuotaName, sysutil.MemoryLimitName})
+	assert.NoError(t, err)
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(podDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(containerDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(podDir, sysutil.MemoryLimit))
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(containerDir, sysutil.MemoryLimit))
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents("/kubepods.slice/kubepods-pod2.slice", sysutil.CPUCFSQuota))
+
+	// the removed dir is tolerated
+	err = ResetSubtree("/kubepods.slice/kubepods-pod3.slice", []sysutil.ResourceType{sysutil.CPUCFSQuotaName})
+	assert.NoError(t, err)
+
+	// no default registered
+	helper.WriteCgroupFileContents(podDir, sysutil.CPUShares, "1024")
+	err = ResetSubtree(podDir, []sysutil.ResourceType{sysutil.CPUSharesName})
+	assert.Error(t, err)
+}
+
+func TestApplyBaseline(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	besteffortDir := "/kubepods.slice/kubepods-besteffort.slice"
+	burstableDir := "/kubepods.slice/kubepods-burstable.slice"
+	helper.WriteCgroupFileContents(besteffortDir, sysutil.CPUCFSQuota, "20000")
+	helper.WriteCgroupFileContents(besteffortDir, sysutil.MemoryLimit, "2097152")
+	helper.WriteCgroupFileContents(burstableDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(burstableDir, sysutil.MemoryLimit, "1048576")
+	// the sub-directory is untouched
+	podDir := burstableDir + "/kubepods-burstable-pod1.slice"
+	helper.WriteCgroupFileContents(podDir, sysutil.CPUCFSQuota, "30000")
+
+	err := ApplyBaseline([]string{besteffortDir, burstableDir})
+	assert.NoError(t, err)
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(besteffortDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(besteffortDir, sysutil.MemoryLimit))
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(burstableDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(burstableDir, sysutil.MemoryLimit))
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(podDir, sysutil.CPUCFSQuota))
+
+	// the missing dir is skipped
+	err = ApplyBaseline([]string{"/kubepods.slice/kubepods-pod2.slice"})
+	assert.NoError(t, err)
+}
+
+func TestDefaultUpdaterFor(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+	applied, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, applied.update())
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	u, err := DefaultUpdaterFor(applied)
+	assert.NoError(t, err)
+	assert.Equal(t, applied.Path(), u.Path())
+	assert.Equal(t, "-1", u.Value())
+	assert.Equal(t, "20000", applied.Value())
+	assert.NoError(t, u.update())
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// no default registered
+	applied, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	_, err = DefaultUpdaterFor(applied)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// RuntimeHookRequest is the update intent of a cgroup resource sent to the runtime hook instead of writing the
+// cgroup file directly.
+type RuntimeHookRequest struct {
+	ResourceType string `json:"resourceType"`
+	ParentDir    string `json:"parentDir"`
+	Value        string `json:"value"`
+}
+
+// RuntimeHookClient delegates the resource updates to a runtime hook.
+type RuntimeHookClient interface {
+	UpdateResource(req *RuntimeHookRequest) error
+}
+
+// RuntimeHookUpdateFunc returns an UpdateFunc which sends the cgroup resource and value to the given runtime hook
+// client rather than writing the cgroup file.
+func RuntimeHookUpdateFunc(client RuntimeHookClient) UpdateFunc {
+	return func(resource ResourceUpdater) error {
+		c, ok := resource.(*CgroupResourceUpdater)
+		if !ok {
+			return fmt.Errorf("not a CgroupResourceUpdater")
+		}
+		if client == nil {
+			return fmt.Errorf("runtime hook client is nil")
+		}
+		if valid, msg := c.file.IsValid(c.value); !valid {
+			return fmt.Errorf("update cgroup %s via runtime hook failed, value[%v] not valid, msg: %s", c.ResourceType(), c.value, msg)
+		}
+
+		req := &RuntimeHookRequest{
+			ResourceType: string(c.ResourceType()),
+			ParentDir:    c.parentDir,
+			Value:        c.value,
+		}
+		if err := client.UpdateResource(req); err != nil {
+			klog.V(5).Infof("failed to update cgroup %s to %v via runtime hook, err: %v", c.Path(), c.value, err)
+			return err
+		}
+		auditUpdateVia(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), "runtime hook", c.auditTags)
+		return nil
+	}
+}
+
+// NewRuntimeHookUpdater returns a CgroupResourceUpdater which delegates the update to the runtime hook client.
+func NewRuntimeHookUpdater(client RuntimeHookClient, resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	return NewCgroupUpdater(resourceType, parentDir, value, RuntimeHookUpdateFunc(client), e)
+}
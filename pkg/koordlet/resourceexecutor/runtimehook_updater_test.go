@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+type fakeRuntimeHookClient struct {
+	requests []*RuntimeHookRequest
+	err      error
+}
+
+func (f *fakeRuntimeHookClient) UpdateResource(req *RuntimeHookRequest) error {
+	f.requests = append(f.requests, req)
+	return f.err
+}
+
+func TestNewRuntimeHookUpdater(t *testing.T) {
+	type args struct {
+		resourceType sysutil.ResourceType
+		value        string
+		hookErr      error
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []*RuntimeHookRequest
+		wantErr bool
+	}{
+		{
+			name: "send cfs quota to the hook",
+			args: args{
+				resourceType: sysutil.CPUCFSQuotaName,
+				value:        "20000",
+			},
+			want: []*RuntimeHookRequest{
+				{
+					ResourceType: sysutil.CPUCFSQuotaName,
+					ParentDir:    "/kubepods.slice/kubepods-pod1.slice",
+					Value:        "20000",
+				},
+			},
+		},
+		{
+			name: "return the hook error",
+			args: args{
+				resourceType: sysutil.CPUSharesName,
+				value:        "1024",
+				hookErr:      fmt.Errorf("expected error"),
+			},
+			want: []*RuntimeHookRequest{
+				{
+					ResourceType: sysutil.CPUSharesName,
+					ParentDir:    "/kubepods.slice/kubepods-pod1.slice",
+					Value:        "1024",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid value is not sent",
+			args: args{
+				resourceType: sysutil.CPUSharesName,
+				value:        "1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			client := &fakeRuntimeHookClient{err: tt.args.hookErr}
+			u, err := NewRuntimeHookUpdater(client, tt.args.resourceType, "/kubepods.slice/kubepods-pod1.slice", tt.args.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, client.requests)
+		})
+	}
+}
+
+func TestRuntimeHookUpdater_Audit(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	oldAuditor := audit.Default
+	defer func() {
+		audit.Default = oldAuditor
+	}()
+	auditDir := t.TempDir()
+	audit.Default = audit.NewAuditor(&audit.Config{LogDir: auditDir, Verbose: 3, MaxDiskSpaceMB: 16})
+
+	u, err := NewRuntimeHookUpdater(&fakeRuntimeHookClient{}, sysutil.CPUCFSQuotaName, "/kubepods.slice/kubepods-pod1.slice", "20000", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	assert.NoError(t, audit.Default.LoggerWriter().Flush())
+
+	iter := audit.NewEventReader(auditDir).NewReverseInterator()
+	defer iter.Close()
+	event, err := iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, ReasonUpdateCgroups, event.Reason)
+	assert.Contains(t, event.Message, "cpu.cfs_quota_us to 20000 via runtime hook")
+}
@@ -0,0 +1,122 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+// tmpfsRemountPreservedFlags maps the ST_* flags returned by the statfs to the MS_* per-mount flags which are kept in
+// the remount, since the remount resets the flags not specified.
+var tmpfsRemountPreservedFlags = map[int64]uintptr{
+	unix.ST_RDONLY:      unix.MS_RDONLY,
+	unix.ST_NOSUID:      unix.MS_NOSUID,
+	unix.ST_NODEV:       unix.MS_NODEV,
+	unix.ST_NOEXEC:      unix.MS_NOEXEC,
+	unix.ST_SYNCHRONOUS: unix.MS_SYNCHRONOUS,
+	unix.ST_MANDLOCK:    unix.MS_MANDLOCK,
+	unix.ST_NOATIME:     unix.MS_NOATIME,
+	unix.ST_NODIRATIME:  unix.MS_NODIRATIME,
+	unix.ST_RELATIME:    unix.MS_RELATIME,
+}
+
+var (
+	tmpfsStatfs  = unix.Statfs
+	tmpfsRemount = func(mountPath string, flags uintptr, data string) error {
+		return unix.Mount("", mountPath, "", flags, data)
+	}
+)
+
+// NewTmpfsSizeUpdater returns an updater which enforces the size limit of the tmpfs mounted at the mountPath, e.g.
+// the memory-backed emptyDir of a pod, by remounting it with the `size=` option. The size is a quantity like "1Gi".
+func NewTmpfsSizeUpdater(mountPath, size string) (ResourceUpdater, error) {
+	if !filepath.IsAbs(mountPath) {
+		return nil, fmt.Errorf("invalid tmpfs mount path %q, should be absolute", mountPath)
+	}
+	sizeBytes, err := parseTmpfsSize(size)
+	if err != nil {
+		return nil, err
+	}
+	return NewCommonDefaultUpdaterWithUpdateFunc(mountPath, mountPath, strconv.FormatInt(sizeBytes, 10), TmpfsSizeUpdateFunc, nil)
+}
+
+// parseTmpfsSize parses the size quantity into bytes. The zero size is rejected since the tmpfs takes it as unlimited.
+func parseTmpfsSize(size string) (int64, error) {
+	q, err := resource.ParseQuantity(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tmpfs size %q, err: %v", size, err)
+	}
+	sizeBytes := q.Value()
+	if sizeBytes <= 0 {
+		return 0, fmt.Errorf("invalid tmpfs size %q, should be positive", size)
+	}
+	return sizeBytes, nil
+}
+
+// TmpfsSizeUpdateFunc remounts the tmpfs with the size of the updater, and keeps the other per-mount flags. It skips
+// the remount if the size is unchanged.
+func TmpfsSizeUpdateFunc(resource ResourceUpdater) error {
+	c := resource.(*DefaultResourceUpdater)
+	var st unix.Statfs_t
+	if err := tmpfsStatfs(c.file, &st); err != nil {
+		return fmt.Errorf("failed to statfs %s, err: %w", c.file, err)
+	}
+	if st.Type != unix.TMPFS_MAGIC {
+		return fmt.Errorf("failed to update tmpfs size of %s, not a tmpfs, type 0x%x", c.file, st.Type)
+	}
+	sizeBytes, err := strconv.ParseInt(c.value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid tmpfs size %q, err: %v", c.value, err)
+	}
+	// the tmpfs rounds the size up to the blocks
+	if blockSize := int64(st.Bsize); blockSize > 0 && int64(st.Blocks)*blockSize == (sizeBytes+blockSize-1)/blockSize*blockSize {
+		klog.V(6).Infof("skip remounting tmpfs %s, size %v unchanged", c.file, c.value)
+		return nil
+	}
+
+	flags := uintptr(unix.MS_REMOUNT)
+	for stFlag, msFlag := range tmpfsRemountPreservedFlags {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	if err = tmpfsRemount(c.file, flags, "size="+c.value); err != nil {
+		switch {
+		case errors.Is(err, unix.EPERM):
+			return fmt.Errorf("failed to remount tmpfs %s, permission denied, err: %w", c.file, err)
+		case errors.Is(err, unix.EINVAL):
+			// e.g. shrinking the size below the usage
+			return fmt.Errorf("failed to remount tmpfs %s with size %v, invalid size or not a mount point, err: %w",
+				c.file, c.value, err)
+		default:
+			return fmt.Errorf("failed to remount tmpfs %s, err: %w", c.file, err)
+		}
+	}
+	klog.V(5).Infof("remount tmpfs %s with size %v", c.file, c.value)
+	auditUpdate(c.eventHelper, ReasonUpdateSystemConfig, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
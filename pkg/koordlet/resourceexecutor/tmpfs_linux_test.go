@@ -0,0 +1,169 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func Test_parseTmpfsSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name: "parse binary quantity",
+			size: "1Gi",
+			want: 1 << 30,
+		},
+		{
+			name: "parse decimal quantity",
+			size: "100M",
+			want: 100000000,
+		},
+		{
+			name: "parse bytes",
+			size: "4096",
+			want: 4096,
+		},
+		{
+			name:    "reject zero size",
+			size:    "0",
+			wantErr: true,
+		},
+		{
+			name:    "reject negative size",
+			size:    "-1Mi",
+			wantErr: true,
+		},
+		{
+			name:    "reject invalid quantity",
+			size:    "1GG",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := parseTmpfsSize(tt.size)
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTmpfsSizeUpdater(t *testing.T) {
+	type remountArgs struct {
+		mountPath string
+		flags     uintptr
+		data      string
+	}
+	tests := []struct {
+		name        string
+		mountPath   string
+		size        string
+		statfs      unix.Statfs_t
+		remountErr  error
+		wantNewErr  bool
+		wantErr     bool
+		wantRemount *remountArgs
+	}{
+		{
+			name:      "remount with the new size",
+			mountPath: "/var/lib/kubelet/pods/pod1/volumes/kubernetes.io~empty-dir/cache",
+			size:      "64Mi",
+			statfs:    unix.Statfs_t{Type: unix.TMPFS_MAGIC, Bsize: 4096, Blocks: 4096, Flags: unix.ST_NOSUID | unix.ST_NODEV | unix.ST_RELATIME},
+			wantRemount: &remountArgs{
+				mountPath: "/var/lib/kubelet/pods/pod1/volumes/kubernetes.io~empty-dir/cache",
+				flags:     unix.MS_REMOUNT | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_RELATIME,
+				data:      "size=67108864",
+			},
+		},
+		{
+			name:      "skip remount when the size is unchanged",
+			mountPath: "/dev/shm",
+			size:      "16Mi",
+			statfs:    unix.Statfs_t{Type: unix.TMPFS_MAGIC, Bsize: 4096, Blocks: 4096},
+		},
+		{
+			name:       "failed to remount",
+			mountPath:  "/dev/shm",
+			size:       "1Mi",
+			statfs:     unix.Statfs_t{Type: unix.TMPFS_MAGIC, Bsize: 4096, Blocks: 4096},
+			remountErr: unix.EINVAL,
+			wantErr:    true,
+			wantRemount: &remountArgs{
+				mountPath: "/dev/shm",
+				flags:     unix.MS_REMOUNT,
+				data:      "size=1048576",
+			},
+		},
+		{
+			name:      "not a tmpfs",
+			mountPath: "/var/lib/data",
+			size:      "1Mi",
+			statfs:    unix.Statfs_t{Type: unix.EXT4_SUPER_MAGIC, Bsize: 4096, Blocks: 4096},
+			wantErr:   true,
+		},
+		{
+			name:       "invalid mount path",
+			mountPath:  "dev/shm",
+			size:       "1Mi",
+			wantNewErr: true,
+		},
+		{
+			name:       "invalid size",
+			mountPath:  "/dev/shm",
+			size:       "1xx",
+			wantNewErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStatfs, oldRemount := tmpfsStatfs, tmpfsRemount
+			defer func() {
+				tmpfsStatfs, tmpfsRemount = oldStatfs, oldRemount
+			}()
+			tmpfsStatfs = func(path string, buf *unix.Statfs_t) error {
+				assert.Equal(t, tt.mountPath, path)
+				*buf = tt.statfs
+				return nil
+			}
+			var gotRemount *remountArgs
+			tmpfsRemount = func(mountPath string, flags uintptr, data string) error {
+				gotRemount = &remountArgs{mountPath: mountPath, flags: flags, data: data}
+				return tt.remountErr
+			}
+
+			u, gotErr := NewTmpfsSizeUpdater(tt.mountPath, tt.size)
+			assert.Equal(t, tt.wantNewErr, gotErr != nil, gotErr)
+			if tt.wantNewErr {
+				return
+			}
+			gotErr = u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantRemount, gotRemount)
+		})
+	}
+}
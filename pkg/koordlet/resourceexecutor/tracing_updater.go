@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+const (
+	TracingAttributePath  = "koordlet.resource.path"
+	TracingAttributeValue = "koordlet.resource.value"
+)
+
+var _ ResourceUpdater = &TracingResourceUpdater{}
+
+// TracingResourceUpdater decorates a ResourceUpdater to start a span named by the resource type around each Update
+// and MergeUpdate.
+type TracingResourceUpdater struct {
+	// ctx carries the parent span of the spans, e.g. the span of the reconcile issuing the update.
+	ctx    context.Context
+	inner  ResourceUpdater
+	tracer trace.Tracer
+}
+
+// NewTracingUpdater returns a ResourceUpdater which emits a span per write with the given tracer. The spans are the
+// children of the span in the ctx, so the writes are linked to the caller's trace, while they are the root spans if
+// the ctx is nil or carries no span. It returns the inner updater directly when the tracer is nil, so there is no
+// overhead if the tracing is not configured.
+func NewTracingUpdater(ctx context.Context, inner ResourceUpdater, tracer trace.Tracer) ResourceUpdater {
+	if tracer == nil {
+		return inner
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &TracingResourceUpdater{
+		ctx:    ctx,
+		inner:  inner,
+		tracer: tracer,
+	}
+}
+
+func (u *TracingResourceUpdater) ResourceType() sysutil.ResourceType {
+	return u.inner.ResourceType()
+}
+
+func (u *TracingResourceUpdater) Key() string {
+	return u.inner.Key()
+}
+
+func (u *TracingResourceUpdater) Path() string {
+	return u.inner.Path()
+}
+
+// Paths returns the paths of the inner updater.
+func (u *TracingResourceUpdater) Paths() []string {
+	return PlannedPaths([]ResourceUpdater{u.inner})
+}
+
+func (u *TracingResourceUpdater) Value() string {
+	return u.inner.Value()
+}
+
+func (u *TracingResourceUpdater) IsMergeable() bool {
+	return u.inner.IsMergeable()
+}
+
+func (u *TracingResourceUpdater) MergeUpdate() (ResourceUpdater, error) {
+	span := u.startSpan()
+	merged, err := u.inner.MergeUpdate()
+	endSpan(span, err)
+	if merged == nil {
+		return nil, err
+	}
+	return NewTracingUpdater(u.ctx, merged, u.tracer), err
+}
+
+func (u *TracingResourceUpdater) Clone() ResourceUpdater {
+	return NewTracingUpdater(u.ctx, u.inner.Clone(), u.tracer)
+}
+
+func (u *TracingResourceUpdater) GetLastUpdateTimestamp() time.Time {
+	return u.inner.GetLastUpdateTimestamp()
+}
+
+func (u *TracingResourceUpdater) UpdateLastUpdateTimestamp(time time.Time) {
+	u.inner.UpdateLastUpdateTimestamp(time)
+}
+
+func (u *TracingResourceUpdater) update() error {
+	span := u.startSpan()
+	err := u.inner.update()
+	endSpan(span, err)
+	return err
+}
+
+func (u *TracingResourceUpdater) startSpan() trace.Span {
+	_, span := u.tracer.Start(u.ctx, string(u.inner.ResourceType()))
+	span.SetAttributes(attribute.String(TracingAttributePath, u.inner.Path()),
+		attribute.String(TracingAttributeValue, u.inner.Value()))
+	return span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
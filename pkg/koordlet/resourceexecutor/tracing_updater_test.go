@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestNewTracingUpdater(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	inner, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+
+	// no tracer configured
+	assert.Equal(t, inner, NewTracingUpdater(context.Background(), inner, nil))
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+	u := NewTracingUpdater(nil, inner, tracer)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, sysutil.CPUCFSQuotaName, spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, attribute.String(TracingAttributePath, inner.Path()))
+	assert.Contains(t, spans[0].Attributes, attribute.String(TracingAttributeValue, "20000"))
+	assert.Equal(t, codes.Unset, spans[0].StatusCode)
+	assert.False(t, spans[0].Parent.IsValid())
+
+	// record the error
+	exporter.Reset()
+	failed, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice/not-exist", "20000", nil)
+	assert.NoError(t, err)
+	u = NewTracingUpdater(context.Background(), failed, tracer)
+	_, err = u.MergeUpdate()
+	assert.Error(t, err)
+	spans = exporter.GetSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, codes.Error, spans[0].StatusCode)
+
+	// the spans of the updater and its clones are the children of the span in the ctx
+	exporter.Reset()
+	ctx, parent := tracer.Start(context.Background(), "reconcile")
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "30000", nil)
+	assert.NoError(t, err)
+	u = NewTracingUpdater(ctx, u, tracer)
+	assert.NoError(t, u.update())
+	assert.NoError(t, u.Clone().update())
+	parent.End()
+	spans = exporter.GetSpans()
+	assert.Equal(t, 3, len(spans))
+	for _, span := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+	}
+}
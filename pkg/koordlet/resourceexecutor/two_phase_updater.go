@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+var _ ResourceUpdater = &TwoPhaseResourceUpdater{}
+
+// TwoPhaseResourceUpdater writes a prepare knob before the main one for the knobs which take effect only after an
+// enable flag is written, e.g. enabling a controller feature then setting its parameter. If the main write fails, the
+// prepare knob is rolled back to the value before the update. The updater is identified as the main updater.
+type TwoPhaseResourceUpdater struct {
+	prepare             ResourceUpdater
+	main                ResourceUpdater
+	lastUpdateTimestamp time.Time
+}
+
+// NewTwoPhaseUpdater returns a TwoPhaseResourceUpdater which writes the prepare updater and then the main updater.
+// The prepare updater should be a cgroup updater or a default updater to snapshot its current value for the rollback.
+func NewTwoPhaseUpdater(prepare, main ResourceUpdater) *TwoPhaseResourceUpdater {
+	return &TwoPhaseResourceUpdater{
+		prepare: prepare,
+		main:    main,
+	}
+}
+
+func (u *TwoPhaseResourceUpdater) ResourceType() sysutil.ResourceType {
+	return u.main.ResourceType()
+}
+
+func (u *TwoPhaseResourceUpdater) Key() string {
+	return u.main.Key()
+}
+
+func (u *TwoPhaseResourceUpdater) Path() string {
+	return u.main.Path()
+}
+
+// Paths returns the paths of the prepare and the main updaters.
+func (u *TwoPhaseResourceUpdater) Paths() []string {
+	return append(PlannedPaths([]ResourceUpdater{u.prepare}), PlannedPaths([]ResourceUpdater{u.main})...)
+}
+
+func (u *TwoPhaseResourceUpdater) Value() string {
+	return u.main.Value()
+}
+
+func (u *TwoPhaseResourceUpdater) IsMergeable() bool {
+	return false
+}
+
+func (u *TwoPhaseResourceUpdater) MergeUpdate() (ResourceUpdater, error) {
+	return nil, u.update()
+}
+
+func (u *TwoPhaseResourceUpdater) Clone() ResourceUpdater {
+	return &TwoPhaseResourceUpdater{
+		prepare:             u.prepare.Clone(),
+		main:                u.main.Clone(),
+		lastUpdateTimestamp: u.lastUpdateTimestamp,
+	}
+}
+
+func (u *TwoPhaseResourceUpdater) GetLastUpdateTimestamp() time.Time {
+	return u.lastUpdateTimestamp
+}
+
+func (u *TwoPhaseResourceUpdater) UpdateLastUpdateTimestamp(time time.Time) {
+	u.lastUpdateTimestamp = time
+}
+
+func (u *TwoPhaseResourceUpdater) update() error {
+	previous, err := snapshotUpdater(u.prepare)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot the prepare %s, err: %w", u.prepare.Path(), err)
+	}
+	if err = u.prepare.update(); err != nil {
+		return fmt.Errorf("failed to prepare %s to %v, err: %w", u.prepare.Path(), u.prepare.Value(), err)
+	}
+	if err = u.main.update(); err != nil {
+		err = fmt.Errorf("failed to update %s to %v, err: %w", u.main.Path(), u.main.Value(), err)
+		return utilerrors.NewAggregate([]error{err, rollbackUpdaters([]ResourceUpdater{previous})})
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestTwoPhaseResourceUpdater(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "-1")
+	mainPath := filepath.Join(helper.TempDir, "main")
+	helper.WriteFileContents(mainPath, "0")
+
+	newPrepare := func() ResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "10000", nil)
+		assert.NoError(t, err)
+		return u
+	}
+
+	// the main write fails and the prepare is rolled back
+	brokenMain, err := NewCommonDefaultUpdater("main", filepath.Join(helper.TempDir, "not-exist", "main"), "1", nil)
+	assert.NoError(t, err)
+	u := NewTwoPhaseUpdater(newPrepare(), brokenMain)
+	assert.Equal(t, brokenMain.Path(), u.Path())
+	assert.Error(t, u.update())
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// both phases succeed
+	main, err := NewCommonDefaultUpdater("main", mainPath, "1", nil)
+	assert.NoError(t, err)
+	u = NewTwoPhaseUpdater(newPrepare(), main)
+	assert.NoError(t, u.Clone().update())
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "1", helper.ReadFileContents(mainPath))
+
+	// the prepare fails and the main is not written
+	prepare, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice/not-exist.slice", "10000", nil)
+	assert.NoError(t, err)
+	main, err = NewCommonDefaultUpdater("main", mainPath, "2", nil)
+	assert.NoError(t, err)
+	assert.Error(t, NewTwoPhaseUpdater(prepare, main).update())
+	assert.Equal(t, "1", helper.ReadFileContents(mainPath))
+}
@@ -19,10 +19,16 @@ package resourceexecutor
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -46,14 +52,18 @@ func init() {
 	DefaultCgroupUpdaterFactory.Register(NewCommonCgroupUpdater,
 		sysutil.CPUBurstName,
 		sysutil.CPUBVTWarpNsName,
+		sysutil.CPUIdleName,
 		sysutil.CPUTasksName,
 		sysutil.CPUProcsName,
+		sysutil.CPUWeightNiceName,
 		sysutil.MemoryWmarkRatioName,
 		sysutil.MemoryWmarkScaleFactorName,
 		sysutil.MemoryWmarkMinAdjName,
 		sysutil.MemoryPriorityName,
 		sysutil.MemoryUsePriorityOomName,
 		sysutil.MemoryOomGroupName,
+		sysutil.MemorySwappinessName,
+		sysutil.IOPrioClassName,
 	)
 	// special cases
 	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateCPUSharesFunc), sysutil.CPUSharesName)
@@ -65,9 +75,10 @@ func init() {
 		sysutil.MemoryLowName,
 		sysutil.MemoryHighName,
 	)
-	DefaultCgroupUpdaterFactory.Register(NewMergeableCgroupUpdaterWithConditionFunc(CommonCgroupUpdateFunc, MergeConditionIfCPUSetIsLooser),
+	DefaultCgroupUpdaterFactory.Register(NewMergeableCgroupUpdaterWithConditionFunc(CgroupUpdateCPUSetFunc, MergeConditionNonEmptyCPUSet(MergeConditionIfCPUSetIsLooser)),
 		sysutil.CPUSetCPUSName,
 	)
+	DefaultCgroupUpdaterFactory.Register(NewCPUSetExclusiveUpdater, sysutil.CPUSetCPUSExclusiveName)
 	DefaultCgroupUpdaterFactory.Register(NewBlkIOResourceUpdater,
 		sysutil.BlkioTRIopsName,
 		sysutil.BlkioTRBpsName,
@@ -76,6 +87,23 @@ func init() {
 		sysutil.BlkioIOQoSName,
 		sysutil.BlkioIOWeightName,
 	)
+	DefaultCgroupUpdaterFactory.Register(NewMergeableCgroupUpdaterWithConditionFunc(CgroupUpdatePidsMaxFunc, MergeConditionIfValueIsSmaller),
+		sysutil.PidsMaxName,
+	)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateIOWeightFunc), sysutil.BlkioWeightName)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateBlkioWeightDeviceFunc), sysutil.BlkioWeightDeviceName)
+	DefaultCgroupUpdaterFactory.Register(NewBlkioBFQWeightUpdater, sysutil.BlkioBFQWeightName)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateIOLatencyFunc), sysutil.IOLatencyName)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupLineWriteFunc), sysutil.IOMaxName)
+	DefaultCgroupUpdaterFactory.Register(NewIOCostUpdater, sysutil.IOCostQoSName, sysutil.IOCostModelName)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateMemoryReclaimFunc), sysutil.MemoryReclaimName)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateFreezerFunc), sysutil.FreezerStateName)
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupUpdateSchedLoadBalanceFunc), sysutil.CPUSetSchedLoadBalanceName)
+	// write-only interfaces
+	DefaultCgroupUpdaterFactory.Register(NewCgroupUpdaterWithUpdateFunc(CgroupWriteOnlyUpdateFunc),
+		sysutil.DevicesAllowName,
+		sysutil.DevicesDenyName,
+	)
 }
 
 type UpdateFunc func(resource ResourceUpdater) error
@@ -88,12 +116,26 @@ type ResourceUpdater interface {
 	Path() string
 	Value() string
 	MergeUpdate() (ResourceUpdater, error)
+	// IsMergeable returns whether the MergeUpdate merges the value with the current one, which helps to plan the
+	// leveled batches without the type assertions.
+	IsMergeable() bool
 	Clone() ResourceUpdater
 	GetLastUpdateTimestamp() time.Time
 	UpdateLastUpdateTimestamp(time time.Time)
 	update() error
 }
 
+// CacheKey returns the key of the updater for the cache maps, which combines the updater kind, the resource type and
+// the path. So the updaters of the same path but different merge semantics never collide, e.g. a common updater and
+// a mergeable updater of the `cpuset.cpus`.
+func CacheKey(u ResourceUpdater) string {
+	kind := fmt.Sprintf("%T", u)
+	if u.IsMergeable() {
+		kind += "/mergeable"
+	}
+	return fmt.Sprintf("%s|%s|%s", kind, u.ResourceType(), u.Path())
+}
+
 type CgroupResourceUpdater struct {
 	file      sysutil.Resource
 	parentDir string
@@ -108,7 +150,18 @@ type CgroupResourceUpdater struct {
 	// 2. update each cgroup resource by the order of layers: firstly update resources from upper to lower by merging
 	//    the new value with old value; then update resources from lower to upper with the new value.
 	mergeUpdateFunc MergeUpdateFunc
-	eventHelper     *audit.EventHelper
+	// mergeCondition is the merge condition of the mergeUpdateFunc, which is used to check the merge without writing.
+	mergeCondition MergeConditionFunc
+	eventHelper    *audit.EventHelper
+	// auditTags is the metadata attached to the audit events, e.g. the pod UID of the reconcile.
+	auditTags map[string]string
+	// owner is the module which initiates the update, e.g. a koordlet plugin, for the metrics attribution.
+	owner string
+	// state is the state of the current update.
+	state updateState
+	// writeBudgetExempt indicates the writes are not limited by the write budget, e.g. the restoring writes of the
+	// rollbacks.
+	writeBudgetExempt bool
 }
 
 func (u *CgroupResourceUpdater) ResourceType() sysutil.ResourceType {
@@ -127,8 +180,55 @@ func (u *CgroupResourceUpdater) Value() string {
 	return u.value
 }
 
+// updateState is the state of an update of the updater, which is reset at the beginning of each update.
+type updateState struct {
+	// budgetCharged indicates the write budget is charged.
+	budgetCharged bool
+	// writes are the writes issued, where the skipped writes are not included.
+	writes []issuedWrite
+	// value is the value to write adjusted by the options, e.g. clamped to the parent ceiling, which is nil if the
+	// requested value is written.
+	value *string
+}
+
+// valueToWrite returns the value to write in the current update. The options adjust the value to write instead of the
+// requested value, so the updater keeps the requested value for the cache, the retries and the next updates.
+func (u *CgroupResourceUpdater) valueToWrite() string {
+	if u.state.value != nil {
+		return *u.state.value
+	}
+	return u.value
+}
+
+// setValueToWrite sets the value to write in the current update, which is reset when the update finishes.
+func (u *CgroupResourceUpdater) setValueToWrite(value string) {
+	u.state.value = &value
+}
+
+// issuedWrite is a write issued to the file, whose value is the one actually written, e.g. the merged value or the
+// converted `cpu.weight`.
+type issuedWrite struct {
+	resourceType sysutil.ResourceType
+	path         string
+	value        string
+	err          error
+}
+
 func (u *CgroupResourceUpdater) update() error {
-	return u.updateFunc(u)
+	start := time.Now()
+	u.state = updateState{}
+	err := u.updateFunc(u)
+	recordResourceUpdate(u.ResourceType(), u.owner, start, len(u.state.writes) > 0, err)
+	u.recordIssuedWrites()
+	return err
+}
+
+// recordIssuedWrites records the writes issued in the current update into the write history and resets the state.
+func (u *CgroupResourceUpdater) recordIssuedWrites() {
+	for _, w := range u.state.writes {
+		recordWriteHistory(w)
+	}
+	u.state = updateState{}
 }
 
 func (u *CgroupResourceUpdater) GetEventHelper() *audit.EventHelper {
@@ -139,11 +239,23 @@ func (u *CgroupResourceUpdater) SetEventHelper(a *audit.EventHelper) {
 	u.eventHelper = a
 }
 
+func (u *CgroupResourceUpdater) IsMergeable() bool {
+	return u.mergeUpdateFunc != nil
+}
+
 func (u *CgroupResourceUpdater) MergeUpdate() (ResourceUpdater, error) {
+	start := time.Now()
+	u.state = updateState{}
 	if u.mergeUpdateFunc == nil {
-		return nil, u.updateFunc(u)
+		err := u.updateFunc(u)
+		recordResourceUpdate(u.ResourceType(), u.owner, start, len(u.state.writes) > 0, err)
+		u.recordIssuedWrites()
+		return nil, err
 	}
-	return u.mergeUpdateFunc(u)
+	merged, err := u.mergeUpdateFunc(u)
+	recordResourceUpdate(u.ResourceType(), u.owner, start, len(u.state.writes) > 0, err)
+	u.recordIssuedWrites()
+	return merged, err
 }
 
 func (u *CgroupResourceUpdater) Clone() ResourceUpdater {
@@ -154,10 +266,18 @@ func (u *CgroupResourceUpdater) Clone() ResourceUpdater {
 		lastUpdateTimestamp: u.lastUpdateTimestamp,
 		updateFunc:          u.updateFunc,
 		mergeUpdateFunc:     u.mergeUpdateFunc,
+		mergeCondition:      u.mergeCondition,
 		eventHelper:         u.eventHelper,
+		auditTags:           u.auditTags,
+		owner:               u.owner,
 	}
 }
 
+// CloneWithValue returns a clone of the updater with the given value, whose last update timestamp is reset.
+func (u *CgroupResourceUpdater) CloneWithValue(value string) ResourceUpdater {
+	return cloneCgroupUpdaterWithValue(u, value, u.eventHelper)
+}
+
 func (u *CgroupResourceUpdater) GetLastUpdateTimestamp() time.Time {
 	return u.lastUpdateTimestamp
 }
@@ -166,6 +286,88 @@ func (u *CgroupResourceUpdater) UpdateLastUpdateTimestamp(time time.Time) {
 	u.lastUpdateTimestamp = time
 }
 
+// ParentDir returns the parent directory of the cgroup resource.
+func (u *CgroupResourceUpdater) ParentDir() string {
+	return u.parentDir
+}
+
+// WithParentDir returns a clone of the updater with the given parent directory.
+func (u *CgroupResourceUpdater) WithParentDir(dir string) ResourceUpdater {
+	c := u.Clone().(*CgroupResourceUpdater)
+	c.parentDir = dir
+	return c
+}
+
+// ReadCurrent reads the current value of the cgroup file.
+func (u *CgroupResourceUpdater) ReadCurrent() (string, error) {
+	return cgroupFileRead(u.parentDir, u.file)
+}
+
+// ReadCurrentNormalized reads the current value of the cgroup file and normalizes it into the canonical form of the
+// resource type. e.g. "max\n" of `memory.max` is normalized into "-1".
+func (u *CgroupResourceUpdater) ReadCurrentNormalized() (string, error) {
+	v, err := u.ReadCurrent()
+	if err != nil {
+		return "", err
+	}
+	return NormalizeCgroupValue(u.ResourceType(), v), nil
+}
+
+// ReadCurrentInt64 reads the current value of the cgroup file and parses it into an int64. The unlimited values like
+// "max" are parsed as -1.
+func (u *CgroupResourceUpdater) ReadCurrentInt64() (int64, error) {
+	v, err := u.ReadCurrentNormalized()
+	if err != nil {
+		return -1, err
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("cannot parse cgroup %s value %q as int64, err: %v", u.Path(), v, err)
+	}
+	return i, nil
+}
+
+// ReadCurrentCPUSet reads the current value of the cgroup file and parses it into a cpuset, e.g. `cpuset.cpus`.
+func (u *CgroupResourceUpdater) ReadCurrentCPUSet() (cpuset.CPUSet, error) {
+	v, err := u.ReadCurrent()
+	if err != nil {
+		return cpuset.NewCPUSet(), err
+	}
+	cpus, err := cpuset.Parse(strings.TrimSpace(v))
+	if err != nil {
+		return cpuset.NewCPUSet(), fmt.Errorf("cannot parse cgroup %s value %q as cpuset, err: %v", u.Path(), v, err)
+	}
+	return cpus, nil
+}
+
+// WouldChange checks whether the update would change the current value without writing. For the mergeable updater,
+// it checks the merge condition with the current value. The value is converted and compared as the write path does,
+// e.g. the `cpu.shares` is compared with the `cpu.weight` on the cgroups-v2.
+func (u *CgroupResourceUpdater) WouldChange() (bool, error) {
+	return u.wouldChange(u.mergeUpdateFunc != nil)
+}
+
+// wouldChange checks whether the update would change the current value, where the merge indicates the check is for
+// the MergeUpdate instead of the update.
+func (u *CgroupResourceUpdater) wouldChange(merge bool) (bool, error) {
+	currentValue, err := cgroupFileRead(u.parentDir, u.file)
+	if err != nil {
+		return false, err
+	}
+	value, err := ConvertCgroupValue(u.file, u.valueToWrite())
+	if err != nil {
+		return false, err
+	}
+	if merge && u.mergeCondition != nil {
+		_, needMerge, err := u.mergeCondition(currentValue, value)
+		if err != nil {
+			return false, err
+		}
+		return needMerge, nil
+	}
+	return !isCgroupValueEqual(u.file, currentValue, value), nil
+}
+
 func (u *CgroupResourceUpdater) WithUpdateFunc(updateFunc UpdateFunc) *CgroupResourceUpdater {
 	u.updateFunc = updateFunc
 	return u
@@ -183,6 +385,11 @@ type DefaultResourceUpdater struct {
 	lastUpdateTimestamp time.Time
 	updateFunc          UpdateFunc
 	eventHelper         *audit.EventHelper
+	auditTags           map[string]string
+	// writeBudgetExempt indicates the write is not limited by the write budget, e.g. the restoring write of a rollback.
+	writeBudgetExempt bool
+	// writes are the writes issued in the current update.
+	writes []issuedWrite
 }
 
 func (u *DefaultResourceUpdater) ResourceType() sysutil.ResourceType {
@@ -202,11 +409,21 @@ func (u *DefaultResourceUpdater) Value() string {
 }
 
 func (u *DefaultResourceUpdater) update() error {
-	return u.updateFunc(u)
+	u.writes = nil
+	err := u.updateFunc(u)
+	for _, w := range u.writes {
+		recordWriteHistory(w)
+	}
+	u.writes = nil
+	return err
+}
+
+func (u *DefaultResourceUpdater) IsMergeable() bool {
+	return false
 }
 
 func (u *DefaultResourceUpdater) MergeUpdate() (ResourceUpdater, error) {
-	return nil, u.updateFunc(u)
+	return nil, u.update()
 }
 
 func (u *DefaultResourceUpdater) Clone() ResourceUpdater {
@@ -217,7 +434,42 @@ func (u *DefaultResourceUpdater) Clone() ResourceUpdater {
 		lastUpdateTimestamp: u.lastUpdateTimestamp,
 		updateFunc:          u.updateFunc,
 		eventHelper:         u.eventHelper,
+		auditTags:           u.auditTags,
+	}
+}
+
+// CloneWithValue returns a clone of the updater with the given value, whose last update timestamp is reset.
+func (u *DefaultResourceUpdater) CloneWithValue(value string) ResourceUpdater {
+	c := u.Clone().(*DefaultResourceUpdater)
+	c.value = value
+	c.lastUpdateTimestamp = time.Time{}
+	return c
+}
+
+// ParentDir returns the directory of the file.
+func (u *DefaultResourceUpdater) ParentDir() string {
+	return filepath.Dir(u.file)
+}
+
+// WithParentDir returns a clone of the updater with the file moved into the given directory. The key is also updated
+// if it is the filepath.
+func (u *DefaultResourceUpdater) WithParentDir(dir string) ResourceUpdater {
+	c := u.Clone().(*DefaultResourceUpdater)
+	c.file = filepath.Join(dir, filepath.Base(u.file))
+	if u.key == u.file {
+		c.key = c.file
 	}
+	return c
+}
+
+// WouldChange checks whether the update would change the current value without writing. The values are compared as
+// the CommonFileWriteIfDifferent does.
+func (u *DefaultResourceUpdater) WouldChange() (bool, error) {
+	currentValue, err := sysutil.CommonFileRead(u.Path())
+	if err != nil {
+		return false, err
+	}
+	return currentValue != u.value, nil
 }
 
 func (u *DefaultResourceUpdater) GetLastUpdateTimestamp() time.Time {
@@ -257,7 +509,13 @@ type ResourceUpdaterFactory interface {
 }
 
 func NewCgroupUpdater(resourceType sysutil.ResourceType, parentDir string, value string, updateFunc UpdateFunc, e *audit.EventHelper) (ResourceUpdater, error) {
-	r, err := sysutil.GetCgroupResource(resourceType)
+	return NewCgroupUpdaterForVersion(sysutil.GetCurrentCgroupVersion(), resourceType, parentDir, value, updateFunc, e)
+}
+
+// NewCgroupUpdaterForVersion returns a CgroupResourceUpdater of the resource in the given cgroup version instead of
+// the detected one, e.g. for the tooling to generate the cgroups-v2 updaters on a cgroups-v1 host.
+func NewCgroupUpdaterForVersion(version sysutil.CgroupVersion, resourceType sysutil.ResourceType, parentDir string, value string, updateFunc UpdateFunc, e *audit.EventHelper) (ResourceUpdater, error) {
+	r, err := sysutil.GetCgroupResourceForVersion(version, resourceType)
 	if err != nil {
 		return nil, err
 	}
@@ -281,6 +539,12 @@ func NewCommonCgroupUpdater(resourceType sysutil.ResourceType, parentDir string,
 	return NewCgroupUpdaterWithUpdateFunc(CommonCgroupUpdateFunc)(resourceType, parentDir, value, e)
 }
 
+// NewCommonCgroupUpdaterForVersion returns a CgroupResourceUpdater for updating the known cgroup resource in the given
+// cgroup version instead of the detected one.
+func NewCommonCgroupUpdaterForVersion(version sysutil.CgroupVersion, resourceType sysutil.ResourceType, parentDir string, value string) (ResourceUpdater, error) {
+	return NewCgroupUpdaterForVersion(version, resourceType, parentDir, value, CommonCgroupUpdateFunc, nil)
+}
+
 func NewMergeableCgroupUpdaterWithCondition(resourceType sysutil.ResourceType, parentDir string, value string, updateFunc UpdateFunc, mergeCondition MergeConditionFunc, e *audit.EventHelper) (ResourceUpdater, error) {
 	r, err := sysutil.GetCgroupResource(resourceType)
 	if err != nil {
@@ -294,7 +558,8 @@ func NewMergeableCgroupUpdaterWithCondition(resourceType sysutil.ResourceType, p
 		mergeUpdateFunc: func(resource ResourceUpdater) (ResourceUpdater, error) {
 			return MergeFuncUpdateCgroup(resource, mergeCondition)
 		},
-		eventHelper: e,
+		mergeCondition: mergeCondition,
+		eventHelper:    e,
 	}, nil
 }
 
@@ -308,6 +573,23 @@ func NewMergeableCgroupUpdaterIfValueLarger(resourceType sysutil.ResourceType, p
 	return NewMergeableCgroupUpdaterWithConditionFunc(CommonCgroupUpdateFunc, MergeConditionIfValueIsLarger)(resourceType, parentDir, value, e)
 }
 
+// MergeSelectorFunc selects the merge policy by the caller-provided metadata, e.g. the labels and annotations of the
+// pod. It returns true to merge looser, otherwise to merge tighter.
+type MergeSelectorFunc func(metadata map[string]string) bool
+
+// NewSelectiveMergeCgroupUpdaterFunc returns a constructor of the mergeable updaters which pick the looser or tighter
+// merge condition by the selector over the metadata, so the merge policy depending on the pod metadata is kept in one
+// place, e.g. only broaden the cpuset for the guaranteed pods. The selector is evaluated at the merge time.
+func NewSelectiveMergeCgroupUpdaterFunc(updateFn UpdateFunc, looser, tighter MergeConditionFunc, selector MergeSelectorFunc, metadata map[string]string) NewResourceUpdaterFunc {
+	mergeCondition := func(oldValue, newValue string) (string, bool, error) {
+		if selector(metadata) {
+			return looser(oldValue, newValue)
+		}
+		return tighter(oldValue, newValue)
+	}
+	return NewMergeableCgroupUpdaterWithConditionFunc(updateFn, mergeCondition)
+}
+
 // NewDetailCgroupUpdater returns a new *CgroupResourceUpdater according to the given Resource, which is generally used
 // for backwards compatibility. It is not guaranteed for updating successfully since it does not retrieve from the
 // known cgroup resources.
@@ -348,6 +630,7 @@ func (f *CgroupUpdaterFactoryImpl) Register(g NewResourceUpdaterFunc, resourceTy
 func (f *CgroupUpdaterFactoryImpl) New(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
 	f.lock.RLock()
 	defer f.lock.RUnlock()
+	resourceType = ResolveAlias(resourceType)
 	g, ok := f.registry[resourceType]
 	if !ok {
 		return nil, fmt.Errorf("resource type %s not registered", resourceType)
@@ -369,8 +652,8 @@ func CgroupUpdateWithUnlimitedFunc(resource ResourceUpdater) error {
 	c := resource.(*CgroupResourceUpdater)
 	// NOTE: convert "-1" to "max", since some cgroups-v2 files only accept "max" to unlimit resource instead of "-1".
 	//       DO NOT use it on the cgroups which has a valid value of "-1".
-	if c.value == sysutil.CgroupUnlimitedSymbolStr && sysutil.GetCurrentCgroupVersion() == sysutil.CgroupVersionV2 {
-		c.value = sysutil.CgroupMaxSymbolStr
+	if c.valueToWrite() == sysutil.CgroupUnlimitedSymbolStr && sysutil.IsCgroupV2Resource(c.file) {
+		return cgroupWriteValueIfDifferentWithLog(c, sysutil.CgroupMaxSymbolStr)
 	}
 	return cgroupWriteIfDifferentWithLog(c)
 }
@@ -378,22 +661,555 @@ func CgroupUpdateWithUnlimitedFunc(resource ResourceUpdater) error {
 func CgroupUpdateCPUSharesFunc(resource ResourceUpdater) error {
 	c := resource.(*CgroupResourceUpdater)
 	// convert values in `cpu.shares` (v1) into values in `cpu.weight` (v2)
+	if sysutil.IsCgroupV2Resource(c.file) {
+		// keep the requested value unchanged, so the retries and the rollbacks do not convert it twice
+		v, err := ConvertCgroupValue(c.file, c.valueToWrite())
+		if err != nil {
+			return err
+		}
+		return cgroupWriteValueIfDifferentWithLog(c, v)
+	}
+	return cgroupWriteIfDifferentWithLog(c)
+}
+
+// CgroupWriteOnlyUpdateFunc writes the cgroup file without reading the current value, which is used for the
+// write-only or append-style interfaces whose read does not return the written value, e.g. `devices.allow` returns
+// the full device list.
+func CgroupWriteOnlyUpdateFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if err := c.writeFile(c.file, c.valueToWrite()); err != nil {
+		return err
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+// CgroupUpdateMemoryReclaimFunc triggers the proactive reclaim of the given bytes by writing the cgroups-v2
+// `memory.reclaim`. The file is write-only, so the value is written without comparing to the current. The kernel
+// returns EAGAIN if less than the requested bytes are reclaimed, which is considered a success when the
+// `memory.current` drops.
+func CgroupUpdateMemoryReclaimFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	usageResource, err := sysutil.GetCgroupResource(sysutil.MemoryUsageName)
+	if err != nil {
+		return err
+	}
+	before, beforeErr := readCgroupAndParseInt64(c.parentDir, usageResource)
+	err = c.writeFile(c.file, c.valueToWrite())
+	after, afterErr := readCgroupAndParseInt64(c.parentDir, usageResource)
+	reclaimed := beforeErr == nil && afterErr == nil && after < before
+	if err != nil && !(ClassifyWriteError(err) == ErrorClassRetryable && reclaimed) {
+		return err
+	}
+	if reclaimed {
+		klog.V(5).Infof("memory reclaim %s of %v bytes, memory.current dropped from %d to %d, err: %v",
+			c.Path(), c.valueToWrite(), before, after, err)
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+var (
+	freezerStateToCgroupFreeze = map[string]string{
+		sysutil.FreezerStateFrozen: sysutil.CgroupFreezeFrozen,
+		sysutil.FreezerStateThawed: sysutil.CgroupFreezeThawed,
+	}
+	cgroupFreezeToFreezerState = map[string]string{
+		sysutil.CgroupFreezeFrozen: sysutil.FreezerStateFrozen,
+		sysutil.CgroupFreezeThawed: sysutil.FreezerStateThawed,
+	}
+)
+
+// CgroupUpdateFreezerFunc pauses or resumes the tasks of the cgroup, i.e. writes the `freezer.state` on cgroups-v1 and
+// the `cgroup.freeze` on cgroups-v2. Both the representations are accepted, "FROZEN"/"THAWED" of the v1 and "1"/"0" of
+// the v2, which are converted into the one of the current version.
+func CgroupUpdateFreezerFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if sysutil.IsCgroupV2Resource(c.file) {
+		if v, ok := freezerStateToCgroupFreeze[c.valueToWrite()]; ok {
+			c.setValueToWrite(v)
+		}
+	} else if v, ok := cgroupFreezeToFreezerState[c.valueToWrite()]; ok {
+		c.setValueToWrite(v)
+	}
+	return cgroupWriteIfDifferentWithLog(c)
+}
+
+// IsCgroupFrozen checks whether the cgroup is completely frozen. On cgroups-v1, the `freezer.self_freezing` confirms
+// the cgroup itself is requested to freeze, and the `freezer.state` turns "FROZEN" once all the tasks are frozen. On
+// cgroups-v2, the `cgroup.events` reports "frozen 1".
+func IsCgroupFrozen(parentDir string) (bool, error) {
 	if sysutil.GetCurrentCgroupVersion() == sysutil.CgroupVersionV2 {
-		v, err := sysutil.ConvertCPUSharesToWeight(c.value)
+		events, err := cgroupFileRead(parentDir, sysutil.CgroupEventsV2)
 		if err != nil {
+			return false, err
+		}
+		for _, line := range strings.Split(events, "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "frozen" {
+				return fields[1] == "1", nil
+			}
+		}
+		return false, fmt.Errorf("frozen not found in %s", sysutil.CgroupEventsV2.Path(parentDir))
+	}
+	selfFreezing, err := cgroupFileRead(parentDir, sysutil.FreezerSelfFreezing)
+	if err != nil {
+		return false, err
+	}
+	state, err := cgroupFileRead(parentDir, sysutil.FreezerState)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(selfFreezing) == "1" && strings.TrimSpace(state) == sysutil.FreezerStateFrozen, nil
+}
+
+// appendOnlyResources are the resources whose writes are appended instead of replacing the content, so a multi-line
+// value has to be written line by line, e.g. each line of `devices.allow` is a rule.
+var appendOnlyResources = map[sysutil.ResourceType]bool{
+	sysutil.DevicesAllowName: true,
+	sysutil.DevicesDenyName:  true,
+	sysutil.CPUProcsName:     true,
+	sysutil.CPUTasksName:     true,
+}
+
+// perDeviceResources are the device-keyed resources whose kernel parser handles one device per write, so a
+// multi-device value has to be written line by line, e.g. the `io.max` applies only the first line of a write.
+var perDeviceResources = map[sysutil.ResourceType]bool{
+	sysutil.IOMaxName:             true,
+	sysutil.IOLatencyName:         true,
+	sysutil.IOCostQoSName:         true,
+	sysutil.IOCostModelName:       true,
+	sysutil.BlkioWeightDeviceName: true,
+	sysutil.BlkioTRIopsName:       true,
+	sysutil.BlkioTRBpsName:        true,
+	sysutil.BlkioTWIopsName:       true,
+	sysutil.BlkioTWBpsName:        true,
+}
+
+// cgroupBufferedFileWrite writes the cgroup file for the CgroupBufferedWriteFunc and the CgroupLineWriteFunc, which
+// is replaced in tests to count the write calls.
+var cgroupBufferedFileWrite = cgroupFileWrite
+
+// CgroupBufferedWriteFunc writes a multi-line value. The complete content is buffered and written in a single call to
+// save the syscalls if the file accepts a whole multi-line write, while the lines of the append-only and the
+// per-device resources are written one by one since the kernel parses one line per write. The value is validated
+// before any write, so a malformed line does not leave the file partially written.
+func CgroupBufferedWriteFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if appendOnlyResources[c.ResourceType()] || perDeviceResources[c.ResourceType()] {
+		return CgroupLineWriteFunc(resource)
+	}
+	lines := splitValueLines(c.valueToWrite())
+	if err := validateBufferedLines(c, lines); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := c.writeFileBy(cgroupBufferedFileWrite, c.file, buf.String()); err != nil {
+		return err
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+// CgroupLineWriteFunc writes a multi-line value line by line, e.g. the per-device limits of `io.max`, since the kernel
+// parses one line per write. The value is validated before any write, so a malformed line does not leave the file
+// partially written.
+func CgroupLineWriteFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	lines := splitValueLines(c.valueToWrite())
+	if err := validateBufferedLines(c, lines); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := c.writeFileBy(cgroupBufferedFileWrite, c.file, line); err != nil {
 			return err
 		}
-		c.value = strconv.FormatInt(v, 10)
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+func splitValueLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// validateBufferedLines validates the lines of the append-only resources one by one, and the others as a whole, so
+// the cross-line checks like the duplicated devices take effect.
+func validateBufferedLines(c *CgroupResourceUpdater, lines []string) error {
+	if !appendOnlyResources[c.ResourceType()] {
+		lines = []string{strings.Join(lines, "\n")}
+	}
+	for _, line := range lines {
+		if valid, msg := c.file.IsValid(line); !valid {
+			return fmt.Errorf("write cgroup %s failed, value[%v] not valid, msg: %s", c.ResourceType(), line, msg)
+		}
+	}
+	return nil
+}
+
+// CgroupUpdatePidsMaxFunc updates the `pids.max`. If the write is rejected, it checks if the value is below the
+// `pids.current` and returns a descriptive error.
+func CgroupUpdatePidsMaxFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	err := cgroupWriteIfDifferentWithLog(c)
+	if err == nil || sysutil.IsResourceUnsupportedErr(err) || IsCgroupDirErr(err) {
+		return err
+	}
+	return wrapPidsMaxErr(c, err)
+}
+
+func wrapPidsMaxErr(c *CgroupResourceUpdater, err error) error {
+	r, rErr := sysutil.GetCgroupResource(sysutil.PidsCurrentName)
+	if rErr != nil {
+		return err
+	}
+	current, rErr := readCgroupAndParseInt64(c.parentDir, r)
+	if rErr != nil {
+		return err
+	}
+	limit, pErr := strconv.ParseInt(c.valueToWrite(), 10, 64)
+	if pErr == nil && limit < current {
+		return fmt.Errorf("failed to update %s to %v since it is below the pids.current %d, err: %w",
+			c.Path(), c.valueToWrite(), current, err)
+	}
+	return err
+}
+
+// CgroupUpdateIOWeightFunc updates the proportional IO weight, i.e. `blkio.weight` on cgroups-v1 and `io.weight` on
+// cgroups-v2. The `io.weight` is a flat keyed file like "default 100\n8:0 200", where writing a line only updates the
+// weight of the given key, so the write is skipped if the merged content is unchanged.
+func CgroupUpdateIOWeightFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if !sysutil.IsCgroupV2Resource(c.file) {
+		return cgroupWriteIfDifferentWithLog(c)
+	}
+
+	currentValue, err := cgroupFileRead(c.parentDir, c.file)
+	if err != nil {
+		return err
+	}
+	if MergeIOWeightValue(currentValue, c.valueToWrite()) == MergeIOWeightValue(currentValue, "") {
+		klog.V(6).Infof("no need to update io weight %s: currentValue is %s, value is %s", c.Path(), currentValue, c.valueToWrite())
+		return nil
+	}
+	if err = c.writeFile(c.file, c.valueToWrite()); err != nil {
+		return err
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+// MergeIOWeightValue merges a line of the weight into the content of the flat keyed `io.weight`, and returns the
+// merged content whose lines are sorted with the "default" first. A single weight is considered as the default.
+// e.g. MergeIOWeightValue("default 100\n8:0 200", "8:16 300") = "default 100\n8:0 200\n8:16 300".
+func MergeIOWeightValue(current, value string) string {
+	weights := map[string]string{}
+	for _, line := range append(strings.Split(current, "\n"), value) {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 1:
+			weights["default"] = fields[0]
+		case 2:
+			weights[fields[0]] = fields[1]
+		}
+	}
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		if key != "default" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := weights["default"]; ok {
+		keys = append([]string{"default"}, keys...)
+	}
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+" "+weights[key])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CgroupUpdateSchedLoadBalanceFunc updates the cgroups-v1 `cpuset.sched_load_balance`. When disabling the load
+// balancing, it warns if the parent cgroup still enables it, since the scheduler domains of the parent then cover the
+// cpus and the child's setting takes no effect. The cgroups-v2 uses the `cpuset.cpus.partition` instead.
+func CgroupUpdateSchedLoadBalanceFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if c.valueToWrite() == "0" {
+		if enabled, err := isParentSchedLoadBalanceEnabled(c); err != nil {
+			klog.V(5).Infof("failed to check the parent sched_load_balance of %s, err: %v", c.Path(), err)
+		} else if enabled {
+			klog.Warningf("parent of cgroup %s still enables sched_load_balance, disabling it takes no effect", c.Path())
+		}
 	}
 	return cgroupWriteIfDifferentWithLog(c)
 }
 
+// isParentSchedLoadBalanceEnabled returns if the parent cgroup of the updater enables the sched_load_balance. The root
+// cgroup has no parent.
+func isParentSchedLoadBalanceEnabled(c *CgroupResourceUpdater) (bool, error) {
+	dir := filepath.Clean("/" + c.parentDir)
+	if dir == "/" {
+		return false, nil
+	}
+	v, err := cgroupFileRead(filepath.Dir(dir), c.file)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(v) == "1", nil
+}
+
+// NewBlkioBFQWeightUpdater returns an updater of the blkio weight which resolves the weight file of the io scheduler
+// in the parentDir. It writes the `blkio.bfq.weight` (1..1000) on the kernels using the BFQ scheduler, or falls back to
+// the cfq's `blkio.weight` (10..1000) when the bfq file does not exist. The value is validated by the range of the
+// resolved file. It returns the unsupported error when neither file exists.
+func NewBlkioBFQWeightUpdater(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	bfqResource, err := sysutil.GetCgroupResource(sysutil.BlkioBFQWeightName)
+	if err == nil {
+		if supported, _ := bfqResource.IsSupported(parentDir); supported {
+			return NewCgroupUpdater(sysutil.BlkioBFQWeightName, parentDir, value, CommonCgroupUpdateFunc, e)
+		}
+	}
+	cfqResource, err := sysutil.GetCgroupResource(sysutil.BlkioWeightName)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := sysutil.PathExists(cfqResource.Path(parentDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s, err: %w", cfqResource.Path(parentDir), err)
+	}
+	if !exists {
+		return nil, sysutil.ResourceUnsupportedErr(fmt.Sprintf("neither %s nor %s exists in %s",
+			sysutil.BlkioBFQWeightName, sysutil.BlkioWeightName, parentDir))
+	}
+	return NewCgroupUpdater(sysutil.BlkioWeightName, parentDir, value, CgroupUpdateIOWeightFunc, e)
+}
+
+// CgroupUpdateBlkioWeightDeviceFunc updates the per-device weights of the cgroups-v1 `blkio.weight_device`, which
+// override the global `blkio.weight` of the devices. The value can contain multiple lines, e.g. "default 500\n8:0 800",
+// where the default weight is written into the `blkio.weight` first as the fallback of the other devices. Writing a
+// device line only updates the weight of the device and preserves the others, so the unchanged lines are skipped.
+func CgroupUpdateBlkioWeightDeviceFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	var defaultWeight string
+	var deviceLines []string
+	for _, line := range strings.Split(c.valueToWrite(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if valid, msg := c.file.IsValid(line); !valid {
+			return fmt.Errorf("invalid %s value %q, msg: %s", c.ResourceType(), line, msg)
+		}
+		if len(fields) == 1 || fields[0] == "default" {
+			defaultWeight = fields[len(fields)-1]
+			continue
+		}
+		deviceLines = append(deviceLines, strings.Join(fields, " "))
+	}
+
+	if defaultWeight != "" {
+		weightResource, err := sysutil.GetCgroupResource(sysutil.BlkioWeightName)
+		if err != nil {
+			return err
+		}
+		updated, err := c.writeFileIfDifferent(weightResource, defaultWeight)
+		if err != nil {
+			return fmt.Errorf("failed to update the default weight of %s, err: %w", c.Path(), err)
+		}
+		if updated {
+			auditUpdate(c.eventHelper, ReasonUpdateCgroups, weightResource.Path(c.parentDir), defaultWeight, c.auditTags)
+		}
+	}
+
+	currentValue, err := cgroupFileRead(c.parentDir, c.file)
+	if err != nil {
+		return err
+	}
+	for _, line := range deviceLines {
+		merged := MergeIOWeightValue(currentValue, line)
+		if merged == MergeIOWeightValue(currentValue, "") {
+			klog.V(6).Infof("no need to update blkio weight device %s: currentValue is %s, value is %s", c.Path(), currentValue, line)
+			continue
+		}
+		if err = c.writeFile(c.file, line); err != nil {
+			return err
+		}
+		auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), line, c.auditTags)
+		currentValue = merged
+	}
+	return nil
+}
+
+// CgroupUpdateIOLatencyFunc updates the IO latency target of a device in the cgroups-v2 `io.latency`, e.g.
+// "8:0 target=75". The file is keyed by the devices, where writing a line only updates the target of the given device
+// and preserves the others, so the write is skipped if the merged content is unchanged.
+func CgroupUpdateIOLatencyFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	currentValue, err := cgroupFileRead(c.parentDir, c.file)
+	if err != nil {
+		return err
+	}
+	if MergeIOLatencyValue(currentValue, c.valueToWrite()) == MergeIOLatencyValue(currentValue, "") {
+		klog.V(6).Infof("no need to update io latency %s: currentValue is %s, value is %s", c.Path(), currentValue, c.valueToWrite())
+		return nil
+	}
+	if err = c.writeFile(c.file, c.valueToWrite()); err != nil {
+		return err
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+// MergeIOLatencyValue merges a line of the device target into the content of the `io.latency`, and returns the merged
+// content whose lines are sorted by the devices.
+// e.g. MergeIOLatencyValue("8:0 target=75", "8:16 target=100") = "8:0 target=75\n8:16 target=100".
+func MergeIOLatencyValue(current, value string) string {
+	// the `io.latency` shares the "key value" line format with the `io.weight` except the default
+	return MergeIOWeightValue(current, value)
+}
+
+// NewIOCostUpdater returns a mergeable updater of the cgroups-v2 IO cost controller, i.e. the `io.cost.qos` and the
+// `io.cost.model` in the root cgroup. The value is the parameters of one device, e.g. "8:0 enable=1 rpct=95", where
+// only the given parameters of the device are updated and the other parameters and devices are preserved. It returns
+// the unsupported error when the io.cost is not available.
+func NewIOCostUpdater(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	r, err := sysutil.GetCgroupResource(resourceType)
+	if err != nil {
+		return nil, err
+	}
+	if supported, msg := r.IsSupported(parentDir); !supported {
+		return nil, sysutil.ResourceUnsupportedErr(fmt.Sprintf("update cgroup %s failed, msg: %s", resourceType, msg))
+	}
+	return NewMergeableCgroupUpdaterWithCondition(resourceType, parentDir, value, CgroupUpdateIOCostFunc, MergeConditionIOCostParams, e)
+}
+
+// CgroupUpdateIOCostFunc updates the parameters of a device in the `io.cost.qos` or the `io.cost.model`. The kernel
+// only updates the given parameters of the device, so the write is skipped if they are unchanged.
+func CgroupUpdateIOCostFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	currentValue, err := cgroupFileRead(c.parentDir, c.file)
+	if err != nil {
+		return err
+	}
+	if MergeIOCostValue(currentValue, c.valueToWrite()) == MergeIOCostValue(currentValue, "") {
+		klog.V(6).Infof("no need to update io cost %s: currentValue is %s, value is %s", c.Path(), currentValue, c.valueToWrite())
+		return nil
+	}
+	if err = c.writeFile(c.file, c.valueToWrite()); err != nil {
+		return err
+	}
+	auditUpdate(c.eventHelper, ReasonUpdateCgroups, c.Path(), c.Value(), c.auditTags)
+	return nil
+}
+
+// MergeConditionIOCostParams returns a merge condition of the io.cost where the new parameters of a device override
+// the old ones, and the merged value is the full parameters of the targeted device. The other devices are not written
+// so they are preserved.
+func MergeConditionIOCostParams(oldValue, newValue string) (string, bool, error) {
+	newParams := parseIOCostValue(newValue)
+	if len(newParams) != 1 {
+		return newValue, false, fmt.Errorf("new value %q should contain the parameters of one device", newValue)
+	}
+	var device string
+	for d := range newParams {
+		device = d
+	}
+	oldParams := parseIOCostValue(oldValue)
+	merged := mergeIOCostParams(oldParams[device], newParams[device])
+	mergedValue := formatIOCostLine(device, merged)
+	return mergedValue, mergedValue != formatIOCostLine(device, oldParams[device]), nil
+}
+
+// MergeIOCostValue merges a line of the device parameters into the content of the `io.cost.qos` or the
+// `io.cost.model`, and returns the merged content whose lines are sorted by the devices.
+// e.g. MergeIOCostValue("8:0 enable=1 rpct=95.00\n8:16 enable=0", "8:0 rpct=90") = "8:0 enable=1 rpct=90\n8:16 enable=0".
+func MergeIOCostValue(current, value string) string {
+	devices := parseIOCostValue(current)
+	for device, params := range parseIOCostValue(value) {
+		devices[device] = mergeIOCostParams(devices[device], params)
+	}
+	keys := make([]string, 0, len(devices))
+	for device := range devices {
+		keys = append(keys, device)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, device := range keys {
+		lines = append(lines, formatIOCostLine(device, devices[device]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ioCostParam is a "key=value" parameter of the io.cost. The params are kept in order to format as the kernel does.
+type ioCostParam struct {
+	key   string
+	value string
+}
+
+func parseIOCostValue(value string) map[string][]ioCostParam {
+	devices := map[string][]ioCostParam{}
+	for _, line := range strings.Split(value, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var params []ioCostParam
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			params = append(params, ioCostParam{key: kv[0], value: kv[1]})
+		}
+		devices[fields[0]] = mergeIOCostParams(devices[fields[0]], params)
+	}
+	return devices
+}
+
+func mergeIOCostParams(old, new []ioCostParam) []ioCostParam {
+	merged := make([]ioCostParam, len(old), len(old)+len(new))
+	copy(merged, old)
+	for _, p := range new {
+		found := false
+		for i := range merged {
+			if merged[i].key == p.key {
+				merged[i].value = p.value
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+func formatIOCostLine(device string, params []ioCostParam) string {
+	fields := make([]string, 0, len(params)+1)
+	fields = append(fields, device)
+	for _, p := range params {
+		fields = append(fields, p.key+"="+p.value)
+	}
+	return strings.Join(fields, " ")
+}
+
 type MergeConditionFunc func(oldValue, newValue string) (mergedValue string, needMerge bool, err error)
 
 func MergeFuncUpdateCgroup(resource ResourceUpdater, mergeCondition MergeConditionFunc) (ResourceUpdater, error) {
 	c := resource.(*CgroupResourceUpdater)
 
-	isValid, msg := c.file.IsValid(c.value)
+	isValid, msg := c.file.IsValid(c.valueToWrite())
 	if !isValid {
 		klog.V(6).Infof("failed to merge update cgroup %v, read new value err: %s", c.Path(), msg)
 		return resource, fmt.Errorf("parse new value failed, err: %v", msg)
@@ -405,7 +1221,7 @@ func MergeFuncUpdateCgroup(resource ResourceUpdater, mergeCondition MergeConditi
 		return resource, err
 	}
 
-	mergedValue, needMerge, err := mergeCondition(oldStr, c.value)
+	mergedValue, needMerge, err := mergeCondition(oldStr, c.valueToWrite())
 	if err != nil {
 		klog.V(6).Infof("failed to merge update cgroup %v, check merge condition err: %s", c.Path(), err)
 		return resource, err
@@ -415,28 +1231,30 @@ func MergeFuncUpdateCgroup(resource ResourceUpdater, mergeCondition MergeConditi
 		merged := resource.Clone().(*CgroupResourceUpdater)
 		merged.value = oldStr
 		klog.V(6).Infof("skip merge update cgroup %v since no need to merge new value[%v] with old[%v]",
-			c.Path(), c.value, oldStr)
+			c.Path(), c.valueToWrite(), oldStr)
 		return merged, nil
 	}
 
 	// otherwise, do write for the current value
-	if c.eventHelper != nil {
-		_ = c.eventHelper.Do()
-	} else {
-		_ = audit.V(3).Reason(ReasonUpdateCgroups).Message("update %v to %v", resource.Path(), resource.Value()).Do()
-	}
+	auditUpdateDiff(c.eventHelper, ReasonUpdateCgroups, resource.Path(), &oldStr, mergedValue, c.auditTags)
 	klog.V(6).Infof("merge update cgroup %v with merged value[%v], original new[%v], old[%v]",
-		c.Path(), mergedValue, c.value, oldStr)
+		c.Path(), mergedValue, c.valueToWrite(), oldStr)
 	// suppose current value is different
-	return resource, cgroupFileWrite(c.parentDir, c.file, mergedValue)
+	if err = c.writeFile(c.file, mergedValue); err != nil {
+		return resource, err
+	}
+	// return the value actually written, so the callers verifying or caching the merge see the merged value on disk
+	merged := resource.Clone().(*CgroupResourceUpdater)
+	merged.value = mergedValue
+	return merged, nil
 }
 
 func MergeFuncUpdateCgroupUnlimited(resource ResourceUpdater, mergeCondition MergeConditionFunc) (ResourceUpdater, error) {
 	c := resource.(*CgroupResourceUpdater)
 	// NOTE: convert "-1" to "max", since some cgroups-v2 files only accept "max" to unlimit resource instead of "-1".
 	//       DO NOT use it on the cgroups which has a valid value of "-1".
-	if c.value == sysutil.CgroupUnlimitedSymbolStr && sysutil.GetCurrentCgroupVersion() == sysutil.CgroupVersionV2 {
-		c.value = sysutil.CgroupMaxSymbolStr
+	if c.valueToWrite() == sysutil.CgroupUnlimitedSymbolStr && sysutil.IsCgroupV2Resource(c.file) {
+		c.setValueToWrite(sysutil.CgroupMaxSymbolStr)
 	}
 	return MergeFuncUpdateCgroup(c, mergeCondition)
 }
@@ -464,6 +1282,118 @@ func MergeConditionIfValueIsLarger(oldValue, newValue string) (string, bool, err
 	return newValue, newV > oldV, nil
 }
 
+// MergeConditionIfValueIsSmaller returns a merge condition where only do update when the new value is smaller.
+// The unlimited values ("max" and "-1") are considered as MaxInt64.
+func MergeConditionIfValueIsSmaller(oldValue, newValue string) (string, bool, error) {
+	newV, err := parseMergeInt64(newValue)
+	if err != nil {
+		return newValue, false, fmt.Errorf("new value is not int64, err: %v", err)
+	}
+	oldV, err := parseMergeInt64(oldValue)
+	if err != nil {
+		return newValue, false, fmt.Errorf("old value is not int64, err: %v", err)
+	}
+	return newValue, newV < oldV, nil
+}
+
+// FlagParseFunc splits a cgroup value into the base value and the kernel-managed flags appended to it.
+type FlagParseFunc func(value string) (base string, flags string)
+
+// FlagParserBySeparator returns a FlagParseFunc which regards the content after the first separator as the flags,
+// e.g. "100 [flag]" is split into "100" and " [flag]" with the separator " ".
+func FlagParserBySeparator(sep string) FlagParseFunc {
+	return func(value string) (string, string) {
+		idx := strings.Index(value, sep)
+		if idx < 0 {
+			return value, ""
+		}
+		return value[:idx], value[idx:]
+	}
+}
+
+// MergeConditionIfFlagsPreserved returns a merge condition which keeps the kernel-managed flags in the old value and
+// re-appends them to the new value, so the rewrite does not strip the kernel state. The flags are parsed by the given
+// per-resource FlagParseFunc.
+func MergeConditionIfFlagsPreserved(parse FlagParseFunc) MergeConditionFunc {
+	return func(oldValue, newValue string) (string, bool, error) {
+		_, oldFlags := parse(oldValue)
+		newBase, _ := parse(newValue)
+		merged := newBase + oldFlags
+		return merged, merged != oldValue, nil
+	}
+}
+
+// JSONSchemaFunc validates the merged fields of a JSON-structured value, e.g. checks the known fields and their ranges
+// of a kernel knob.
+type JSONSchemaFunc func(fields map[string]interface{}) error
+
+// MergeConditionJSONFields returns a merge condition for the JSON-structured values, which merges the new value into
+// the old one field by field, so the fields not set in the new value are kept. The nested objects are merged
+// recursively, and the other fields of the new value override the old ones. The merged value is validated by the
+// schema if it is not nil. An empty old value is taken as an empty object.
+func MergeConditionJSONFields(schema JSONSchemaFunc) MergeConditionFunc {
+	return func(oldValue, newValue string) (string, bool, error) {
+		newFields, err := unmarshalJSONFields(newValue)
+		if err != nil {
+			return newValue, false, fmt.Errorf("new value is not valid json object, err: %v", err)
+		}
+		oldFields, err := unmarshalJSONFields(oldValue)
+		if err != nil {
+			return newValue, false, fmt.Errorf("old value is not valid json object, err: %v", err)
+		}
+		merged := mergeJSONFields(oldFields, newFields)
+		if schema != nil {
+			if err = schema(merged); err != nil {
+				return newValue, false, fmt.Errorf("merged value is invalid, err: %w", err)
+			}
+		}
+		mergedValue, err := json.Marshal(merged)
+		if err != nil {
+			return newValue, false, fmt.Errorf("failed to marshal merged value, err: %v", err)
+		}
+		return string(mergedValue), !reflect.DeepEqual(merged, oldFields), nil
+	}
+}
+
+func unmarshalJSONFields(value string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if strings.TrimSpace(value) == "" {
+		return fields, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	// keep the precision of the large integers
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// mergeJSONFields returns a copy of the old fields overridden by the new ones, where the nested objects are merged.
+func mergeJSONFields(oldFields, newFields map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(oldFields)+len(newFields))
+	for k, v := range oldFields {
+		merged[k] = v
+	}
+	for k, v := range newFields {
+		oldObj, oldIsObj := merged[k].(map[string]interface{})
+		newObj, newIsObj := v.(map[string]interface{})
+		if oldIsObj && newIsObj {
+			merged[k] = mergeJSONFields(oldObj, newObj)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+func parseMergeInt64(value string) (int64, error) {
+	if value == sysutil.CgroupMaxSymbolStr || value == sysutil.CgroupUnlimitedSymbolStr {
+		return math.MaxInt64, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
 // MergeConditionIfCPUSetIsLooser returns a merge condition where only do update when the new cpuset value is looser.
 func MergeConditionIfCPUSetIsLooser(oldValue, newValue string) (string, bool, error) {
 	v, err := cpuset.Parse(newValue)
@@ -489,42 +1419,224 @@ func MergeConditionIfCPUSetIsLooser(oldValue, newValue string) (string, bool, er
 	return merged.String(), true, nil
 }
 
+// ErrEmptyCPUSet is returned when the cpuset to write is empty, which can stall all processes in the cgroup.
+var ErrEmptyCPUSet = errors.New("empty cpuset")
+
+func IsEmptyCPUSetErr(err error) bool {
+	return errors.Is(err, ErrEmptyCPUSet)
+}
+
+// CgroupUpdateCPUSetFunc updates the `cpuset.cpus`, and rejects the empty cpuset with ErrEmptyCPUSet before writing
+// to defend against the buggy allocators. Use WithEmptyCPUSetAllowed to write the empty cpuset explicitly.
+func CgroupUpdateCPUSetFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if err := checkNonEmptyCPUSet(c.valueToWrite()); err != nil {
+		return fmt.Errorf("failed to update cgroup %s, err: %w", c.Path(), err)
+	}
+	return CommonCgroupUpdateFunc(resource)
+}
+
+// MergeConditionNonEmptyCPUSet wraps the merge condition of the cpuset to reject the merged cpuset which is empty
+// with ErrEmptyCPUSet.
+func MergeConditionNonEmptyCPUSet(mergeCondition MergeConditionFunc) MergeConditionFunc {
+	return func(oldValue, newValue string) (string, bool, error) {
+		mergedValue, needMerge, err := mergeCondition(oldValue, newValue)
+		if err != nil || !needMerge {
+			return mergedValue, needMerge, err
+		}
+		if err = checkNonEmptyCPUSet(mergedValue); err != nil {
+			return mergedValue, false, fmt.Errorf("failed to merge old cpuset %q with new %q, err: %w", oldValue, newValue, err)
+		}
+		return mergedValue, needMerge, nil
+	}
+}
+
+func checkNonEmptyCPUSet(value string) error {
+	v, err := cpuset.Parse(value)
+	if err != nil {
+		return fmt.Errorf("value %q is not valid cpuset, err: %v", value, err)
+	}
+	if v.IsEmpty() {
+		return ErrEmptyCPUSet
+	}
+	return nil
+}
+
+// PlanCPUSetTransition returns the ordered cpuset values to write for transitioning from the current cpuset to the
+// desired. The CPUs are added before removed, so the cpuset never passes through an empty or over-tight intermediate.
+// e.g. PlanCPUSetTransition("0-1", "2-3") = ["0-3", "2-3"]. It returns nil if the desired equals the current.
+func PlanCPUSetTransition(current, desired cpuset.CPUSet) []string {
+	if desired.Equals(current) {
+		return nil
+	}
+	// only add or only remove the CPUs
+	if current.IsEmpty() || desired.IsSubsetOf(current) || current.IsSubsetOf(desired) {
+		return []string{desired.String()}
+	}
+	return []string{current.Union(desired).String(), desired.String()}
+}
+
+// ErrExclusiveCPUSetNotSubset is returned when the `cpuset.cpus.exclusive` to write is not a subset of the cgroup's
+// `cpuset.cpus`, which the kernel rejects with EINVAL.
+var ErrExclusiveCPUSetNotSubset = errors.New("exclusive cpuset is not a subset of cpuset.cpus")
+
+func IsExclusiveCPUSetNotSubsetErr(err error) bool {
+	return errors.Is(err, ErrExclusiveCPUSetNotSubset)
+}
+
+// NewCPUSetExclusiveUpdater returns a mergeable updater of the cgroups-v2 `cpuset.cpus.exclusive`, which reserves the
+// CPUs exclusively to the subtree. The exclusive set, including the merged one, is validated to be a subset of the
+// cgroup's `cpuset.cpus` before writing. Like the `cpuset.cpus`, the merge only updates when the new value is looser.
+func NewCPUSetExclusiveUpdater(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
+	mergeCondition := func(oldValue, newValue string) (string, bool, error) {
+		mergedValue, needMerge, err := MergeConditionIfCPUSetIsLooser(oldValue, newValue)
+		if err != nil || !needMerge {
+			return mergedValue, needMerge, err
+		}
+		if err = checkExclusiveCPUSetSubset(parentDir, mergedValue); err != nil {
+			return mergedValue, false, err
+		}
+		return mergedValue, needMerge, nil
+	}
+	return NewMergeableCgroupUpdaterWithCondition(resourceType, parentDir, value, CgroupUpdateCPUSetExclusiveFunc, mergeCondition, e)
+}
+
+// CgroupUpdateCPUSetExclusiveFunc updates the `cpuset.cpus.exclusive`, and rejects the exclusive set which is not a
+// subset of the `cpuset.cpus` with ErrExclusiveCPUSetNotSubset. An empty value clears the exclusive set.
+func CgroupUpdateCPUSetExclusiveFunc(resource ResourceUpdater) error {
+	c := resource.(*CgroupResourceUpdater)
+	if err := checkExclusiveCPUSetSubset(c.parentDir, c.valueToWrite()); err != nil {
+		return fmt.Errorf("failed to update cgroup %s, err: %w", c.Path(), err)
+	}
+	return CommonCgroupUpdateFunc(resource)
+}
+
+func checkExclusiveCPUSetSubset(parentDir string, value string) error {
+	exclusive, err := cpuset.Parse(value)
+	if err != nil {
+		return fmt.Errorf("value %q is not valid cpuset, err: %v", value, err)
+	}
+	if exclusive.IsEmpty() {
+		return nil
+	}
+	cpusResource, err := sysutil.GetCgroupResource(sysutil.CPUSetCPUSName)
+	if err != nil {
+		return err
+	}
+	cpusStr, err := cgroupFileRead(parentDir, cpusResource)
+	if err != nil {
+		return fmt.Errorf("failed to read %s, err: %w", cpusResource.Path(parentDir), err)
+	}
+	cpus, err := cpuset.Parse(cpusStr)
+	if err != nil {
+		return fmt.Errorf("current %s %q is not valid cpuset, err: %v", cpusResource.Path(parentDir), cpusStr, err)
+	}
+	if !exclusive.IsSubsetOf(cpus) {
+		return fmt.Errorf("%w, exclusive %q, cpus %q", ErrExclusiveCPUSetNotSubset, value, cpusStr)
+	}
+	return nil
+}
+
 func cgroupWriteIfDifferentWithLog(c *CgroupResourceUpdater) error {
-	updated, err := cgroupFileWriteIfDifferent(c.parentDir, c.file, c.value)
+	return cgroupWriteValueIfDifferentWithLog(c, c.valueToWrite())
+}
+
+// cgroupWriteValueIfDifferentWithLog writes the given value which can be converted from the updater's value, e.g. the
+// `cpu.weight` converted from the `cpu.shares`.
+func cgroupWriteValueIfDifferentWithLog(c *CgroupResourceUpdater, value string) error {
+	var oldValue *string
+	if klog.V(auditDiffLogLevel).Enabled() {
+		if v, err := cgroupFileRead(c.parentDir, c.file); err == nil {
+			oldValue = &v
+		}
+	}
+	updated, err := c.writeFileIfDifferent(c.file, value)
 	if err != nil {
 		return err
 	}
-	if updated && c.eventHelper != nil {
-		_ = c.eventHelper.Do()
-	} else if updated {
-		_ = audit.V(3).Reason(ReasonUpdateCgroups).Message("update %v to %v", c.Path(), c.Value()).Do()
+	if updated {
+		auditUpdateDiff(c.eventHelper, ReasonUpdateCgroups, c.Path(), oldValue, value, c.auditTags)
 	}
 	return nil
 }
 
+// writeFile writes the cgroup file in the parent dir of the updater, which charges the write budget of the update.
+func (u *CgroupResourceUpdater) writeFile(r sysutil.Resource, value string) error {
+	return u.writeFileBy(cgroupFileWrite, r, value)
+}
+
+// writeFileBy writes the cgroup file in the parent dir of the updater by the given write func. The budget is charged
+// after the checks, so the writes rejected before issuing do not consume the budget.
+func (u *CgroupResourceUpdater) writeFileBy(write cgroupWriteFunc, r sysutil.Resource, value string) error {
+	if err := checkCgroupFileWrite(u.parentDir, r, value); err != nil {
+		return err
+	}
+	if err := u.acquireWriteBudget(r.Path(u.parentDir), value); err != nil {
+		return err
+	}
+	err := write(u.parentDir, r, value)
+	u.addIssuedWrite(r, value, err)
+	return err
+}
+
+// addIssuedWrite adds the write issued in the current update, which is recorded into the write history when the
+// update finishes.
+func (u *CgroupResourceUpdater) addIssuedWrite(r sysutil.Resource, value string, err error) {
+	u.state.writes = append(u.state.writes, issuedWrite{
+		resourceType: r.ResourceType(),
+		path:         r.Path(u.parentDir),
+		value:        value,
+		err:          err,
+	})
+}
+
+// writeFileIfDifferent writes the cgroup file in the parent dir of the updater if the current value is different.
+func (u *CgroupResourceUpdater) writeFileIfDifferent(r sysutil.Resource, value string) (bool, error) {
+	return cgroupFileWriteIfDifferentBy(u.parentDir, r, value, func(_ string, r sysutil.Resource, value string) error {
+		return u.writeFile(r, value)
+	})
+}
+
+// addIssuedWrite adds the write issued in the current update, which is recorded into the write history when the
+// update finishes.
+func (u *DefaultResourceUpdater) addIssuedWrite(value string, err error) {
+	u.writes = append(u.writes, issuedWrite{resourceType: u.ResourceType(), path: u.Path(), value: value, err: err})
+}
+
 func commonWriteIfDifferentWithLog(c *DefaultResourceUpdater) error {
-	updated, err := sysutil.CommonFileWriteIfDifferent(c.Path(), c.value)
+	oldValue, err := sysutil.CommonFileRead(c.Path())
 	if err != nil {
 		return err
 	}
-	if updated && c.eventHelper != nil {
-		_ = c.eventHelper.Do()
-	} else if updated {
-		_ = audit.V(3).Reason(ReasonUpdateSystemConfig).Message("update %v to %v", c.Path(), c.Value()).Do()
+	if oldValue == c.value {
+		klog.V(6).Infof("read before write %s and got the same value, skip the write", c.Path())
+		return nil
+	}
+	if !c.writeBudgetExempt {
+		if err = acquireWriteBudget(c.Path(), c.value); err != nil {
+			return err
+		}
+	}
+	err = sysutil.CommonFileWrite(c.Path(), c.value)
+	c.addIssuedWrite(c.value, err)
+	if err != nil {
+		return err
 	}
+	auditUpdateDiff(c.eventHelper, ReasonUpdateSystemConfig, c.Path(), &oldValue, c.Value(), c.auditTags)
 	return nil
 }
 
 func BlkIOUpdateFunc(resource ResourceUpdater) error {
 	info := resource.(*CgroupResourceUpdater)
-	return cgroupBlkIOFileWriteIfDifferent(info.parentDir, info.file, info.Value())
+	return cgroupBlkIOFileWriteIfDifferent(info, info.Value())
 }
 
 func NewBlkIOResourceUpdater(resourceType sysutil.ResourceType, parentDir string, value string, e *audit.EventHelper) (ResourceUpdater, error) {
 	return NewCgroupUpdaterWithUpdateFunc(BlkIOUpdateFunc)(resourceType, parentDir, value, e)
 }
 
-func cgroupBlkIOFileWriteIfDifferent(cgroupTaskDir string, file sysutil.Resource, value string) error {
+func cgroupBlkIOFileWriteIfDifferent(c *CgroupResourceUpdater, value string) error {
+	cgroupTaskDir, file := c.parentDir, c.file
 	var needUpdate bool
 	currentValue, currentErr := cgroupFileRead(cgroupTaskDir, file)
 	if currentErr != nil {
@@ -546,7 +1658,7 @@ func cgroupBlkIOFileWriteIfDifferent(cgroupTaskDir string, file sysutil.Resource
 	}
 
 	klog.V(6).Infof("need to update blk cgroup file %s/%s: currentValue is %s, value is %s", cgroupTaskDir, file.ResourceType(), currentValue, value)
-	return cgroupFileWrite(cgroupTaskDir, file, value)
+	return c.writeFile(file, value)
 }
 
 // https://www.alibabacloud.com/help/en/elastic-compute-service/latest/configure-the-weight-based-throttling-feature-of-blk-iocost
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// UpdaterBuilder builds a cgroup updater fluently, which keeps the callers readable as the options proliferate. e.g.
+//
+//	u, err := Build(sysutil.CPUSetCPUSName).ParentDir(dir).Value("0-3").Merge(MergeConditionIfCPUSetIsLooser).Build()
+type UpdaterBuilder struct {
+	factory        ResourceUpdaterFactory
+	resourceType   sysutil.ResourceType
+	parentDir      string
+	value          string
+	mergeCondition MergeConditionFunc
+	auditLevel     *int
+}
+
+// Build starts building an updater of the resource type with the DefaultCgroupUpdaterFactory.
+func Build(resourceType sysutil.ResourceType) *UpdaterBuilder {
+	return &UpdaterBuilder{
+		factory:      DefaultCgroupUpdaterFactory,
+		resourceType: resourceType,
+	}
+}
+
+// Factory sets the factory constructing the updater.
+func (b *UpdaterBuilder) Factory(f ResourceUpdaterFactory) *UpdaterBuilder {
+	b.factory = f
+	return b
+}
+
+// ParentDir sets the cgroup parent dir, which is required.
+func (b *UpdaterBuilder) ParentDir(parentDir string) *UpdaterBuilder {
+	b.parentDir = parentDir
+	return b
+}
+
+// Value sets the value to update.
+func (b *UpdaterBuilder) Value(value string) *UpdaterBuilder {
+	b.value = value
+	return b
+}
+
+// Merge makes the updater mergeable with the merge condition, which overrides the registered one.
+func (b *UpdaterBuilder) Merge(mergeCondition MergeConditionFunc) *UpdaterBuilder {
+	b.mergeCondition = mergeCondition
+	return b
+}
+
+// AuditLevel makes the updater record the audit events in the verbosity level.
+func (b *UpdaterBuilder) AuditLevel(level int) *UpdaterBuilder {
+	b.auditLevel = &level
+	return b
+}
+
+// Build validates the required fields and returns the updater.
+func (b *UpdaterBuilder) Build() (ResourceUpdater, error) {
+	if b.resourceType == "" {
+		return nil, fmt.Errorf("resource type is required")
+	}
+	if b.parentDir == "" {
+		return nil, fmt.Errorf("parent dir of %s is required", b.resourceType)
+	}
+	var e *audit.EventHelper
+	if b.auditLevel != nil {
+		e = audit.V(*b.auditLevel).Reason(ReasonUpdateCgroups).Message("update %v of %v to %v", b.resourceType, b.parentDir, b.value)
+	}
+	u, err := b.factory.New(b.resourceType, b.parentDir, b.value, e)
+	if err != nil {
+		return nil, err
+	}
+	if b.mergeCondition != nil {
+		c, ok := u.(*CgroupResourceUpdater)
+		if !ok {
+			return nil, fmt.Errorf("updater %T of %s is not mergeable", u, b.resourceType)
+		}
+		mergeCondition := b.mergeCondition
+		c.mergeCondition = mergeCondition
+		c.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			return MergeFuncUpdateCgroup(resource, mergeCondition)
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestUpdaterBuilder(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+	// equivalent to the direct constructor
+	want, err := NewMergeableCgroupUpdaterWithCondition(sysutil.CPUCFSQuotaName, parentDir, "20000",
+		CgroupUpdateWithUnlimitedFunc, MergeConditionIfValueIsSmaller, nil)
+	assert.NoError(t, err)
+	got, err := Build(sysutil.CPUCFSQuotaName).ParentDir(parentDir).Value("20000").Merge(MergeConditionIfValueIsSmaller).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, want.ResourceType(), got.ResourceType())
+	assert.Equal(t, want.Path(), got.Path())
+	assert.Equal(t, want.Value(), got.Value())
+	assert.Equal(t, want.IsMergeable(), got.IsMergeable())
+	wantChanged, err := want.(*CgroupResourceUpdater).WouldChange()
+	assert.NoError(t, err)
+	gotChanged, err := got.(*CgroupResourceUpdater).WouldChange()
+	assert.NoError(t, err)
+	assert.Equal(t, wantChanged, gotChanged)
+	_, err = got.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.NoError(t, got.update())
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// with the audit level
+	got, err = Build(sysutil.CPUCFSQuotaName).ParentDir(parentDir).Value("30000").AuditLevel(3).Build()
+	assert.NoError(t, err)
+	assert.NotNil(t, got.(*CgroupResourceUpdater).GetEventHelper())
+
+	// missing parent dir
+	_, err = Build(sysutil.CPUCFSQuotaName).Value("20000").Build()
+	assert.Error(t, err)
+
+	// unknown resource
+	_, err = Build("unknown").ParentDir(parentDir).Value("20000").Build()
+	assert.Error(t, err)
+}
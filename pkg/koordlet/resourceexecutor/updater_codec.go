@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+type UpdaterKind string
+
+const (
+	UpdaterKindCgroup  UpdaterKind = "cgroup"
+	UpdaterKindDefault UpdaterKind = "default"
+)
+
+// EncodedUpdater is the serializable update intent of a ResourceUpdater, e.g. for delegating the writes to a helper
+// process. The update funcs are not serialized but re-attached by the factory on decode.
+type EncodedUpdater struct {
+	Kind         UpdaterKind `json:"kind"`
+	ResourceType string      `json:"resourceType,omitempty"`
+	ParentDir    string      `json:"parentDir,omitempty"`
+	Key          string      `json:"key,omitempty"`
+	File         string      `json:"file,omitempty"`
+	Value        string      `json:"value"`
+}
+
+// EncodeUpdater serializes the update intent of the updater into JSON.
+func EncodeUpdater(u ResourceUpdater) ([]byte, error) {
+	var e *EncodedUpdater
+	switch c := u.(type) {
+	case *CgroupResourceUpdater:
+		e = &EncodedUpdater{
+			Kind:         UpdaterKindCgroup,
+			ResourceType: string(c.ResourceType()),
+			ParentDir:    c.parentDir,
+			Value:        c.value,
+		}
+	case *DefaultResourceUpdater:
+		e = &EncodedUpdater{
+			Kind:  UpdaterKindDefault,
+			Key:   c.key,
+			File:  c.file,
+			Value: c.value,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported updater type %T to encode", u)
+	}
+	return json.Marshal(e)
+}
+
+// DecodeUpdater reconstructs a ResourceUpdater from the JSON encoded by EncodeUpdater. The cgroup updaters are
+// constructed by the given factory, and the DefaultCgroupUpdaterFactory is used if it is nil.
+func DecodeUpdater(data []byte, factory ResourceUpdaterFactory, e *audit.EventHelper) (ResourceUpdater, error) {
+	encoded := &EncodedUpdater{}
+	if err := json.Unmarshal(data, encoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal updater, err: %w", err)
+	}
+	switch encoded.Kind {
+	case UpdaterKindCgroup:
+		if factory == nil {
+			factory = DefaultCgroupUpdaterFactory
+		}
+		return factory.New(sysutil.ResourceType(encoded.ResourceType), encoded.ParentDir, encoded.Value, e)
+	case UpdaterKindDefault:
+		return NewCommonDefaultUpdater(encoded.Key, encoded.File, encoded.Value, e)
+	default:
+		return nil, fmt.Errorf("unknown updater kind %q to decode", encoded.Kind)
+	}
+}
+
+// UpdaterSpec is an entry of the declarative spec of the cgroup updaters.
+type UpdaterSpec struct {
+	ResourceType string `json:"resourceType"`
+	ParentDir    string `json:"parentDir"`
+	Value        string `json:"value"`
+}
+
+// LoadUpdatersFromFile constructs the cgroup updaters from the spec file, which is a YAML or JSON list of the
+// UpdaterSpec, e.g. a fixed cgroup config of the node. The updaters are constructed by the given factory, and the
+// DefaultCgroupUpdaterFactory is used if it is nil. Each entry is validated, and no updater is returned if any entry
+// is invalid, with the errors of all the invalid entries aggregated.
+func LoadUpdatersFromFile(path string, factory ResourceUpdaterFactory) ([]ResourceUpdater, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read updater spec file %s, err: %w", path, err)
+	}
+	var specs []UpdaterSpec
+	if err = yaml.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal updater spec file %s, err: %w", path, err)
+	}
+	if factory == nil {
+		factory = DefaultCgroupUpdaterFactory
+	}
+
+	updaters := make([]ResourceUpdater, 0, len(specs))
+	var errs []error
+	for i, spec := range specs {
+		u, err := newUpdaterFromSpec(spec, factory)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid updater spec [%d] %+v, err: %w", i, spec, err))
+			continue
+		}
+		updaters = append(updaters, u)
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return updaters, nil
+}
+
+func newUpdaterFromSpec(spec UpdaterSpec, factory ResourceUpdaterFactory) (ResourceUpdater, error) {
+	if spec.ResourceType == "" {
+		return nil, fmt.Errorf("resourceType is empty")
+	}
+	if spec.ParentDir == "" {
+		return nil, fmt.Errorf("parentDir is empty")
+	}
+	u, err := factory.New(sysutil.ResourceType(spec.ResourceType), spec.ParentDir, spec.Value, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := u.(*CgroupResourceUpdater); ok {
+		if valid, msg := c.file.IsValid(spec.Value); !valid {
+			return nil, fmt.Errorf("value %q is not valid, msg: %s", spec.Value, msg)
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestEncodeDecodeUpdater(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	// cgroup updater
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	data, err := EncodeUpdater(u)
+	assert.NoError(t, err)
+	got, err := DecodeUpdater(data, nil, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &CgroupResourceUpdater{}, got)
+	assert.Equal(t, u.ResourceType(), got.ResourceType())
+	assert.Equal(t, u.Path(), got.Path())
+	assert.Equal(t, u.Value(), got.Value())
+	_, err = got.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// default updater
+	file := filepath.Join(helper.TempDir, "test_file")
+	helper.WriteFileContents(file, "0")
+	u, err = NewCommonDefaultUpdater(file, file, "1", nil)
+	assert.NoError(t, err)
+	data, err = EncodeUpdater(u)
+	assert.NoError(t, err)
+	got, err = DecodeUpdater(data, nil, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &DefaultResourceUpdater{}, got)
+	assert.Equal(t, u.Key(), got.Key())
+	assert.Equal(t, u.Path(), got.Path())
+	assert.Equal(t, u.Value(), got.Value())
+	assert.NoError(t, got.update())
+	assert.Equal(t, "1", helper.ReadFileContents(file))
+
+	// invalid
+	_, err = DecodeUpdater([]byte(`{"kind":"unknown","value":"1"}`), nil, nil)
+	assert.Error(t, err)
+	_, err = DecodeUpdater([]byte(`{"kind":"cgroup","resourceType":"unknown","value":"1"}`), nil, nil)
+	assert.Error(t, err)
+	_, err = DecodeUpdater([]byte(`invalid`), nil, nil)
+	assert.Error(t, err)
+}
+
+func TestLoadUpdatersFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+		want    []UpdaterSpec
+	}{
+		{
+			name: "load yaml spec",
+			spec: `
+- resourceType: cpu.shares
+  parentDir: /kubepods.slice
+  value: "1024"
+- resourceType: memory.limit_in_bytes
+  parentDir: /kubepods.slice/kubepods-besteffort.slice
+  value: "1048576"
+`,
+			want: []UpdaterSpec{
+				{ResourceType: "cpu.shares", ParentDir: "/kubepods.slice", Value: "1024"},
+				{ResourceType: "memory.limit_in_bytes", ParentDir: "/kubepods.slice/kubepods-besteffort.slice", Value: "1048576"},
+			},
+		},
+		{
+			name: "load json spec",
+			spec: `[{"resourceType": "cpu.cfs_quota_us", "parentDir": "/kubepods.slice", "value": "-1"}]`,
+			want: []UpdaterSpec{
+				{ResourceType: "cpu.cfs_quota_us", ParentDir: "/kubepods.slice", Value: "-1"},
+			},
+		},
+		{
+			name: "aggregate the invalid entries",
+			spec: `
+- resourceType: cpu.shares
+  parentDir: /kubepods.slice
+  value: "1"
+- resourceType: unknown.file
+  parentDir: /kubepods.slice
+  value: "1"
+- resourceType: cpu.shares
+  value: "1024"
+`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid spec",
+			spec:    `resourceType: cpu.shares`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.WriteFileContents("spec.yaml", tt.spec)
+
+			got, gotErr := LoadUpdatersFromFile(filepath.Join(helper.TempDir, "spec.yaml"), nil)
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			var gotSpecs []UpdaterSpec
+			for _, u := range got {
+				gotSpecs = append(gotSpecs, UpdaterSpec{
+					ResourceType: string(u.ResourceType()),
+					ParentDir:    u.(*CgroupResourceUpdater).parentDir,
+					Value:        u.Value(),
+				})
+			}
+			assert.Equal(t, tt.want, gotSpecs)
+		})
+	}
+}
@@ -0,0 +1,1203 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+// preUpdateFunc is called before the update and the merge update of a CgroupResourceUpdater. It can adjust the value
+// to write by setValueToWrite, or return skip=true to skip the write.
+type preUpdateFunc func(c *CgroupResourceUpdater) (skip bool, err error)
+
+// withPreUpdate wraps both the updateFunc and the mergeUpdateFunc with the given pre-update hook.
+func (u *CgroupResourceUpdater) withPreUpdate(pre preUpdateFunc) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		c := resource.(*CgroupResourceUpdater)
+		skip, err := pre(c)
+		if err != nil || skip {
+			return err
+		}
+		return updateFn(resource)
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			c := resource.(*CgroupResourceUpdater)
+			skip, err := pre(c)
+			if err != nil {
+				return resource, err
+			}
+			if skip {
+				return nil, nil
+			}
+			return mergeUpdateFn(resource)
+		}
+	}
+	return u
+}
+
+// WithParentCeiling clamps the value to the parent cgroup's value of the given resource type before writing, e.g.
+// the child's `memory.high` should never exceed the parent's. The parent cgroup is the parent of the parentDir.
+func (u *CgroupResourceUpdater) WithParentCeiling(resourceType sysutil.ResourceType) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		return false, clampToParentCeiling(c, resourceType)
+	})
+}
+
+func clampToParentCeiling(c *CgroupResourceUpdater, resourceType sysutil.ResourceType) error {
+	upperDir := filepath.Dir(filepath.Clean(c.parentDir))
+	if upperDir == filepath.Clean(c.parentDir) { // root cgroup has no parent
+		return nil
+	}
+	r, err := sysutil.GetCgroupResource(resourceType)
+	if err != nil {
+		return err
+	}
+	ceiling, err := readCgroupAndParseInt64(upperDir, r)
+	if err != nil {
+		return fmt.Errorf("failed to read parent ceiling %s, err: %w", r.Path(upperDir), err)
+	}
+	if ceiling < 0 { // parent is unlimited
+		return nil
+	}
+
+	value := c.valueToWrite()
+	v := int64(math.MaxInt64)
+	if value != sysutil.CgroupMaxSymbolStr && value != sysutil.CgroupUnlimitedSymbolStr {
+		v, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse value %s for parent ceiling, err: %w", value, err)
+		}
+	}
+	if v > ceiling {
+		klog.V(5).Infof("clamp cgroup %s value from %s to parent ceiling %d", c.Path(), value, ceiling)
+		// keep the requested value, so the value is written as requested once the parent ceiling rises
+		c.setValueToWrite(strconv.FormatInt(ceiling, 10))
+	}
+	return nil
+}
+
+// WithQuantityParsing parses the value in the Kubernetes quantity format (e.g. "512Mi", "1Gi") into bytes before
+// writing. The raw integer values and the unlimited symbols are kept unchanged.
+func (u *CgroupResourceUpdater) WithQuantityParsing() *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		v, err := parseQuantityValue(c.valueToWrite())
+		if err != nil {
+			return false, err
+		}
+		c.setValueToWrite(v)
+		return false, nil
+	})
+}
+
+func parseQuantityValue(value string) (string, error) {
+	if value == sysutil.CgroupMaxSymbolStr || value == sysutil.CgroupUnlimitedSymbolStr {
+		return value, nil
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return value, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return value, fmt.Errorf("value %s is neither an integer nor a valid quantity, err: %w", value, err)
+	}
+	return strconv.FormatInt(q.Value(), 10), nil
+}
+
+// WithShadowWrite makes the updater write the value into a shadow file instead of the real cgroup, and logs the diff
+// against the current value of the real path. The shadow file mirrors the real path relative to the cgroup root under
+// the shadowRoot, e.g. `<shadowRoot>/cpu/kubepods.slice/cpu.cfs_quota_us`, and the value is converted as the real
+// write, e.g. the `cpu.weight` of the `cpu.shares` on the cgroups-v2. The shadowRoot must be outside the cgroup mount,
+// since creating a dir in the cgroupfs creates a cgroup. It helps to validate a new policy computation without
+// affecting the real cgroup.
+func (u *CgroupResourceUpdater) WithShadowWrite(shadowRoot string) *CgroupResourceUpdater {
+	u.mergeUpdateFunc = nil
+	u.mergeCondition = nil
+	return u.WithUpdateFunc(func(resource ResourceUpdater) error {
+		return shadowWrite(resource.(*CgroupResourceUpdater), shadowRoot)
+	})
+}
+
+func shadowWrite(c *CgroupResourceUpdater, shadowRoot string) error {
+	cgroupRoot := filepath.Clean(sysutil.Conf.CgroupRootDir)
+	if isPathUnder(cgroupRoot, shadowRoot) {
+		return fmt.Errorf("shadow root %s is under the cgroup root %s", shadowRoot, cgroupRoot)
+	}
+	relPath, err := filepath.Rel(cgroupRoot, c.Path())
+	if err != nil || !isPathUnder(cgroupRoot, c.Path()) {
+		return fmt.Errorf("cgroup path %s is not under the cgroup root %s", c.Path(), cgroupRoot)
+	}
+	shadowPath := filepath.Join(shadowRoot, relPath)
+	value, err := ConvertCgroupValue(c.file, c.value)
+	if err != nil {
+		return fmt.Errorf("shadow write cgroup %s failed, convert value[%v] err: %w", c.ResourceType(), c.value, err)
+	}
+	if valid, msg := c.file.IsValid(value); !valid {
+		return fmt.Errorf("shadow write cgroup %s failed, value[%v] not valid, msg: %s", c.ResourceType(), value, msg)
+	}
+
+	realValue, err := cgroupFileRead(c.parentDir, c.file)
+	if err != nil {
+		klog.V(4).Infof("shadow write cgroup %s, failed to read the real value, err: %v", c.Path(), err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(shadowPath), 0755); err != nil {
+		return fmt.Errorf("failed to create shadow dir for %s, err: %w", shadowPath, err)
+	}
+	if err = os.WriteFile(shadowPath, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to shadow write %s, err: %w", shadowPath, err)
+	}
+	klog.V(4).Infof("shadow write cgroup %s to %v, real path %s, real value %v, differs %v",
+		shadowPath, value, c.Path(), realValue, !IsCgroupValueDesired(c.file, realValue, c.value))
+	return nil
+}
+
+// isPathUnder checks if the path is the root or under the root.
+func isPathUnder(root, path string) bool {
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// settleCheckInterval is the interval to poll the settle check.
+var settleCheckInterval = 100 * time.Millisecond
+
+// WithSettleCheck makes the updater poll the check after a successful write until it passes or the timeout expires.
+// It helps to block until an asynchronous change is reflected, e.g. the `cpuset.effective_cpus` after writing the
+// `cpuset.cpus`.
+func (u *CgroupResourceUpdater) WithSettleCheck(check func() (bool, error), timeout time.Duration) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		if err := updateFn(resource); err != nil {
+			return err
+		}
+		return waitForSettle(resource, check, timeout)
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			mergedUpdater, err := mergeUpdateFn(resource)
+			if err != nil {
+				return mergedUpdater, err
+			}
+			return mergedUpdater, waitForSettle(resource, check, timeout)
+		}
+	}
+	return u
+}
+
+func waitForSettle(resource ResourceUpdater, check func() (bool, error), timeout time.Duration) error {
+	err := wait.PollImmediate(settleCheckInterval, timeout, check)
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("settle check for %s to %v timed out after %v", resource.Path(), resource.Value(), timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("settle check for %s to %v failed, err: %w", resource.Path(), resource.Value(), err)
+	}
+	return nil
+}
+
+// WithFreezeConfirm makes the freezer updater wait until the cgroup is completely frozen after writing, which may take
+// a while since the tasks are frozen asynchronously. It takes no effect on the thaw.
+func (u *CgroupResourceUpdater) WithFreezeConfirm(timeout time.Duration) *CgroupResourceUpdater {
+	if u.value != sysutil.FreezerStateFrozen && u.value != sysutil.CgroupFreezeFrozen {
+		return u
+	}
+	return u.WithSettleCheck(func() (bool, error) {
+		return IsCgroupFrozen(u.parentDir)
+	}, timeout)
+}
+
+// WithMaxGuard makes the updater of the `memory.high` temporarily raise the `memory.max` by the headroom during the
+// adjustment, which reduces the OOM risk of an aggressive workload when the lowered high forces the reclaim. The max is
+// restored once the reclaim settles, i.e. the memory usage drops below the high, or the timeout expires, unless the
+// max is changed by others meanwhile. The update succeeds once the high is written, while the settle timeout and the
+// failure of the restore are only logged. It takes no effect on the other resources, the unlimited high, the
+// unlimited max and the update not changing the high.
+func (u *CgroupResourceUpdater) WithMaxGuard(headroom int64, timeout time.Duration) *CgroupResourceUpdater {
+	if u.ResourceType() != sysutil.MemoryHighName {
+		return u
+	}
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		return writeWithMaxGuard(resource.(*CgroupResourceUpdater), false, headroom, timeout, func() error {
+			return updateFn(resource)
+		})
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			var mergedUpdater ResourceUpdater
+			err := writeWithMaxGuard(resource.(*CgroupResourceUpdater), true, headroom, timeout, func() error {
+				var err error
+				mergedUpdater, err = mergeUpdateFn(resource)
+				return err
+			})
+			return mergedUpdater, err
+		}
+	}
+	return u
+}
+
+func writeWithMaxGuard(c *CgroupResourceUpdater, merge bool, headroom int64, timeout time.Duration, write func() error) error {
+	high, err := strconv.ParseInt(NormalizeCgroupValue(sysutil.MemoryHighName, c.valueToWrite()), 10, 64)
+	if err != nil || high < 0 {
+		return write()
+	}
+	if changed, err := c.wouldChange(merge); err != nil || !changed {
+		return write()
+	}
+	maxResource, err := sysutil.GetCgroupResource(sysutil.MemoryLimitName)
+	if err != nil {
+		return err
+	}
+	usageResource, err := sysutil.GetCgroupResource(sysutil.MemoryUsageName)
+	if err != nil {
+		return err
+	}
+	originalMax, err := cgroupFileRead(c.parentDir, maxResource)
+	if err != nil {
+		return fmt.Errorf("failed to read memory max for the guard of %s, err: %w", c.Path(), err)
+	}
+	maxValue, err := strconv.ParseInt(NormalizeCgroupValue(sysutil.MemoryLimitName, originalMax), 10, 64)
+	if err != nil || maxValue < 0 {
+		return write()
+	}
+	raisedMax := int64(math.MaxInt64)
+	if headroom < math.MaxInt64-maxValue {
+		raisedMax = maxValue + headroom
+	}
+	raisedMaxStr := strconv.FormatInt(raisedMax, 10)
+	err = cgroupFileWrite(c.parentDir, maxResource, raisedMaxStr)
+	c.addIssuedWrite(maxResource, raisedMaxStr, err)
+	if err != nil {
+		return fmt.Errorf("failed to raise memory max for the guard of %s, err: %w", c.Path(), err)
+	}
+	klog.V(5).Infof("raise memory max of %s from %v to %v before writing %s to %v",
+		c.parentDir, originalMax, raisedMax, c.Path(), c.valueToWrite())
+
+	writeErr := write()
+	if writeErr == nil {
+		err = waitForSettle(c, func() (bool, error) {
+			usage, err := cgroupFileReadInt(c.parentDir, usageResource)
+			if err != nil {
+				return false, err
+			}
+			return *usage < high, nil
+		}, timeout)
+		if err != nil {
+			klog.V(4).Infof("restore memory max of %s before the reclaim settles after writing %s, err: %v",
+				c.parentDir, c.Path(), err)
+		}
+	}
+	restoreMaxIfRaised(c, maxResource, originalMax, raisedMaxStr)
+	return writeErr
+}
+
+// restoreMaxIfRaised restores the memory max raised by the guard, which is skipped if the max is changed by others.
+func restoreMaxIfRaised(c *CgroupResourceUpdater, maxResource sysutil.Resource, originalMax, raisedMax string) {
+	currentMax, err := cgroupFileRead(c.parentDir, maxResource)
+	if err != nil {
+		klog.Warningf("failed to restore memory max of %s to %v, read err: %v", c.parentDir, originalMax, err)
+		return
+	}
+	if !ValuesEqual(sysutil.MemoryLimitName, currentMax, raisedMax) {
+		klog.V(4).Infof("skip restoring memory max of %s to %v, it is changed from %v to %v by others",
+			c.parentDir, originalMax, raisedMax, currentMax)
+		return
+	}
+	err = cgroupFileWrite(c.parentDir, maxResource, originalMax)
+	c.addIssuedWrite(maxResource, originalMax, err)
+	if err != nil {
+		klog.Warningf("failed to restore memory max of %s to %v, err: %v", c.parentDir, originalMax, err)
+		return
+	}
+	klog.V(5).Infof("restore memory max of %s to %v after writing %s", c.parentDir, originalMax, c.Path())
+}
+
+var domainCgroupTypes = []string{sysutil.CgroupTypeDomain, sysutil.CgroupTypeDomainThreaded}
+
+// cgroupTypeRequirements is the allowed cgroups-v2 types of the resources. The resources of the domain controllers
+// (e.g. memory, io) are unavailable in the threaded cgroups. The resources not in the map are allowed in any type.
+var cgroupTypeRequirements = struct {
+	lock         sync.RWMutex
+	requirements map[sysutil.ResourceType][]string
+}{
+	requirements: map[sysutil.ResourceType][]string{
+		sysutil.CPUProcsName:         domainCgroupTypes,
+		sysutil.MemoryLimitName:      domainCgroupTypes,
+		sysutil.MemoryMinName:        domainCgroupTypes,
+		sysutil.MemoryLowName:        domainCgroupTypes,
+		sysutil.MemoryHighName:       domainCgroupTypes,
+		sysutil.MemoryOomGroupName:   domainCgroupTypes,
+		sysutil.MemoryWmarkRatioName: domainCgroupTypes,
+		sysutil.BlkioIOWeightName:    domainCgroupTypes,
+		sysutil.BlkioIOQoSName:       domainCgroupTypes,
+	},
+}
+
+// RegisterCgroupTypeRequirement sets the allowed cgroups-v2 types of the resource for the WithCgroupTypeCheck.
+func RegisterCgroupTypeRequirement(resourceType sysutil.ResourceType, cgroupTypes ...string) {
+	cgroupTypeRequirements.lock.Lock()
+	defer cgroupTypeRequirements.lock.Unlock()
+	cgroupTypeRequirements.requirements[resourceType] = cgroupTypes
+}
+
+// getCgroupTypeRequirement returns the allowed cgroups-v2 types of the resource, and false if any type is allowed.
+func getCgroupTypeRequirement(resourceType sysutil.ResourceType) ([]string, bool) {
+	cgroupTypeRequirements.lock.RLock()
+	defer cgroupTypeRequirements.lock.RUnlock()
+	cgroupTypes, ok := cgroupTypeRequirements.requirements[resourceType]
+	return cgroupTypes, ok
+}
+
+// WithCgroupTypeCheck reads the `cgroup.type` of the cgroup before writing, and returns a descriptive error if the
+// resource is unavailable in the current cgroup type, e.g. writing the `memory.high` in a threaded cgroup. It only
+// takes effect on cgroups-v2.
+func (u *CgroupResourceUpdater) WithCgroupTypeCheck() *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		return false, checkCgroupType(c)
+	})
+}
+
+func checkCgroupType(c *CgroupResourceUpdater) error {
+	if !sysutil.IsCgroupV2Resource(c.file) {
+		return nil
+	}
+	allowedTypes, ok := getCgroupTypeRequirement(c.ResourceType())
+	if !ok {
+		return nil
+	}
+	cgroupType, err := cgroupFileRead(c.parentDir, sysutil.CgroupTypeV2)
+	if err != nil { // e.g. the root cgroup has no cgroup.type
+		klog.V(6).Infof("skip checking cgroup type for %s, failed to read cgroup.type, err: %v", c.Path(), err)
+		return nil
+	}
+	cgroupType = strings.TrimSpace(cgroupType)
+	for _, t := range allowedTypes {
+		if cgroupType == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("resource %s is not available in the %q cgroup %s, allowed cgroup types %q",
+		c.ResourceType(), cgroupType, c.parentDir, allowedTypes)
+}
+
+// WithVerifiedWrite makes the updater read back the value after writing, and re-write it up to the attempts if the
+// value is reverted by other writers. It fails if the value still differs after the attempts. The read-back value is
+// checked by the verify policy of the resource type, e.g. the difference of the kernel clamping (the memory limit
+// rounded down to the page size) is not considered as a revert. See VerifyWrite.
+func (u *CgroupResourceUpdater) WithVerifiedWrite(attempts int) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		c := resource.(*CgroupResourceUpdater)
+		_, err := verifiedWrite(c, attempts, func() (*CgroupResourceUpdater, error) {
+			return c, updateFn(resource)
+		})
+		return err
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			c := resource.(*CgroupResourceUpdater)
+			var mergedUpdater ResourceUpdater
+			written, err := verifiedWrite(c, attempts, func() (*CgroupResourceUpdater, error) {
+				var err error
+				mergedUpdater, err = mergeUpdateFn(resource)
+				if mergedUpdater == nil {
+					return c, err
+				}
+				return mergedUpdater.(*CgroupResourceUpdater), err
+			})
+			if err != nil {
+				return written, err
+			}
+			return mergedUpdater, nil
+		}
+	}
+	return u
+}
+
+// verifiedWrite calls the write func and reads back the written value until it sticks or the attempts are used up.
+// The write func returns the updater whose value is expected on the disk, e.g. the merged value of a merge update.
+func verifiedWrite(c *CgroupResourceUpdater, attempts int, write func() (*CgroupResourceUpdater, error)) (*CgroupResourceUpdater, error) {
+	if attempts <= 0 {
+		attempts = 1
+	}
+	var current string
+	for i := 0; i < attempts; i++ {
+		written, err := write()
+		if err != nil {
+			return written, err
+		}
+		current, err = written.ReadCurrent()
+		if err != nil {
+			return written, fmt.Errorf("failed to verify the write of %s, err: %w", written.Path(), err)
+		}
+		// compare with the value converted by the updateFunc, e.g. the `cpu.weight` written for the `cpu.shares`
+		expected, err := ConvertCgroupValue(written.file, written.value)
+		if err != nil {
+			return written, err
+		}
+		if isCgroupValueEqual(written.file, current, expected) || VerifyWrite(written.ResourceType(), expected, current) == nil {
+			return written, nil
+		}
+		klog.V(5).Infof("verify the write of %s failed, attempt %d/%d, expect %v, current %v",
+			written.Path(), i+1, attempts, written.value, current)
+	}
+	return c, fmt.Errorf("failed to verify the write of %s after %d attempts, expect %v, current %v",
+		c.Path(), attempts, c.value, current)
+}
+
+// isKernelClamped checks if the current value is the value clamped by the kernel, i.e. the normalized values are
+// equal, or the current value of a memory byte resource is the value rounded down to the page size.
+func isKernelClamped(resourceType sysutil.ResourceType, current, value string) bool {
+	current, value = NormalizeCgroupValue(resourceType, current), NormalizeCgroupValue(resourceType, value)
+	if current == value {
+		return true
+	}
+	if _, ok := memoryBytesResources[resourceType]; !ok {
+		return false
+	}
+	c, err := strconv.ParseInt(current, 10, 64)
+	if err != nil {
+		return false
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	pageSize := int64(os.Getpagesize())
+	return v > 0 && c == v/pageSize*pageSize
+}
+
+// getKernelVersion returns the cached kernel version, which can be replaced in tests.
+var getKernelVersion = sysutil.GetKernelVersion
+
+// WithKernelGuard skips the write when the running kernel does not satisfy the predicate, e.g. the `memory.wmark_ratio`
+// only exists on the Anolis kernel. It avoids the errors of writing the knobs unavailable on the kernel.
+func (u *CgroupResourceUpdater) WithKernelGuard(predicate func(kernelVersion string) bool) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		kernelVersion, err := getKernelVersion()
+		if err != nil {
+			klog.V(5).Infof("skip updating cgroup %s, failed to get kernel version, err: %v", c.Path(), err)
+			return true, nil
+		}
+		if !predicate(kernelVersion) {
+			klog.V(6).Infof("skip updating cgroup %s to %v, kernel version %s is not satisfied", c.Path(), c.value, kernelVersion)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// WithApplyCache makes the updater record the written value into the cache[key] after a successful write, which keeps
+// the cache of last-applied values consistent without a separate step. The cache is left unchanged on failure. For the
+// merge update, the merged value on disk is recorded, i.e. the current value if the merge is not needed. The caller
+// should guard the cache if it is shared across goroutines.
+func (u *CgroupResourceUpdater) WithApplyCache(cache map[string]string, key string) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		if err := updateFn(resource); err != nil {
+			return err
+		}
+		cache[key] = resource.Value()
+		return nil
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			mergedUpdater, err := mergeUpdateFn(resource)
+			if err != nil {
+				return mergedUpdater, err
+			}
+			if mergedUpdater != nil {
+				cache[key] = mergedUpdater.Value()
+			}
+			return mergedUpdater, nil
+		}
+	}
+	return u
+}
+
+// WithChecksumSkip makes the updater skip the write, including the read of the WriteIfDifferent, when the checksum of
+// the value matches the stored one of the path. The checksum is stored after a successful write, and is invalidated
+// after a failed write since the file content becomes unknown, or after a merge update.
+func (u *CgroupResourceUpdater) WithChecksumSkip(store *ChecksumStore) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		if store.Matches(resource.Path(), resource.Value()) {
+			klog.V(6).Infof("skip update cgroup %s to %v since the checksum matches", resource.Path(), resource.Value())
+			return nil
+		}
+		if err := updateFn(resource); err != nil {
+			store.Invalidate(resource.Path())
+			return err
+		}
+		store.Set(resource.Path(), resource.Value())
+		return nil
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			if store.Matches(resource.Path(), resource.Value()) {
+				klog.V(6).Infof("skip merge update cgroup %s to %v since the checksum matches", resource.Path(), resource.Value())
+				return nil, nil
+			}
+			// the merged value on disk is transitional and the checksum of the requested value must not match it,
+			// otherwise the following update to the requested value, e.g. the tightening write of the
+			// LeveledUpdateBatch, is skipped
+			store.Invalidate(resource.Path())
+			return mergeUpdateFn(resource)
+		}
+	}
+	return u
+}
+
+// WithDriftDetection makes the updater compare the desired value with both the current value on disk and the
+// last-applied value in the cache[key], e.g. the cache maintained by the WithApplyCache. It logs an "external drift
+// detected" warning when the current value differs from the last-applied one, which means another writer modified
+// the file, rather than our cache being stale. The write is skipped when the current value equals the desired one.
+// The values are compared as converted by the updateFunc, e.g. the `cpu.shares` is compared with the `cpu.weight` on
+// the cgroups-v2.
+// NOTE: The cache is not guarded by any lock, so the updaters sharing a cache must be updated in one goroutine.
+func (u *CgroupResourceUpdater) WithDriftDetection(cache map[string]string, key string) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		current, err := cgroupFileRead(c.parentDir, c.file)
+		if err != nil {
+			klog.V(5).Infof("failed to read cgroup %s for the drift detection, err: %v", c.Path(), err)
+			return false, nil
+		}
+		if lastApplied, ok := cache[key]; ok && !IsCgroupValueDesired(c.file, current, lastApplied) {
+			klog.Warningf("external drift detected on cgroup %s, current %v, last applied %v, desired %v",
+				c.Path(), current, lastApplied, c.value)
+		}
+		if IsCgroupValueDesired(c.file, current, c.value) {
+			klog.V(6).Infof("skip updating cgroup %s since the current value %v is desired", c.Path(), current)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// WithMountNamespace makes the updater write under the namespace of the nsPath, e.g. `/proc/<pid>/ns/mnt` of a
+// container, and restore the namespace of the koordlet after the write. It helps to write the cgroups only visible in
+// the other mount namespaces. It is only supported on linux.
+func (u *CgroupResourceUpdater) WithMountNamespace(nsPath string) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		return runInNamespace(nsPath, func() error {
+			return updateFn(resource)
+		})
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			var mergedUpdater ResourceUpdater
+			err := runInNamespace(nsPath, func() error {
+				var err error
+				mergedUpdater, err = mergeUpdateFn(resource)
+				return err
+			})
+			return mergedUpdater, err
+		}
+	}
+	return u
+}
+
+// ErrStaleUpdate is returned when the update is rejected since a newer sequence has been applied to the path.
+var ErrStaleUpdate = errors.New("stale update")
+
+func IsStaleUpdateErr(err error) bool {
+	return errors.Is(err, ErrStaleUpdate)
+}
+
+// appliedSequence is the last applied sequence of a path. The lock is held during the guarded write so that the check
+// and the write are atomic.
+type appliedSequence struct {
+	lock      sync.Mutex
+	parentDir string
+	seq       uint64
+	applied   bool
+}
+
+// appliedSequences records the last applied sequence of each path. The lock only guards the map, so the writes of the
+// different paths are not serialized.
+var appliedSequences = struct {
+	lock      sync.Mutex
+	sequences map[string]*appliedSequence
+}{
+	sequences: map[string]*appliedSequence{},
+}
+
+func getAppliedSequence(path, parentDir string) *appliedSequence {
+	appliedSequences.lock.Lock()
+	defer appliedSequences.lock.Unlock()
+	s, ok := appliedSequences.sequences[path]
+	if !ok {
+		s = &appliedSequence{parentDir: parentDir}
+		appliedSequences.sequences[path] = s
+	}
+	return s
+}
+
+func deleteAppliedSequence(path string, s *appliedSequence) {
+	appliedSequences.lock.Lock()
+	defer appliedSequences.lock.Unlock()
+	if appliedSequences.sequences[path] == s {
+		delete(appliedSequences.sequences, path)
+	}
+}
+
+// ForgetAppliedSequences drops the applied sequences of the given parent dir and its sub-directories, e.g. when a pod
+// is deleted. The applied sequences of a removed cgroup are also dropped by its next guarded write.
+func ForgetAppliedSequences(parentDir string) {
+	dir := filepath.Clean(parentDir)
+	appliedSequences.lock.Lock()
+	defer appliedSequences.lock.Unlock()
+	for path, s := range appliedSequences.sequences {
+		keyDir := filepath.Clean(s.parentDir)
+		if keyDir == dir || strings.HasPrefix(keyDir, dir+"/") {
+			delete(appliedSequences.sequences, path)
+		}
+	}
+}
+
+// WithSequence makes the updater carry a monotonic sequence number, e.g. the generation of the reconcile, and refuse
+// to apply the value if a higher sequence has been applied to the same path. It prevents an older reconcile's write
+// from clobbering a newer one in a racy controller. ErrStaleUpdate is returned when rejecting.
+func (u *CgroupResourceUpdater) WithSequence(seq uint64) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		return applyInSequence(resource, seq, func() error {
+			return updateFn(resource)
+		})
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			var mergedUpdater ResourceUpdater
+			err := applyInSequence(resource, seq, func() error {
+				var err error
+				mergedUpdater, err = mergeUpdateFn(resource)
+				return err
+			})
+			if IsStaleUpdateErr(err) {
+				return resource, err
+			}
+			return mergedUpdater, err
+		}
+	}
+	return u
+}
+
+func applyInSequence(resource ResourceUpdater, seq uint64, write func() error) error {
+	c := resource.(*CgroupResourceUpdater)
+	path := c.Path()
+	s := getAppliedSequence(path, c.parentDir)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.applied && seq < s.seq {
+		return fmt.Errorf("%w, path %s, value %v, sequence %d is lower than the applied %d",
+			ErrStaleUpdate, path, resource.Value(), seq, s.seq)
+	}
+	if err := write(); err != nil {
+		if IsCgroupDirErr(err) { // the cgroup is removed
+			deleteAppliedSequence(path, s)
+		}
+		return err
+	}
+	s.seq, s.applied = seq, true
+	return nil
+}
+
+// WithEmptyCPUSetAllowed overrides the guard of the `cpuset.cpus` updater to allow writing the empty cpuset, e.g. to
+// drain a cgroup intentionally. It replaces the update funcs, so it should be applied before the other options.
+func (u *CgroupResourceUpdater) WithEmptyCPUSetAllowed() *CgroupResourceUpdater {
+	u.updateFunc = CommonCgroupUpdateFunc
+	if u.mergeUpdateFunc != nil {
+		u.mergeCondition = MergeConditionIfCPUSetIsLooser
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			return MergeFuncUpdateCgroup(resource, MergeConditionIfCPUSetIsLooser)
+		}
+	}
+	return u
+}
+
+// WithFeatureGate skips the write when the named feature gate is disabled, e.g. gateFn is the `Enabled` of the koordlet
+// feature gates. It keeps the gating logic out of the callers.
+func (u *CgroupResourceUpdater) WithFeatureGate(name string, gateFn func(string) bool) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		if !gateFn(name) {
+			klog.V(6).Infof("skip updating cgroup %s to %v, feature gate %s is disabled", c.Path(), c.value, name)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// WithBufferedWrite makes the updater write a multi-line value in a single call where the resource semantics allow,
+// e.g. the truncate-style files, instead of writing line by line. See CgroupBufferedWriteFunc.
+func (u *CgroupResourceUpdater) WithBufferedWrite() *CgroupResourceUpdater {
+	u.mergeUpdateFunc = nil
+	u.mergeCondition = nil
+	return u.WithUpdateFunc(CgroupBufferedWriteFunc)
+}
+
+// WithOwner tags the updater with the module initiating the update, e.g. a koordlet plugin, so the update metrics are
+// labeled with the owner and the audit events note it. The owner is OwnerUnknown if not tagged.
+func (u *CgroupResourceUpdater) WithOwner(module string) *CgroupResourceUpdater {
+	u.owner = module
+	tags := make(map[string]string, len(u.auditTags)+1)
+	for k, v := range u.auditTags {
+		tags[k] = v
+	}
+	tags[ownerKey] = module
+	u.auditTags = tags
+	return u
+}
+
+// writeStackDepth is the max number of the caller frames logged by WithStackTraceOnWrite.
+const writeStackDepth = 8
+
+// WithStackTraceOnWrite makes the updater log a short caller stack of each write when the log verbosity is at or above
+// the minLevel, which helps to identify the controller initiating an unexpected write. The stack is not captured
+// when the verbosity is below the minLevel, so it is cheap to keep the option on.
+func (u *CgroupResourceUpdater) WithStackTraceOnWrite(minLevel int) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		if v := klog.V(klog.Level(minLevel)); v.Enabled() {
+			v.Infof("write cgroup %s to %v, called by: %s", c.Path(), c.value, callerStack(writeStackDepth))
+		}
+		return false, nil
+	})
+}
+
+// callerStack returns the callers of the update funcs in the format of "pkg.func:line <- ...".
+func callerStack(depth int) string {
+	pcs := make([]uintptr, depth)
+	n := runtime.Callers(4, pcs) // skip runtime.Callers, callerStack, the pre-update func and its wrapper
+	frames := runtime.CallersFrames(pcs[:n])
+	var callers []string
+	for {
+		frame, more := frames.Next()
+		callers = append(callers, fmt.Sprintf("%s:%d", filepath.Base(frame.Function), frame.Line))
+		if !more {
+			break
+		}
+	}
+	return strings.Join(callers, " <- ")
+}
+
+// WithTaskCgroupCheck makes the updater of `cgroup.procs` or `tasks` verify the current cgroup of the pid before
+// moving it, which prevents the cross-pod moves from a buggy caller. The write is skipped with a warning if the pid's
+// current cgroup path is not under the expectedPrefix, e.g. "/kubepods.slice/kubepods-pod1.slice".
+func (u *CgroupResourceUpdater) WithTaskCgroupCheck(expectedPrefix string) *CgroupResourceUpdater {
+	prefix := filepath.Clean(expectedPrefix)
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		pid, err := strconv.Atoi(strings.TrimSpace(c.value))
+		if err != nil {
+			return false, fmt.Errorf("invalid pid %q to move into %s, err: %v", c.value, c.Path(), err)
+		}
+		cgroupPath, err := readTaskCgroupPath(pid, c.file)
+		if err != nil {
+			klog.Warningf("skip moving pid %d into %s, failed to read its current cgroup, err: %v", pid, c.Path(), err)
+			return true, nil
+		}
+		if cgroupPath != prefix && !strings.HasPrefix(cgroupPath, prefix+"/") {
+			klog.Warningf("skip moving pid %d into %s, its current cgroup %s is not under the expected %s",
+				pid, c.Path(), cgroupPath, prefix)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// readTaskCgroupPath reads the cgroup path of the pid in the hierarchy of the resource from the `/proc/<pid>/cgroup`.
+// The line is in the format of "hierarchy-ID:controller-list:cgroup-path", e.g. "4:cpu,cpuacct:/kubepods.slice" on
+// cgroups-v1, and "0::/kubepods.slice" on cgroups-v2.
+func readTaskCgroupPath(pid int, r sysutil.Resource) (string, error) {
+	content, err := os.ReadFile(filepath.Join(sysutil.Conf.ProcRootDir, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	subsystem := ""
+	if cr, ok := r.(*sysutil.CgroupResource); ok && !sysutil.IsCgroupV2Resource(r) {
+		subsystem = strings.TrimSuffix(cr.Subfs, "/")
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if subsystem == "" && fields[0] == "0" && fields[1] == "" {
+			return filepath.Clean(fields[2]), nil
+		}
+		if subsystem != "" && util.IsIn(strings.Split(fields[1], ","), subsystem) {
+			return filepath.Clean(fields[2]), nil
+		}
+	}
+	return "", fmt.Errorf("cgroup of %s not found for pid %d", r.ResourceType(), pid)
+}
+
+// updaterClock is the clock of the time-based options. It is replaced in the tests.
+var updaterClock clock.PassiveClock = clock.RealClock{}
+
+// TimeWindow is a daily window in the local time, described by the offsets from the midnight. The window spans the
+// midnight when End is less than Start, e.g. {Start: 22h, End: 2h}.
+type TimeWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains returns if the time of day of t is in [Start, End).
+func (w TimeWindow) Contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// ErrOutsideSchedule is returned when the update is deferred since the current time is outside the schedule windows.
+// The caller can retry it later.
+var ErrOutsideSchedule = errors.New("outside schedule windows")
+
+func IsOutsideScheduleErr(err error) bool {
+	return errors.Is(err, ErrOutsideSchedule)
+}
+
+// WithSchedule makes the updater only apply within the maintenance windows, e.g. the disruptive writes like the
+// memory limit shrinking. The update is deferred with ErrOutsideSchedule outside all the windows.
+func (u *CgroupResourceUpdater) WithSchedule(windows []TimeWindow) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		now := updaterClock.Now()
+		for _, w := range windows {
+			if w.Contains(now) {
+				return false, nil
+			}
+		}
+		klog.V(5).Infof("defer updating cgroup %s to %v, time %v is outside the schedule windows",
+			c.Path(), c.value, now.Format(time.RFC3339))
+		return false, fmt.Errorf("update resource %s deferred, err: %w", c.Key(), ErrOutsideSchedule)
+	})
+}
+
+// WithOnFirstWrite calls the onFirstWrite after a successful write which changes the resource from the unset value for
+// the first time, i.e. the current value before the write is empty or the registered default, and the written value
+// differs from it. It helps to track when a resource becomes managed, e.g. the adoption of a QoS feature.
+func (u *CgroupResourceUpdater) WithOnFirstWrite(onFirstWrite func(ResourceUpdater)) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		unset, current := isResourceUnset(resource.(*CgroupResourceUpdater))
+		if err := updateFn(resource); err != nil {
+			return err
+		}
+		if unset && !ValuesEqual(resource.ResourceType(), current, resource.Value()) {
+			onFirstWrite(resource)
+		}
+		return nil
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			unset, current := isResourceUnset(resource.(*CgroupResourceUpdater))
+			mergedUpdater, err := mergeUpdateFn(resource)
+			if err != nil {
+				return mergedUpdater, err
+			}
+			if unset && mergedUpdater != nil && !ValuesEqual(resource.ResourceType(), current, mergedUpdater.Value()) {
+				onFirstWrite(mergedUpdater)
+			}
+			return mergedUpdater, nil
+		}
+	}
+	return u
+}
+
+// isResourceUnset returns if the current value of the resource is empty or the registered default, and the current
+// value. The resource is not considered unset if it fails to read.
+func isResourceUnset(c *CgroupResourceUpdater) (bool, string) {
+	current, err := c.ReadCurrent()
+	if err != nil {
+		klog.V(6).Infof("failed to read cgroup %s before the write, err: %v", c.Path(), err)
+		return false, ""
+	}
+	if strings.TrimSpace(current) == "" {
+		return true, current
+	}
+	defaultValue, ok := GetDefault(c.ResourceType())
+	return ok && ValuesEqual(c.ResourceType(), current, defaultValue), current
+}
+
+// EventReasonUpdateResourceFailed is the reason of the warning event emitted on a failed write.
+const EventReasonUpdateResourceFailed = "UpdateResourceFailed"
+
+// failureEventInterval is the min interval between the failure events of the same path.
+var failureEventInterval = 5 * time.Minute
+
+// lastFailureEvents records the time of the last failure event of each path, so the events of the persistent failures
+// are rate-limited across the updaters recreated in each reconcile. The records older than failureEventInterval are
+// swept on the next emitted event, so the paths of the removed cgroups are not kept forever.
+var lastFailureEvents = struct {
+	lock  sync.Mutex
+	times map[string]time.Time
+}{
+	times: map[string]time.Time{},
+}
+
+// WithEventRecorder makes the updater emit a Warning event referencing the obj, e.g. the pod or the node, when the
+// write fails, so the operators have a visible signal of the persistent failures. The events of the same path are
+// emitted at most once per failureEventInterval. The successful writes emit no event.
+func (u *CgroupResourceUpdater) WithEventRecorder(recorder record.EventRecorder, obj apiruntime.Object) *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		err := updateFn(resource)
+		if err != nil {
+			recordFailureEvent(recorder, obj, resource, err)
+		}
+		return err
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			mergedUpdater, err := mergeUpdateFn(resource)
+			if err != nil {
+				recordFailureEvent(recorder, obj, resource, err)
+			}
+			return mergedUpdater, err
+		}
+	}
+	return u
+}
+
+func recordFailureEvent(recorder record.EventRecorder, obj apiruntime.Object, resource ResourceUpdater, err error) {
+	now := updaterClock.Now()
+	lastFailureEvents.lock.Lock()
+	last, ok := lastFailureEvents.times[resource.Path()]
+	if ok && now.Sub(last) < failureEventInterval {
+		lastFailureEvents.lock.Unlock()
+		klog.V(6).Infof("skip the failure event of %s since the last is emitted at %v", resource.Path(), last)
+		return
+	}
+	for path, t := range lastFailureEvents.times {
+		if now.Sub(t) >= failureEventInterval {
+			delete(lastFailureEvents.times, path)
+		}
+	}
+	lastFailureEvents.times[resource.Path()] = now
+	lastFailureEvents.lock.Unlock()
+	recorder.Eventf(obj, corev1.EventTypeWarning, EventReasonUpdateResourceFailed, "failed to update %s to %v, err: %v",
+		resource.Path(), resource.Value(), err)
+}
+
+// WithLastWriteTimestamp makes the updater record the time of the successful write into the
+// ResourceLastWriteTimestampSeconds of the resource type, so a stuck reconcile can be alerted by the gauge not
+// advancing. The skipped writes, i.e. the content of the file is unchanged, and the failed writes are not recorded.
+func (u *CgroupResourceUpdater) WithLastWriteTimestamp() *CgroupResourceUpdater {
+	updateFn := u.updateFunc
+	u.updateFunc = func(resource ResourceUpdater) error {
+		return recordIfWritten(resource.(*CgroupResourceUpdater), func() error {
+			return updateFn(resource)
+		})
+	}
+	if u.mergeUpdateFunc != nil {
+		mergeUpdateFn := u.mergeUpdateFunc
+		u.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+			var mergedUpdater ResourceUpdater
+			err := recordIfWritten(resource.(*CgroupResourceUpdater), func() error {
+				var err error
+				mergedUpdater, err = mergeUpdateFn(resource)
+				return err
+			})
+			return mergedUpdater, err
+		}
+	}
+	return u
+}
+
+// recordIfWritten records the last write timestamp if the write succeeds and changes the content of the file. The
+// write is considered changed if the content fails to read before the write.
+func recordIfWritten(c *CgroupResourceUpdater, write func() error) error {
+	before, beforeErr := c.ReadCurrent()
+	if err := write(); err != nil {
+		return err
+	}
+	if beforeErr == nil {
+		if after, err := c.ReadCurrent(); err == nil && after == before {
+			return nil
+		}
+	}
+	recordLastWriteTimestamp(c.ResourceType(), updaterClock.Now())
+	return nil
+}
+
+// WithRoot makes the updater prefix the cgroup file paths with the given root, e.g. a temp dir as the fake sysfs in
+// the tests, so the writes and the reads including the merge reads go under the root instead of the host cgroupfs.
+// Since there is no kernel behind the root, the resource is considered supported if the file exists under the root.
+func (u *CgroupResourceUpdater) WithRoot(root string) *CgroupResourceUpdater {
+	u.file = &rootedResource{Resource: u.file, root: root}
+	return u
+}
+
+// rootedResource wraps a resource to generate the file path under the root.
+type rootedResource struct {
+	sysutil.Resource
+	root string
+}
+
+func (r *rootedResource) Path(dynamicPath string) string {
+	return filepath.Join(r.root, r.Resource.Path(dynamicPath))
+}
+
+func (r *rootedResource) IsSupported(dynamicPath string) (bool, string) {
+	if exists, _ := sysutil.PathExists(r.Path(dynamicPath)); exists {
+		return true, ""
+	}
+	return r.Resource.IsSupported(dynamicPath)
+}
+
+// GetCgroupVersion returns the cgroup version of the wrapped resource, which is used by sysutil.IsCgroupV2Resource.
+func (r *rootedResource) GetCgroupVersion() sysutil.CgroupVersion {
+	if c, ok := r.Resource.(interface{ GetCgroupVersion() sysutil.CgroupVersion }); ok {
+		return c.GetCgroupVersion()
+	}
+	return sysutil.CgroupVersionV1
+}
+
+func (r *rootedResource) WithValidator(validator sysutil.ResourceValidator) sysutil.Resource {
+	return &rootedResource{Resource: r.Resource.WithValidator(validator), root: r.root}
+}
+
+func (r *rootedResource) WithSupported(supported bool, msg string) sysutil.Resource {
+	return &rootedResource{Resource: r.Resource.WithSupported(supported, msg), root: r.root}
+}
+
+func (r *rootedResource) WithCheckSupported(checkSupportedFn func(r sysutil.Resource, dynamicPath string) (isSupported bool, msg string)) sysutil.Resource {
+	return &rootedResource{Resource: r.Resource.WithCheckSupported(checkSupportedFn), root: r.root}
+}
+
+func (r *rootedResource) WithCheckOnce(isCheckOnce bool) sysutil.Resource {
+	return &rootedResource{Resource: r.Resource.WithCheckOnce(isCheckOnce), root: r.root}
+}
+
+// ErrUpdateDisabled is returned when the write is suppressed by the disable marker. The executor ignores it without
+// caching the update, so the write is resumed once the marker is removed.
+var ErrUpdateDisabled = errors.New("update disabled by the marker")
+
+func IsUpdateDisabledErr(err error) bool {
+	return errors.Is(err, ErrUpdateDisabled)
+}
+
+// WithDisableMarker skips the write when the marker file exists, e.g. "/var/run/koordlet/disable-cpu" touched by the
+// operators to halt a misbehaving reconcile of a controller or a resource without restarting the koordlet. The
+// suppressed write returns ErrUpdateDisabled. The writes are resumed once the marker is removed.
+func (u *CgroupResourceUpdater) WithDisableMarker(path string) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		exists, err := sysutil.PathExists(path)
+		if err != nil {
+			klog.V(4).Infof("failed to check the disable marker %s for cgroup %s, err: %v", path, c.Path(), err)
+			return false, nil
+		}
+		if exists {
+			klog.V(4).Infof("skip updating cgroup %s to %v, disabled by the marker %s", c.Path(), c.value, path)
+			return false, fmt.Errorf("update cgroup %s skipped, err: %w", c.Path(), ErrUpdateDisabled)
+		}
+		return false, nil
+	})
+}
+
+// BoundsSource provides the valid range of the values of a resource, e.g. read from a companion file exposed by the
+// kernel, so the range adapts to the kernel differences instead of being hardcoded in the validator.
+type BoundsSource interface {
+	Bounds(resourceType sysutil.ResourceType, parentDir string) (min int64, max int64, err error)
+}
+
+// BoundsSourceFunc is a function implementing the BoundsSource.
+type BoundsSourceFunc func(resourceType sysutil.ResourceType, parentDir string) (int64, int64, error)
+
+func (f BoundsSourceFunc) Bounds(resourceType sysutil.ResourceType, parentDir string) (int64, int64, error) {
+	return f(resourceType, parentDir)
+}
+
+var ErrValueOutOfBounds = errors.New("value out of bounds")
+
+func IsValueOutOfBoundsErr(err error) bool {
+	return errors.Is(err, ErrValueOutOfBounds)
+}
+
+// WithDynamicBounds checks the integer value against the bounds read from the source before writing. If clamp is
+// true, the out-of-range value is clamped into the bounds, otherwise the write fails with ErrValueOutOfBounds. The
+// unlimited values like "max" are not checked.
+func (u *CgroupResourceUpdater) WithDynamicBounds(source BoundsSource, clamp bool) *CgroupResourceUpdater {
+	return u.withPreUpdate(func(c *CgroupResourceUpdater) (bool, error) {
+		return false, applyDynamicBounds(c, source, clamp)
+	})
+}
+
+func applyDynamicBounds(c *CgroupResourceUpdater, source BoundsSource, clamp bool) error {
+	value := c.valueToWrite()
+	if value == sysutil.CgroupMaxSymbolStr || value == sysutil.CgroupUnlimitedSymbolStr {
+		return nil
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse value %s for bounds, err: %w", value, err)
+	}
+	min, max, err := source.Bounds(c.ResourceType(), c.parentDir)
+	if err != nil {
+		return fmt.Errorf("failed to get bounds of cgroup %s, err: %w", c.Path(), err)
+	}
+	bounded := v
+	if bounded < min {
+		bounded = min
+	}
+	if bounded > max {
+		bounded = max
+	}
+	if bounded == v {
+		return nil
+	}
+	if !clamp {
+		return fmt.Errorf("%w, cgroup %s value %d is not in [%d, %d]", ErrValueOutOfBounds, c.Path(), v, min, max)
+	}
+	klog.V(5).Infof("clamp cgroup %s value from %s to bounds [%d, %d]", c.Path(), value, min, max)
+	// keep the requested value, so the value is written as requested once the bounds cover it
+	c.setValueToWrite(strconv.FormatInt(bounded, 10))
+	return nil
+}
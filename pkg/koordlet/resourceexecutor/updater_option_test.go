@@ -0,0 +1,1563 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestCgroupResourceUpdater_WithParentCeiling(t *testing.T) {
+	tests := []struct {
+		name        string
+		parentValue string
+		value       string
+		want        string
+	}{
+		{
+			name:        "clamp child value above the parent ceiling",
+			parentValue: "1048576",
+			value:       "2097152",
+			want:        "1048576",
+		},
+		{
+			name:        "keep child value below the parent ceiling",
+			parentValue: "2097152",
+			value:       "1048576",
+			want:        "1048576",
+		},
+		{
+			name:        "keep child value when parent is unlimited",
+			parentValue: "max",
+			value:       "2097152",
+			want:        "2097152",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(true)
+
+			upperDir := "/kubepods.slice/kubepods-burstable.slice"
+			parentDir := upperDir + "/kubepods-burstable-pod1.slice"
+			helper.WriteCgroupFileContents(upperDir, sysutil.MemoryHighV2, tt.parentValue)
+			helper.WriteCgroupFileContents(parentDir, sysutil.MemoryHighV2, "0")
+
+			u, err := NewCommonCgroupUpdater(sysutil.MemoryHighName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			u = u.(*CgroupResourceUpdater).WithParentCeiling(sysutil.MemoryHighName)
+			assert.NoError(t, u.update())
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+			assert.Equal(t, tt.value, u.Value())
+		})
+	}
+	t.Run("write the requested value after the parent ceiling rises", func(t *testing.T) {
+		helper := sysutil.NewFileTestUtil(t)
+		defer helper.Cleanup()
+		helper.SetCgroupsV2(true)
+
+		upperDir := "/kubepods.slice/kubepods-burstable.slice"
+		parentDir := upperDir + "/kubepods-burstable-pod1.slice"
+		helper.WriteCgroupFileContents(upperDir, sysutil.MemoryHighV2, "1048576")
+		helper.WriteCgroupFileContents(parentDir, sysutil.MemoryHighV2, "0")
+
+		u, err := NewCommonCgroupUpdater(sysutil.MemoryHighName, parentDir, "2097152", nil)
+		assert.NoError(t, err)
+		u = u.(*CgroupResourceUpdater).WithParentCeiling(sysutil.MemoryHighName)
+		assert.NoError(t, u.update())
+		assert.Equal(t, "1048576", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+		helper.WriteCgroupFileContents(upperDir, sysutil.MemoryHighV2, "4194304")
+		assert.NoError(t, u.update())
+		assert.Equal(t, "2097152", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+	})
+}
+
+func TestCgroupResourceUpdater_WithQuantityParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "parse binary quantity",
+			value: "512Mi",
+			want:  "536870912",
+		},
+		{
+			name:  "parse quantity of Gi",
+			value: "1Gi",
+			want:  "1073741824",
+		},
+		{
+			name:  "keep raw integer",
+			value: "1048576",
+			want:  "1048576",
+		},
+		{
+			name:    "invalid quantity",
+			value:   "512Mx",
+			want:    "0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(true)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.MemoryHighV2, "0")
+
+			u, err := NewCommonCgroupUpdater(sysutil.MemoryHighName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			u = u.(*CgroupResourceUpdater).WithQuantityParsing()
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+			assert.Equal(t, tt.value, u.Value())
+		})
+	}
+	t.Run("clamp the parsed value to the parent ceiling", func(t *testing.T) {
+		helper := sysutil.NewFileTestUtil(t)
+		defer helper.Cleanup()
+		helper.SetCgroupsV2(true)
+
+		upperDir := "/kubepods.slice/kubepods-burstable.slice"
+		parentDir := upperDir + "/kubepods-burstable-pod1.slice"
+		helper.WriteCgroupFileContents(upperDir, sysutil.MemoryHighV2, "1048576")
+		helper.WriteCgroupFileContents(parentDir, sysutil.MemoryHighV2, "0")
+
+		u, err := NewCommonCgroupUpdater(sysutil.MemoryHighName, parentDir, "1Gi", nil)
+		assert.NoError(t, err)
+		u = u.(*CgroupResourceUpdater).WithParentCeiling(sysutil.MemoryHighName).WithQuantityParsing()
+		assert.NoError(t, u.update())
+		assert.Equal(t, "1048576", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+		helper.WriteCgroupFileContents(upperDir, sysutil.MemoryHighV2, "max")
+		assert.NoError(t, u.update())
+		assert.Equal(t, "1073741824", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+		assert.Equal(t, "1Gi", u.Value())
+	})
+}
+
+func TestCgroupResourceUpdater_WithShadowWrite(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	shadowRoot := t.TempDir()
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	shadowPath := func(r sysutil.Resource) string {
+		rel, err := filepath.Rel(sysutil.Conf.CgroupRootDir, r.Path(parentDir))
+		assert.NoError(t, err)
+		return filepath.Join(shadowRoot, rel)
+	}
+	readShadow := func(r sysutil.Resource) string {
+		content, err := os.ReadFile(shadowPath(r))
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	u = u.(*CgroupResourceUpdater).WithShadowWrite(shadowRoot)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "20000", readShadow(sysutil.CPUCFSQuota))
+
+	// the merge update also goes to the shadow path
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "30000", nil)
+	assert.NoError(t, err)
+	u = u.(*CgroupResourceUpdater).WithShadowWrite(shadowRoot)
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "30000", readShadow(sysutil.CPUCFSQuota))
+
+	// no dir is created in the cgroupfs
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "40000", nil)
+	assert.NoError(t, err)
+	assert.Error(t, u.(*CgroupResourceUpdater).WithShadowWrite(filepath.Join(sysutil.Conf.CgroupRootDir, "shadow")).update())
+	_, err = os.Stat(filepath.Join(sysutil.Conf.CgroupRootDir, "shadow"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the value is converted as the real write
+	helper.SetCgroupsV2(true)
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "100")
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithShadowWrite(shadowRoot).update())
+	assert.Equal(t, "100", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+	assert.Equal(t, "39", readShadow(sysutil.CPUSharesV2))
+}
+
+func TestCgroupResourceUpdater_WithSettleCheck(t *testing.T) {
+	oldInterval := settleCheckInterval
+	settleCheckInterval = time.Millisecond
+	defer func() {
+		settleCheckInterval = oldInterval
+	}()
+
+	tests := []struct {
+		name       string
+		passAfter  int
+		wantPolls  int
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:      "settle after two polls",
+			passAfter: 2,
+			wantPolls: 2,
+		},
+		{
+			name:       "settle check times out",
+			passAfter:  -1,
+			wantErr:    true,
+			wantErrMsg: "timed out",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			polls := 0
+			check := func() (bool, error) {
+				polls++
+				return tt.passAfter > 0 && polls >= tt.passAfter, nil
+			}
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			u = u.(*CgroupResourceUpdater).WithSettleCheck(check, 50*time.Millisecond)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.wantErr {
+				assert.Contains(t, gotErr.Error(), tt.wantErrMsg)
+				assert.Contains(t, gotErr.Error(), u.Path())
+			} else {
+				assert.Equal(t, tt.wantPolls, polls)
+			}
+			assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithCgroupTypeCheck(t *testing.T) {
+	RegisterCgroupTypeRequirement(sysutil.CPUCFSQuotaName, sysutil.CgroupTypeThreaded)
+	defer delete(cgroupTypeRequirements.requirements, sysutil.CPUCFSQuotaName)
+
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		cgroupType   string
+		resource     sysutil.Resource
+		value        string
+		want         string
+		wantErrMsg   string
+	}{
+		{
+			name:         "write a threaded-only file in a domain cgroup",
+			useCgroupsV2: true,
+			cgroupType:   sysutil.CgroupTypeDomain,
+			resource:     sysutil.CPUCFSQuotaV2,
+			value:        "20000",
+			want:         "max 100000",
+			wantErrMsg:   `resource cpu.cfs_quota_us is not available in the "domain" cgroup`,
+		},
+		{
+			name:         "write a threaded-only file in a threaded cgroup",
+			useCgroupsV2: true,
+			cgroupType:   sysutil.CgroupTypeThreaded,
+			resource:     sysutil.CPUCFSQuotaV2,
+			value:        "20000",
+			want:         "20000",
+		},
+		{
+			name:         "write a domain-only file in a threaded cgroup",
+			useCgroupsV2: true,
+			cgroupType:   sysutil.CgroupTypeThreaded,
+			resource:     sysutil.MemoryLimitV2,
+			value:        "1048576",
+			want:         "max",
+			wantErrMsg:   `resource memory.limit_in_bytes is not available in the "threaded" cgroup`,
+		},
+		{
+			name:         "write a domain-only file in a threaded root",
+			useCgroupsV2: true,
+			cgroupType:   sysutil.CgroupTypeDomainThreaded,
+			resource:     sysutil.MemoryLimitV2,
+			value:        "1048576",
+			want:         "1048576",
+		},
+		{
+			name:       "skip the check on cgroups-v1",
+			cgroupType: sysutil.CgroupTypeDomain,
+			resource:   sysutil.CPUCFSQuota,
+			value:      "20000",
+			want:       "20000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			if tt.useCgroupsV2 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CgroupTypeV2, tt.cgroupType+"\n")
+			}
+			initValue := "-1"
+			if tt.useCgroupsV2 {
+				initValue = "max"
+				if tt.resource == sysutil.CPUCFSQuotaV2 {
+					initValue = "max 100000"
+				}
+			}
+			helper.WriteCgroupFileContents(parentDir, tt.resource, initValue)
+
+			u, err := DefaultCgroupUpdaterFactory.New(tt.resource.ResourceType(), parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			u = u.(*CgroupResourceUpdater).WithCgroupTypeCheck()
+			gotErr := u.update()
+			if tt.wantErrMsg != "" {
+				assert.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tt.wantErrMsg)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, tt.resource))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithVerifiedWrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		reverts    int
+		attempts   int
+		value      string
+		want       string
+		wantWrites int
+		wantErr    bool
+	}{
+		{
+			name:       "write sticks at the first attempt",
+			attempts:   3,
+			value:      "20000",
+			want:       "20000",
+			wantWrites: 1,
+		},
+		{
+			name:       "value reverted once then sticks",
+			reverts:    1,
+			attempts:   3,
+			value:      "20000",
+			want:       "20000",
+			wantWrites: 2,
+		},
+		{
+			name:       "value keeps reverted",
+			reverts:    3,
+			attempts:   3,
+			value:      "20000",
+			want:       "10000",
+			wantWrites: 3,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			writes := 0
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+				writes++
+				if writes <= tt.reverts { // the other writer reverts the value
+					helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+					return nil
+				}
+				return CommonCgroupUpdateFunc(resource)
+			}).WithVerifiedWrite(tt.attempts)
+			gotErr := c.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantWrites, writes)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+		})
+	}
+}
+
+func Test_isKernelClamped(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+	assert.True(t, isKernelClamped(sysutil.MemoryLimitName, strconv.FormatInt(pageSize, 10), strconv.FormatInt(pageSize+1, 10)))
+	assert.True(t, isKernelClamped(sysutil.MemoryLimitName, "max", "-1"))
+	assert.False(t, isKernelClamped(sysutil.MemoryLimitName, strconv.FormatInt(pageSize, 10), strconv.FormatInt(2*pageSize, 10)))
+	assert.False(t, isKernelClamped(sysutil.CPUCFSQuotaName, "10000", "20000"))
+	// the page rounding only applies to the memory byte resources
+	assert.False(t, isKernelClamped(sysutil.CPUCFSQuotaName, strconv.FormatInt(pageSize, 10), strconv.FormatInt(pageSize+1, 10)))
+}
+
+func TestCgroupResourceUpdater_WithVerifiedWrite_Merge(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-1")
+
+	writes := 0
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "2-3", nil)
+	assert.NoError(t, err)
+	c := u.(*CgroupResourceUpdater)
+	mergeUpdateFn := c.mergeUpdateFunc
+	c.mergeUpdateFunc = func(resource ResourceUpdater) (ResourceUpdater, error) {
+		writes++
+		return mergeUpdateFn(resource)
+	}
+	// the merged union on disk is verified instead of the requested value
+	merged, err := c.WithVerifiedWrite(3).MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, writes)
+	assert.Equal(t, "0-3", merged.Value())
+	assert.Equal(t, "0-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+}
+
+func TestCgroupResourceUpdater_WithVerifiedWrite_CgroupsV2(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "100")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2, "20000 100000")
+
+	// the `cpu.weight` converted from the `cpu.shares` is verified
+	writes := 0
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	c := u.(*CgroupResourceUpdater)
+	updateFn := c.updateFunc
+	c.updateFunc = func(resource ResourceUpdater) error {
+		writes++
+		return updateFn(resource)
+	}
+	assert.NoError(t, c.WithVerifiedWrite(3).update())
+	assert.Equal(t, 1, writes)
+	assert.Equal(t, "1024", c.Value())
+	assert.Equal(t, "39", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+
+	// the unlimited `cpu.max` is verified
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "-1", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithVerifiedWrite(3).update())
+	assert.Equal(t, "max", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2))
+}
+
+func TestCgroupResourceUpdater_WithKernelGuard(t *testing.T) {
+	oldGetKernelVersion := getKernelVersion
+	defer func() {
+		getKernelVersion = oldGetKernelVersion
+	}()
+	isAnolisKernel := func(kernelVersion string) bool {
+		return strings.Contains(kernelVersion, ".an8.")
+	}
+
+	tests := []struct {
+		name          string
+		kernelVersion string
+		kernelErr     error
+		want          string
+	}{
+		{
+			name:          "write on the matching kernel",
+			kernelVersion: "5.10.134-13.an8.x86_64",
+			want:          "20000",
+		},
+		{
+			name:          "skip on the non-matching kernel",
+			kernelVersion: "5.15.0-91-generic",
+			want:          "10000",
+		},
+		{
+			name:      "skip if failed to get kernel version",
+			kernelErr: fmt.Errorf("expected error"),
+			want:      "10000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			getKernelVersion = func() (string, error) {
+				return tt.kernelVersion, tt.kernelErr
+			}
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			u = u.(*CgroupResourceUpdater).WithKernelGuard(isAnolisKernel)
+			assert.NoError(t, u.update())
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+			_, err = u.MergeUpdate()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithApplyCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		writeErr  error
+		wantCache map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "record the value on success",
+			value:     "20000",
+			wantCache: map[string]string{"pod1/cfs_quota": "20000"},
+		},
+		{
+			name:      "keep the cache unchanged on failure",
+			value:     "20000",
+			writeErr:  fmt.Errorf("expected error"),
+			wantCache: map[string]string{"pod1/cfs_quota": "10000"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			cache := map[string]string{"pod1/cfs_quota": "10000"}
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+				if tt.writeErr != nil {
+					return tt.writeErr
+				}
+				return CommonCgroupUpdateFunc(resource)
+			}).WithApplyCache(cache, "pod1/cfs_quota")
+			gotErr := c.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantCache, cache)
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithApplyCache_Merge(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-1")
+
+	cache := map[string]string{}
+	newUpdater := func(value string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithApplyCache(cache, "pod1/cpuset")
+	}
+
+	// the merged union actually written is recorded instead of the requested value
+	_, err := newUpdater("2-3").MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "0-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+	assert.Equal(t, map[string]string{"pod1/cpuset": "0-3"}, cache)
+
+	// the current value is recorded when the merge is not needed
+	_, err = newUpdater("1").MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "0-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+	assert.Equal(t, map[string]string{"pod1/cpuset": "0-3"}, cache)
+}
+
+func TestCgroupResourceUpdater_WithChecksumSkip(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+	store := NewChecksumStore()
+	calls := 0
+	newUpdater := func(value string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+			calls++ // read and write if different
+			return CommonCgroupUpdateFunc(resource)
+		}).WithChecksumSkip(store)
+	}
+
+	assert.NoError(t, newUpdater("20000").update())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// the identical write is skipped without a read
+	assert.NoError(t, newUpdater("20000").update())
+	assert.Equal(t, 1, calls)
+
+	// a different value is written
+	assert.NoError(t, newUpdater("30000").update())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// rewrite after the external change is detected
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	store.Invalidate(newUpdater("30000").Path())
+	assert.NoError(t, newUpdater("30000").update())
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// not skipped after a failed write
+	failed, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, "/kubepods.slice/not-exist", "30000", nil)
+	assert.NoError(t, err)
+	store.Set(failed.Path(), "20000")
+	assert.Error(t, failed.(*CgroupResourceUpdater).WithChecksumSkip(store).update())
+	assert.False(t, store.Matches(failed.Path(), "20000"))
+}
+
+func TestCgroupResourceUpdater_WithChecksumSkip_Merge(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-1")
+
+	store := NewChecksumStore()
+	newUpdater := func(value string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithChecksumSkip(store)
+	}
+
+	// the merged union is written at first
+	merged, err := newUpdater("2-3").MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "0-3", merged.Value())
+	assert.Equal(t, "0-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+	assert.False(t, store.Matches(merged.Path(), "2-3"))
+
+	// the tightening write is not skipped
+	assert.NoError(t, newUpdater("2-3").update())
+	assert.Equal(t, "2-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+	assert.True(t, store.Matches(merged.Path(), "2-3"))
+}
+
+func TestCgroupResourceUpdater_WithFreezeConfirm(t *testing.T) {
+	oldInterval := settleCheckInterval
+	settleCheckInterval = time.Millisecond
+	defer func() {
+		settleCheckInterval = oldInterval
+	}()
+
+	tests := []struct {
+		name        string
+		useCgroupV2 bool
+		value       string
+		frozen      string
+		wantErr     bool
+	}{
+		{
+			name:   "confirm the freeze on cgroups-v1",
+			value:  sysutil.FreezerStateFrozen,
+			frozen: "1",
+		},
+		{
+			name:    "timeout on cgroups-v1",
+			value:   sysutil.FreezerStateFrozen,
+			frozen:  "0",
+			wantErr: true,
+		},
+		{
+			name:   "no wait for the thaw",
+			value:  sysutil.FreezerStateThawed,
+			frozen: "0",
+		},
+		{
+			name:        "confirm the freeze on cgroups-v2",
+			useCgroupV2: true,
+			value:       sysutil.CgroupFreezeFrozen,
+			frozen:      "1",
+		},
+		{
+			name:        "timeout on cgroups-v2",
+			useCgroupV2: true,
+			value:       sysutil.CgroupFreezeFrozen,
+			frozen:      "0",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			if tt.useCgroupV2 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.FreezerStateV2, sysutil.CgroupFreezeThawed)
+				helper.WriteCgroupFileContents(parentDir, sysutil.CgroupEventsV2, "populated 1\nfrozen "+tt.frozen)
+			} else {
+				helper.WriteCgroupFileContents(parentDir, sysutil.FreezerState, sysutil.FreezerStateThawed)
+				helper.WriteCgroupFileContents(parentDir, sysutil.FreezerSelfFreezing, tt.frozen)
+			}
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.FreezerStateName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.(*CgroupResourceUpdater).WithFreezeConfirm(20 * time.Millisecond).update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithMaxGuard(t *testing.T) {
+	oldInterval := settleCheckInterval
+	settleCheckInterval = time.Millisecond
+	defer func() {
+		settleCheckInterval = oldInterval
+	}()
+
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimitV2, "2000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryHighV2, "max")
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryUsageV2, "1800")
+
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.MemoryHighName, parentDir, "1000", nil)
+	assert.NoError(t, err)
+	c := u.(*CgroupResourceUpdater).WithMaxGuard(500, 5*time.Second)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.update()
+	}()
+
+	// the max is raised and the high is written while the reclaim is not settled
+	assert.Eventually(t, func() bool {
+		return helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2) == "1000"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "2500", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+	select {
+	case err = <-done:
+		t.Fatalf("update returned before the reclaim settled, err: %v", err)
+	default:
+	}
+
+	// the max is restored after the usage drops below the high
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryUsageV2, "900")
+	assert.NoError(t, <-done)
+	assert.Equal(t, "2000", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+	assert.Equal(t, "1000", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+
+	// the max is restored and the update succeeds even if the reclaim does not settle
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryUsageV2, "1800")
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.MemoryHighName, parentDir, "800", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithMaxGuard(500, 10*time.Millisecond).update())
+	assert.Equal(t, "2000", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+	assert.Equal(t, "800", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+
+	// no guard when the high is unchanged
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.MemoryHighName, parentDir, "800", nil)
+	assert.NoError(t, err)
+	c = u.(*CgroupResourceUpdater)
+	updateFn := c.updateFunc
+	c.updateFunc = func(resource ResourceUpdater) error {
+		assert.Equal(t, "2000", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+		return updateFn(resource)
+	}
+	assert.NoError(t, c.WithMaxGuard(500, 10*time.Millisecond).update())
+	assert.Equal(t, "2000", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+
+	// the max changed by others during the adjustment is kept
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.MemoryHighName, parentDir, "750", nil)
+	assert.NoError(t, err)
+	c = u.(*CgroupResourceUpdater)
+	updateFn = c.updateFunc
+	c.updateFunc = func(resource ResourceUpdater) error {
+		assert.Equal(t, "2500", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+		helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimitV2, "3000")
+		return updateFn(resource)
+	}
+	assert.NoError(t, c.WithMaxGuard(500, 10*time.Millisecond).update())
+	assert.Equal(t, "3000", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+	assert.Equal(t, "750", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimitV2, "2000")
+
+	// no guard for the unlimited max
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimitV2, "max")
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.MemoryHighName, parentDir, "700", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithMaxGuard(500, 10*time.Millisecond).update())
+	assert.Equal(t, "max", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimitV2))
+	assert.Equal(t, "700", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryHighV2))
+}
+
+func TestCgroupResourceUpdater_WithSequence(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+	newUpdater := func(value string, seq uint64) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithSequence(seq)
+	}
+
+	// the newer reconcile applies first
+	assert.NoError(t, newUpdater("30000", 2).update())
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// the older reconcile is rejected
+	gotErr := newUpdater("20000", 1).update()
+	assert.True(t, IsStaleUpdateErr(gotErr), gotErr)
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	_, gotErr = newUpdater("20000", 1).MergeUpdate()
+	assert.True(t, IsStaleUpdateErr(gotErr), gotErr)
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// the same and the higher sequences are applied
+	assert.NoError(t, newUpdater("40000", 2).update())
+	assert.Equal(t, "40000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	_, gotErr = newUpdater("50000", 3).MergeUpdate()
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "50000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// the sequences are dropped with the pod
+	ForgetAppliedSequences("/kubepods.slice/kubepods-pod1.slice")
+	assert.NoError(t, newUpdater("20000", 1).update())
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// the sequence of a removed cgroup is dropped by the next write
+	removedDir := "/kubepods.slice/kubepods-pod2.slice"
+	helper.WriteCgroupFileContents(removedDir, sysutil.CPUCFSQuota, "10000")
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, removedDir, "20000", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithSequence(1).update())
+	removedPath := u.Path()
+	assert.NoError(t, os.RemoveAll(filepath.Dir(removedPath)))
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, removedDir, "30000", nil)
+	assert.NoError(t, err)
+	assert.Error(t, u.(*CgroupResourceUpdater).WithSequence(2).update())
+	appliedSequences.lock.Lock()
+	_, ok := appliedSequences.sequences[removedPath]
+	appliedSequences.lock.Unlock()
+	assert.False(t, ok)
+	ForgetAppliedSequences("/kubepods.slice")
+}
+
+func TestCgroupResourceUpdater_WithFeatureGate(t *testing.T) {
+	tests := []struct {
+		name  string
+		gates map[string]bool
+		want  string
+	}{
+		{
+			name:  "write with the gate enabled",
+			gates: map[string]bool{"CPUBurst": true},
+			want:  "20000",
+		},
+		{
+			name:  "skip with the gate disabled",
+			gates: map[string]bool{"CPUBurst": false},
+			want:  "10000",
+		},
+		{
+			name:  "skip with the gate unknown",
+			gates: map[string]bool{},
+			want:  "10000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+			gateFn := func(name string) bool {
+				return tt.gates[name]
+			}
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			u = u.(*CgroupResourceUpdater).WithFeatureGate("CPUBurst", gateFn)
+			assert.NoError(t, u.update())
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+			_, err = u.MergeUpdate()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithStackTraceOnWrite(t *testing.T) {
+	fs := setupKlogCapture(t)
+
+	tests := []struct {
+		name      string
+		verbosity string
+		wantStack bool
+	}{
+		{
+			name:      "log the stack at the configured level",
+			verbosity: "6",
+			wantStack: true,
+		},
+		{
+			name:      "omit the stack below the level",
+			verbosity: "5",
+			wantStack: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			var buf bytes.Buffer
+			klog.SetOutput(&buf)
+			assert.NoError(t, fs.Set("v", tt.verbosity))
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+			assert.NoError(t, err)
+			assert.NoError(t, u.(*CgroupResourceUpdater).WithStackTraceOnWrite(6).update())
+			klog.Flush()
+			assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+			assert.Equal(t, tt.wantStack, strings.Contains(buf.String(), "called by: "), buf.String())
+			if tt.wantStack {
+				assert.Contains(t, buf.String(), "TestCgroupResourceUpdater_WithStackTraceOnWrite")
+			}
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithDriftDetection(t *testing.T) {
+	setupKlogCapture(t)
+
+	tests := []struct {
+		name        string
+		current     string
+		cache       map[string]string
+		value       string
+		want        string
+		wantDrift   bool
+		wantApplied string
+	}{
+		{
+			name:        "write without the last-applied value",
+			current:     "10000",
+			cache:       map[string]string{},
+			value:       "20000",
+			want:        "20000",
+			wantApplied: "20000",
+		},
+		{
+			name:        "write the desired value changed by ourselves",
+			current:     "10000",
+			cache:       map[string]string{"quota": "10000"},
+			value:       "20000",
+			want:        "20000",
+			wantApplied: "20000",
+		},
+		{
+			name:        "detect the external drift and write",
+			current:     "30000",
+			cache:       map[string]string{"quota": "10000"},
+			value:       "10000",
+			want:        "10000",
+			wantDrift:   true,
+			wantApplied: "10000",
+		},
+		{
+			name:        "detect the external drift and skip the desired value",
+			current:     "20000",
+			cache:       map[string]string{"quota": "10000"},
+			value:       "20000",
+			want:        "20000",
+			wantDrift:   true,
+			wantApplied: "10000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, tt.current)
+
+			var buf bytes.Buffer
+			klog.SetOutput(&buf)
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			// the cache is only refreshed by the write not skipped
+			c := u.(*CgroupResourceUpdater).WithApplyCache(tt.cache, "quota").WithDriftDetection(tt.cache, "quota")
+			assert.NoError(t, c.update())
+			klog.Flush()
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+			assert.Equal(t, tt.wantDrift, strings.Contains(buf.String(), "external drift detected"), buf.String())
+			assert.Equal(t, tt.wantApplied, tt.cache["quota"])
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithDriftDetection_CgroupsV2(t *testing.T) {
+	setupKlogCapture(t)
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "39")
+	var buf bytes.Buffer
+	klog.SetOutput(&buf)
+
+	// the `cpu.weight` converted from the desired and the last-applied `cpu.shares` is neither drifted nor rewritten
+	writes := 0
+	cache := map[string]string{"shares": "1024"}
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	c := u.(*CgroupResourceUpdater).WithUpdateFunc(func(resource ResourceUpdater) error {
+		writes++
+		return CgroupUpdateCPUSharesFunc(resource)
+	}).WithDriftDetection(cache, "shares")
+	assert.NoError(t, c.update())
+	klog.Flush()
+	assert.Equal(t, 0, writes)
+	assert.NotContains(t, buf.String(), "external drift detected")
+	assert.Equal(t, "39", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+}
+
+func TestCgroupResourceUpdater_WithOwner(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	ResourceUpdateTotal.Reset()
+	defer ResourceUpdateTotal.Reset()
+
+	resourceType := string(sysutil.CPUSharesName)
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "2048", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithOwner("cpuburst").update())
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "4096", nil)
+	assert.NoError(t, err)
+	owned := u.(*CgroupResourceUpdater).WithOwner("cpuqos")
+	assert.NoError(t, owned.update())
+	// the update of the unchanged value is skipped
+	assert.NoError(t, owned.Clone().update())
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(ResourceUpdateTotal.WithLabelValues(resourceType, "cpuburst", statusSucceed)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ResourceUpdateTotal.WithLabelValues(resourceType, "cpuqos", statusSucceed)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ResourceUpdateTotal.WithLabelValues(resourceType, "cpuqos", statusSkipped)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ResourceUpdateTotal.WithLabelValues(resourceType, OwnerUnknown, statusSucceed)))
+	assert.Equal(t, map[string]string{ownerKey: "cpuqos"}, owned.auditTags)
+}
+
+func TestCgroupResourceUpdater_WithTaskCgroupCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		useCgroupV2 bool
+		procCgroup  string
+		value       string
+		wantErr     bool
+		wantValue   string
+	}{
+		{
+			name:       "move the pid in the expected cgroup",
+			procCgroup: "5:memory:/kubepods.slice/kubepods-pod2.slice\n4:cpu,cpuacct:/kubepods.slice/kubepods-pod1.slice/cri-containerd-abc.scope\n",
+			value:      "100",
+			wantValue:  "100",
+		},
+		{
+			name:       "skip the pid in an unexpected cgroup",
+			procCgroup: "5:memory:/kubepods.slice/kubepods-pod1.slice\n4:cpu,cpuacct:/kubepods.slice/kubepods-pod2.slice\n",
+			value:      "100",
+			wantValue:  "",
+		},
+		{
+			name:       "skip the pid in a cgroup with the similar prefix",
+			procCgroup: "4:cpu,cpuacct:/kubepods.slice/kubepods-pod1.slice-other\n",
+			value:      "100",
+			wantValue:  "",
+		},
+		{
+			name:      "skip the pid exited",
+			value:     "101",
+			wantValue: "",
+		},
+		{
+			name:       "invalid pid",
+			procCgroup: "4:cpu,cpuacct:/kubepods.slice/kubepods-pod1.slice\n",
+			value:      "abc",
+			wantErr:    true,
+			wantValue:  "",
+		},
+		{
+			name:        "move the pid in the expected cgroup on cgroups-v2",
+			useCgroupV2: true,
+			procCgroup:  "0::/kubepods.slice/kubepods-pod1.slice\n",
+			value:       "100",
+			wantValue:   "100",
+		},
+		{
+			name:        "skip the pid in an unexpected cgroup on cgroups-v2",
+			useCgroupV2: true,
+			procCgroup:  "0::/kubepods.slice/kubepods-pod2.slice\n",
+			value:       "100",
+			wantValue:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			procs := sysutil.CPUProcs
+			if tt.useCgroupV2 {
+				procs = sysutil.CPUProcsV2
+			}
+			helper.WriteCgroupFileContents(parentDir, procs, "")
+			if tt.procCgroup != "" {
+				helper.WriteProcSubFileContents("100/cgroup", tt.procCgroup)
+			}
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUProcsName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.(*CgroupResourceUpdater).WithTaskCgroupCheck(parentDir).update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, procs))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithSchedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		windows   []TimeWindow
+		wantErr   bool
+		wantValue string
+	}{
+		{
+			name:      "write inside the window",
+			now:       time.Date(2022, 10, 1, 3, 30, 0, 0, time.Local),
+			windows:   []TimeWindow{{Start: 3 * time.Hour, End: 4 * time.Hour}},
+			wantValue: "1024",
+		},
+		{
+			name:      "defer outside the window",
+			now:       time.Date(2022, 10, 1, 12, 0, 0, 0, time.Local),
+			windows:   []TimeWindow{{Start: 3 * time.Hour, End: 4 * time.Hour}},
+			wantErr:   true,
+			wantValue: "2048",
+		},
+		{
+			name:      "write inside the window spanning the midnight",
+			now:       time.Date(2022, 10, 1, 1, 0, 0, 0, time.Local),
+			windows:   []TimeWindow{{Start: 22 * time.Hour, End: 2 * time.Hour}},
+			wantValue: "1024",
+		},
+		{
+			name:      "defer at the end of the window",
+			now:       time.Date(2022, 10, 1, 2, 0, 0, 0, time.Local),
+			windows:   []TimeWindow{{Start: 22 * time.Hour, End: 2 * time.Hour}},
+			wantErr:   true,
+			wantValue: "2048",
+		},
+		{
+			name:      "defer without windows",
+			now:       time.Date(2022, 10, 1, 3, 30, 0, 0, time.Local),
+			wantErr:   true,
+			wantValue: "2048",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			oldClock := updaterClock
+			updaterClock = clock.NewFakePassiveClock(tt.now)
+			defer func() {
+				updaterClock = oldClock
+			}()
+
+			parentDir := "/kubepods.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "2048")
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+			assert.NoError(t, err)
+			gotErr := u.(*CgroupResourceUpdater).WithSchedule(tt.windows).update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.wantErr {
+				assert.True(t, IsOutsideScheduleErr(gotErr))
+			}
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_WithOnFirstWrite(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimit, "9223372036854771712")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUBurst, "0")
+
+	var fired []string
+	onFirstWrite := func(u ResourceUpdater) {
+		fired = append(fired, u.Path()+"="+u.Value())
+	}
+	update := func(resourceType sysutil.ResourceType, value string) {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, u.(*CgroupResourceUpdater).WithOnFirstWrite(onFirstWrite).update())
+	}
+	memoryLimitPath := sysutil.GetCgroupFilePath(parentDir, sysutil.MemoryLimit)
+	cpuBurstPath := sysutil.GetCgroupFilePath(parentDir, sysutil.CPUBurst)
+
+	// the initial managed write from the unlimited default
+	update(sysutil.MemoryLimitName, "1048576")
+	assert.Equal(t, []string{memoryLimitPath + "=1048576"}, fired)
+	// the subsequent writes
+	update(sysutil.MemoryLimitName, "2097152")
+	update(sysutil.MemoryLimitName, "2097152")
+	assert.Equal(t, []string{memoryLimitPath + "=1048576"}, fired)
+	// writing the default is not managed
+	update(sysutil.CPUBurstName, "0")
+	assert.Equal(t, []string{memoryLimitPath + "=1048576"}, fired)
+	update(sysutil.CPUBurstName, "1000")
+	assert.Equal(t, []string{memoryLimitPath + "=1048576", cpuBurstPath + "=1000"}, fired)
+}
+
+func TestCgroupResourceUpdater_WithEventRecorder(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	now := time.Now()
+	fakeClock := clock.NewFakePassiveClock(now)
+	oldClock := updaterClock
+	updaterClock = fakeClock
+	defer func() {
+		updaterClock = oldClock
+	}()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	recorder := record.NewFakeRecorder(10)
+	update := func(parentDir, value string) error {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithEventRecorder(recorder, pod).update()
+	}
+
+	// successful writes emit no event
+	assert.NoError(t, update(parentDir, "2048"))
+	assert.Len(t, recorder.Events, 0)
+	// a failed write emits a warning event
+	assert.Error(t, update(parentDir, "1"))
+	assert.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.True(t, strings.HasPrefix(event, corev1.EventTypeWarning+" "+EventReasonUpdateResourceFailed+" "), event)
+	// the repeated failures are rate-limited
+	assert.Error(t, update(parentDir, "1"))
+	assert.Error(t, update(parentDir, "1"))
+	assert.Len(t, recorder.Events, 0)
+	// the failures of another path are not limited
+	assert.Error(t, update("/kubepods.slice/kubepods-pod2.slice", "1"))
+	assert.Len(t, recorder.Events, 1)
+	<-recorder.Events
+	// emit again after the interval
+	fakeClock.SetTime(now.Add(failureEventInterval))
+	assert.Error(t, update(parentDir, "1"))
+	assert.Len(t, recorder.Events, 1)
+	// the expired records are swept
+	expiredPath := sysutil.GetCgroupFilePath("/kubepods.slice/kubepods-pod2.slice", sysutil.CPUShares)
+	lastFailureEvents.lock.Lock()
+	_, ok := lastFailureEvents.times[expiredPath]
+	lastFailureEvents.lock.Unlock()
+	assert.False(t, ok)
+}
+
+func TestCgroupResourceUpdater_WithLastWriteTimestamp(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	now := time.Unix(1664582400, 0)
+	fakeClock := clock.NewFakePassiveClock(now)
+	oldClock := updaterClock
+	updaterClock = fakeClock
+	defer func() {
+		updaterClock = oldClock
+	}()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-1")
+	// the cgroup file of the broken dir is a directory, so the write always fails
+	brokenDir := "/kubepods.slice/kubepods-pod2.slice"
+	helper.MkDirAll(filepath.Join(sysutil.GetCgroupFilePath(brokenDir, sysutil.CPUCFSQuota), "broken"))
+	var updateDir func(resourceType sysutil.ResourceType, dir, value string, merge bool) error
+	update := func(resourceType sysutil.ResourceType, value string, merge bool) error {
+		return updateDir(resourceType, parentDir, value, merge)
+	}
+	updateDir = func(resourceType sysutil.ResourceType, dir, value string, merge bool) error {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, dir, value, nil)
+		assert.NoError(t, err)
+		c := u.(*CgroupResourceUpdater).WithLastWriteTimestamp()
+		if merge {
+			_, err = c.MergeUpdate()
+			return err
+		}
+		return c.update()
+	}
+	gotTimestamp := func(resourceType sysutil.ResourceType) float64 {
+		return testutil.ToFloat64(ResourceLastWriteTimestampSeconds.WithLabelValues(string(resourceType)))
+	}
+
+	// a successful write
+	assert.NoError(t, update(sysutil.CPUCFSQuotaName, "20000", false))
+	assert.Equal(t, float64(now.Unix()), gotTimestamp(sysutil.CPUCFSQuotaName))
+	// the skipped write
+	fakeClock.SetTime(now.Add(time.Minute))
+	assert.NoError(t, update(sysutil.CPUCFSQuotaName, "20000", false))
+	assert.Equal(t, float64(now.Unix()), gotTimestamp(sysutil.CPUCFSQuotaName))
+	// the failed write
+	assert.Error(t, updateDir(sysutil.CPUCFSQuotaName, brokenDir, "30000", false))
+	assert.Equal(t, float64(now.Unix()), gotTimestamp(sysutil.CPUCFSQuotaName))
+	// advance on the next successful write
+	assert.NoError(t, update(sysutil.CPUCFSQuotaName, "30000", false))
+	assert.Equal(t, float64(now.Add(time.Minute).Unix()), gotTimestamp(sysutil.CPUCFSQuotaName))
+
+	// the merge update
+	fakeClock.SetTime(now.Add(2 * time.Minute))
+	assert.NoError(t, update(sysutil.CPUSetCPUSName, "0-3", true))
+	assert.Equal(t, float64(now.Add(2*time.Minute).Unix()), gotTimestamp(sysutil.CPUSetCPUSName))
+	// the merge skipped since the cpuset is not looser
+	fakeClock.SetTime(now.Add(3 * time.Minute))
+	assert.NoError(t, update(sysutil.CPUSetCPUSName, "0", true))
+	assert.Equal(t, float64(now.Add(2*time.Minute).Unix()), gotTimestamp(sysutil.CPUSetCPUSName))
+}
+
+func TestCgroupResourceUpdater_WithRoot(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	root := t.TempDir()
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	writeRooted := func(r sysutil.Resource, value string) {
+		p := filepath.Join(root, r.Path(parentDir))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(value), 0644))
+	}
+	readRooted := func(r sysutil.Resource) string {
+		content, err := os.ReadFile(filepath.Join(root, r.Path(parentDir)))
+		assert.NoError(t, err)
+		return string(content)
+	}
+	newUpdater := func(resourceType sysutil.ResourceType, value string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithRoot(root)
+	}
+	// the host files are not touched
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "-1")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-7")
+	writeRooted(sysutil.CPUCFSQuota, "10000")
+	writeRooted(sysutil.CPUSet, "0-1")
+
+	// write and read through the root
+	u := newUpdater(sysutil.CPUCFSQuotaName, "20000")
+	assert.Equal(t, filepath.Join(root, sysutil.CPUCFSQuota.Path(parentDir)), u.Path())
+	assert.NoError(t, u.update())
+	assert.Equal(t, "20000", readRooted(sysutil.CPUCFSQuota))
+	got, err := u.ReadCurrent()
+	assert.NoError(t, err)
+	assert.Equal(t, "20000", got)
+	assert.Equal(t, "-1", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// merge with the value read from the root
+	_, err = newUpdater(sysutil.CPUSetCPUSName, "2-3").MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "0-3", readRooted(sysutil.CPUSet))
+	assert.Equal(t, "0-7", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+
+	// the cgroup file not exist under the root
+	assert.Error(t, newUpdater(sysutil.CPUSharesName, "1024").update())
+}
+
+func TestCgroupResourceUpdater_WithDisableMarker(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-1")
+	marker := filepath.Join(t.TempDir(), "disable-cpu")
+	newUpdater := func(resourceType sysutil.ResourceType, value string) *CgroupResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.(*CgroupResourceUpdater).WithDisableMarker(marker)
+	}
+
+	// the marker suppresses the writes
+	assert.NoError(t, os.WriteFile(marker, nil, 0644))
+	gotErr := newUpdater(sysutil.CPUCFSQuotaName, "20000").update()
+	assert.True(t, IsUpdateDisabledErr(gotErr), gotErr)
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	_, gotErr = newUpdater(sysutil.CPUSetCPUSName, "0-3").MergeUpdate()
+	assert.True(t, IsUpdateDisabledErr(gotErr), gotErr)
+	assert.Equal(t, "0-1", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+
+	// the suppressed writes are not cached by the executor
+	e := NewTestResourceExecutor().(*ResourceUpdateExecutorImpl)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	e.Run(stopCh)
+	quota := newUpdater(sysutil.CPUCFSQuotaName, "20000")
+	updated, err := e.Update(true, quota)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	assert.True(t, e.needUpdate(quota))
+	cpuset := newUpdater(sysutil.CPUSetCPUSName, "0-3")
+	e.LeveledUpdateBatch([][]ResourceUpdater{{cpuset}})
+	assert.True(t, e.needUpdate(cpuset))
+
+	// removing the marker resumes the writes
+	assert.NoError(t, os.Remove(marker))
+	updated, err = e.Update(true, quota)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	e.LeveledUpdateBatch([][]ResourceUpdater{{cpuset}})
+	assert.Equal(t, "0-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+	assert.False(t, e.needUpdate(cpuset))
+}
+
+func TestCgroupResourceUpdater_WithDynamicBounds(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	var gotRequests []string
+	source := BoundsSourceFunc(func(resourceType sysutil.ResourceType, dir string) (int64, int64, error) {
+		gotRequests = append(gotRequests, string(resourceType)+" "+dir)
+		return 1000, 1000000, nil
+	})
+
+	tests := []struct {
+		name      string
+		value     string
+		clamp     bool
+		wantErr   bool
+		wantValue string
+	}{
+		{name: "in range", value: "200000", clamp: true, wantValue: "200000"},
+		{name: "clamp to max", value: "2000000", clamp: true, wantValue: "1000000"},
+		{name: "clamp to min", value: "100", clamp: true, wantValue: "1000"},
+		{name: "reject out of range", value: "2000000", clamp: false, wantErr: true, wantValue: "100000"},
+		{name: "reject not integer", value: "abc", clamp: true, wantErr: true, wantValue: "100000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSPeriod, "100000")
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSPeriodName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			err = u.(*CgroupResourceUpdater).WithDynamicBounds(source, tt.clamp).update()
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantErr && !tt.clamp, IsValueOutOfBoundsErr(err))
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSPeriod))
+			assert.Equal(t, tt.value, u.Value())
+		})
+	}
+	assert.Contains(t, gotRequests, sysutil.CPUCFSPeriodName+" "+parentDir)
+
+	// the requested value is written once the bounds cover it
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSPeriod, "100000")
+	maxBound := int64(1000000)
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSPeriodName, parentDir, "2000000", nil)
+	assert.NoError(t, err)
+	u = u.(*CgroupResourceUpdater).WithDynamicBounds(BoundsSourceFunc(func(sysutil.ResourceType, string) (int64, int64, error) {
+		return 1000, maxBound, nil
+	}), true)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "1000000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSPeriod))
+	maxBound = 4000000
+	assert.NoError(t, u.update())
+	assert.Equal(t, "2000000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSPeriod))
+
+	// the source fails
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSPeriod, "100000")
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSPeriodName, parentDir, "200000", nil)
+	assert.NoError(t, err)
+	err = u.(*CgroupResourceUpdater).WithDynamicBounds(BoundsSourceFunc(func(sysutil.ResourceType, string) (int64, int64, error) {
+		return 0, 0, fmt.Errorf("bounds file not exist")
+	}), true).update()
+	assert.Error(t, err)
+	assert.False(t, IsValueOutOfBoundsErr(err))
+	assert.Equal(t, "100000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSPeriod))
+}
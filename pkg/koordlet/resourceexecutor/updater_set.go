@@ -0,0 +1,232 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"sort"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// UpdaterSet is a set of updaters applied together, e.g. all resources of a pod in a reconcile.
+type UpdaterSet struct {
+	updaters []ResourceUpdater
+}
+
+func NewUpdaterSet(updaters ...ResourceUpdater) *UpdaterSet {
+	return &UpdaterSet{updaters: updaters}
+}
+
+// Add appends the updaters into the set.
+func (s *UpdaterSet) Add(updaters ...ResourceUpdater) {
+	s.updaters = append(s.updaters, updaters...)
+}
+
+// Updaters returns the updaters of the set in order.
+func (s *UpdaterSet) Updaters() []ResourceUpdater {
+	return s.updaters
+}
+
+// SetUpdateResult is the outcome of an updater in the SetUpdateReport.
+type SetUpdateResult struct {
+	ResourceType sysutil.ResourceType
+	Path         string
+	Value        string
+	// Err is the error of the failed update.
+	Err error
+}
+
+// SetUpdateReport is the structured report of an UpdaterSet.UpdateAll, which tells what changed, what was skipped
+// since the current value is already expected, and what failed.
+type SetUpdateReport struct {
+	Changed []SetUpdateResult
+	Skipped []SetUpdateResult
+	Failed  []SetUpdateResult
+}
+
+// Err returns the aggregated error of the failed updates, or nil if none failed.
+func (r *SetUpdateReport) Err() error {
+	var errs []error
+	for _, result := range r.Failed {
+		errs = append(errs, fmt.Errorf("failed to update %s to %v, err: %w", result.Path, result.Value, result.Err))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+type changeChecker interface {
+	WouldChange() (bool, error)
+}
+
+// wouldUpdateChange checks whether the update of the updater would change the current value. The merge condition is
+// not checked since the UpdateAll writes by the update instead of the MergeUpdate, e.g. a shrinking `cpuset.cpus` is
+// still written.
+func wouldUpdateChange(u ResourceUpdater) (bool, bool, error) {
+	if c, ok := u.(*CgroupResourceUpdater); ok {
+		changed, err := c.wouldChange(false)
+		return changed, true, err
+	}
+	if checker, ok := u.(changeChecker); ok {
+		changed, err := checker.WouldChange()
+		return changed, true, err
+	}
+	return false, false, nil
+}
+
+// UpdateAll updates all updaters of the set in order and reports the outcome of each. An updater is skipped if it
+// would not change the current value, and a failed updater does not stop the others.
+func (s *UpdaterSet) UpdateAll() *SetUpdateReport {
+	report := &SetUpdateReport{}
+	for _, u := range s.updaters {
+		result := SetUpdateResult{
+			ResourceType: u.ResourceType(),
+			Path:         u.Path(),
+			Value:        u.Value(),
+		}
+		if changed, checked, err := wouldUpdateChange(u); checked {
+			if err == nil && !changed {
+				klog.V(6).Infof("skip updating %s to %v, the value is unchanged", u.Path(), u.Value())
+				report.Skipped = append(report.Skipped, result)
+				continue
+			}
+		}
+		if err := u.update(); err != nil {
+			result.Err = err
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+		report.Changed = append(report.Changed, result)
+	}
+	return report
+}
+
+// SwapUpdaterSets flips the resources from the applied set to the target set, e.g. switching a pod between two policy
+// configurations. Only the updaters of the target whose values differ from the applied ones of the same keys are
+// written, and the others are reported as skipped. The swap is applied as a transaction: the current values are read
+// before writing, and if any write fails, the written resources are restored in the reverse order. The Changed of the
+// report records what the swap changed, so swapping back with SwapUpdaterSets(to, from) reverses it.
+func SwapUpdaterSets(from, to *UpdaterSet) (*SetUpdateReport, error) {
+	applied := map[string]string{}
+	for _, u := range from.Updaters() {
+		applied[u.Key()] = u.Value()
+	}
+
+	report := &SetUpdateReport{}
+	var written []ResourceUpdater // the updaters restoring the previous values of the written resources
+	for _, u := range to.Updaters() {
+		result := SetUpdateResult{
+			ResourceType: u.ResourceType(),
+			Path:         u.Path(),
+			Value:        u.Value(),
+		}
+		if v, ok := applied[u.Key()]; ok && v == u.Value() {
+			report.Skipped = append(report.Skipped, result)
+			continue
+		}
+
+		previous, err := snapshotUpdater(u)
+		if err == nil {
+			err = u.update()
+		}
+		if err != nil {
+			result.Err = err
+			report.Failed = append(report.Failed, result)
+			rollbackErr := rollbackUpdaters(written)
+			return report, utilerrors.NewAggregate([]error{report.Err(), rollbackErr})
+		}
+		report.Changed = append(report.Changed, result)
+		written = append(written, previous)
+	}
+	return report, nil
+}
+
+// rollbackUpdaters restores the previous values by the updaters in the reverse order.
+func rollbackUpdaters(previous []ResourceUpdater) error {
+	var errs []error
+	for i := len(previous) - 1; i >= 0; i-- {
+		u := previous[i]
+		if err := u.update(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back %s to %v, err: %w", u.Path(), u.Value(), err))
+			continue
+		}
+		klog.V(5).Infof("rolled back %s to %v", u.Path(), u.Value())
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// snapshotUpdater reads the current value of the resource, and returns an updater restoring the value. The cgroup value
+// read is already in the form of the file, e.g. the `cpu.weight` of the `cpu.shares` on the cgroups-v2, so it is
+// restored by a raw write instead of the updateFunc converting the value again. The restoring write is exempted from
+// the write budget, since a throttled rollback leaves the resources half-applied.
+func snapshotUpdater(u ResourceUpdater) (ResourceUpdater, error) {
+	switch c := u.(type) {
+	case *CgroupResourceUpdater:
+		v, err := c.ReadCurrent()
+		if err != nil {
+			return nil, err
+		}
+		snapshot := cloneCgroupUpdaterWithValue(c, v, c.eventHelper)
+		snapshot.updateFunc = CommonCgroupUpdateFunc
+		snapshot.mergeUpdateFunc = nil
+		snapshot.writeBudgetExempt = true
+		return snapshot, nil
+	case *DefaultResourceUpdater:
+		v, err := sysutil.CommonFileRead(c.Path())
+		if err != nil {
+			return nil, err
+		}
+		d := c.Clone().(*DefaultResourceUpdater)
+		d.value = v
+		d.writeBudgetExempt = true
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported updater type %T to snapshot", u)
+}
+
+// multiPathUpdater is the updater which writes multiple paths, e.g. the composite updaters.
+type multiPathUpdater interface {
+	// Paths returns all paths the updater writes.
+	Paths() []string
+}
+
+// PlannedPaths returns the deduplicated and sorted paths which the updaters will write, e.g. for an operator tool to
+// check the permissions before applying. The composite updaters are expanded to all underlying paths.
+func PlannedPaths(updaters []ResourceUpdater) []string {
+	pathSet := map[string]struct{}{}
+	for _, u := range updaters {
+		for _, p := range updaterPaths(u) {
+			pathSet[p] = struct{}{}
+		}
+	}
+	paths := make([]string, 0, len(pathSet))
+	for p := range pathSet {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// updaterPaths returns all paths the updater writes, where the composite updaters are expanded.
+func updaterPaths(u ResourceUpdater) []string {
+	if m, ok := u.(multiPathUpdater); ok {
+		return m.Paths()
+	}
+	return []string{u.Path()}
+}
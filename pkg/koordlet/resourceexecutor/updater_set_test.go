@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestUpdaterSet_UpdateAll(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+
+	changed, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	skipped, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	failed, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, "/kubepods.slice/kubepods-pod2.slice", "2048", nil)
+	assert.NoError(t, err)
+
+	s := NewUpdaterSet(changed, skipped)
+	s.Add(failed)
+	report := s.UpdateAll()
+
+	assert.Equal(t, []SetUpdateResult{
+		{ResourceType: sysutil.CPUCFSQuotaName, Path: changed.Path(), Value: "20000"},
+	}, report.Changed)
+	assert.Equal(t, []SetUpdateResult{
+		{ResourceType: sysutil.CPUSharesName, Path: skipped.Path(), Value: "1024"},
+	}, report.Skipped)
+	assert.Equal(t, 1, len(report.Failed))
+	assert.Equal(t, sysutil.ResourceType(sysutil.CPUSharesName), report.Failed[0].ResourceType)
+	assert.Equal(t, failed.Path(), report.Failed[0].Path)
+	assert.Error(t, report.Failed[0].Err)
+	assert.Error(t, report.Err())
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// the mergeable updater tightening the current value is written
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-3")
+	tightened, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "0-1", nil)
+	assert.NoError(t, err)
+	report = NewUpdaterSet(tightened).UpdateAll()
+	assert.Equal(t, []SetUpdateResult{
+		{ResourceType: sysutil.CPUSetCPUSName, Path: tightened.Path(), Value: "0-1"},
+	}, report.Changed)
+	assert.Len(t, report.Skipped, 0)
+	assert.Equal(t, "0-1", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+}
+
+func TestSwapUpdaterSets(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimit, "1048576")
+
+	newSet := func(quota, shares, limit string) *UpdaterSet {
+		s := NewUpdaterSet()
+		for _, r := range []struct {
+			resourceType sysutil.ResourceType
+			value        string
+		}{
+			{resourceType: sysutil.CPUCFSQuotaName, value: quota},
+			{resourceType: sysutil.CPUSharesName, value: shares},
+			{resourceType: sysutil.MemoryLimitName, value: limit},
+		} {
+			u, err := DefaultCgroupUpdaterFactory.New(r.resourceType, parentDir, r.value, nil)
+			assert.NoError(t, err)
+			s.Add(u)
+		}
+		return s
+	}
+	setA := newSet("10000", "1024", "1048576")
+	setB := newSet("20000", "1024", "2097152")
+
+	// flip from A to B
+	report, err := SwapUpdaterSets(setA, setB)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(report.Changed))
+	assert.Equal(t, 1, len(report.Skipped))
+	assert.Equal(t, sysutil.ResourceType(sysutil.CPUSharesName), report.Skipped[0].ResourceType)
+	assert.Equal(t, "20000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "2097152", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimit))
+
+	// reverse from B to A
+	report, err = SwapUpdaterSets(setB, setA)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(report.Changed))
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "1024", helper.ReadCgroupFileContents(parentDir, sysutil.CPUShares))
+	assert.Equal(t, "1048576", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryLimit))
+
+	// roll back the written on failure
+	setC := newSet("30000", "1024", "1048576")
+	failed, err := DefaultCgroupUpdaterFactory.New(sysutil.MemoryLimitName, "/kubepods.slice/not-exist", "2097152", nil)
+	assert.NoError(t, err)
+	setC.Add(failed)
+	report, err = SwapUpdaterSets(setA, setC)
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(report.Changed))
+	assert.Equal(t, 1, len(report.Failed))
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, failed.Path(), report.Failed[0].Path)
+}
+
+func TestSwapUpdaterSets_RollbackCgroupsV2(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "100")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2, "max 100000")
+
+	shares, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "2048", nil)
+	assert.NoError(t, err)
+	quota, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	failed, err := DefaultCgroupUpdaterFactory.New(sysutil.MemoryLimitName, "/kubepods.slice/not-exist", "2097152", nil)
+	assert.NoError(t, err)
+
+	// the values read are restored without the conversion of the updateFuncs
+	report, err := SwapUpdaterSets(NewUpdaterSet(), NewUpdaterSet(shares, quota, failed))
+	assert.Error(t, err)
+	assert.Equal(t, 2, len(report.Changed))
+	assert.Equal(t, "100", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+	assert.Equal(t, "max 100000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2))
+}
+
+func TestPlannedPaths(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	newUpdater := func(resourceType sysutil.ResourceType, value string) ResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u
+	}
+	wmark, err := NewMemoryWmarkUpdater(parentDir, "95", "20", "", nil)
+	assert.NoError(t, err)
+	dual, err := NewDualVersionUpdater(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	defaultUpdater, err := NewCommonDefaultUpdater("kidled", "/sys/kernel/mm/kidled/scan_period_in_seconds", "120", nil)
+	assert.NoError(t, err)
+
+	got := PlannedPaths([]ResourceUpdater{
+		newUpdater(sysutil.CPUCFSQuotaName, "10000"),
+		wmark,
+		dual,
+		NewTwoPhaseUpdater(newUpdater(sysutil.CPUCFSPeriodName, "100000"), newUpdater(sysutil.CPUCFSQuotaName, "20000")),
+		NewReadOnlyUpdater(newUpdater(sysutil.MemoryLimitName, "1048576")),
+		defaultUpdater,
+	})
+	assert.Equal(t, []string{
+		"/sys/kernel/mm/kidled/scan_period_in_seconds",
+		sysutil.CPUCFSPeriod.Path(parentDir),
+		sysutil.CPUCFSQuota.Path(parentDir),
+		sysutil.CPUShares.Path(parentDir),
+		sysutil.CPUSharesV2.Path(parentDir),
+		sysutil.MemoryWmarkRatio.Path(parentDir),
+		sysutil.MemoryWmarkScaleFactor.Path(parentDir),
+	}, got)
+	assert.Empty(t, PlannedPaths(nil))
+}
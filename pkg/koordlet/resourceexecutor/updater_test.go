@@ -17,12 +17,23 @@ limitations under the License.
 package resourceexecutor
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 
 	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+	"github.com/koordinator-sh/koordinator/pkg/util/cpuset"
 )
 
 func TestNewCommonCgroupUpdater(t *testing.T) {
@@ -101,6 +112,75 @@ func TestNewCommonCgroupUpdater(t *testing.T) {
 	}
 }
 
+func TestNewCommonCgroupUpdater_Unsupported(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	// the blkio.throttle.* is not registered in cgroups-v2
+	_, err := NewCommonCgroupUpdater(sysutil.BlkioTRIopsName, "/kubepods.slice", "8:0 100", nil)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, sysutil.ErrUnsupportedResource))
+	var unsupportedErr *sysutil.UnsupportedResourceError
+	assert.True(t, errors.As(err, &unsupportedErr))
+	assert.Equal(t, sysutil.ResourceType(sysutil.BlkioTRIopsName), unsupportedErr.ResourceType)
+	assert.Equal(t, sysutil.CgroupVersionV2, unsupportedErr.CgroupVersion)
+
+	// the file missing on the kernel
+	u, err := NewCommonCgroupUpdater(sysutil.CPUIdleName, "/kubepods.slice", "1", nil)
+	assert.NoError(t, err)
+	err = u.update()
+	assert.True(t, errors.Is(err, sysutil.ErrUnsupportedResource), err)
+	assert.True(t, errors.As(err, &unsupportedErr))
+	assert.Equal(t, sysutil.ResourceType(sysutil.CPUIdleName), unsupportedErr.ResourceType)
+}
+
+func TestNewCommonCgroupUpdaterForVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      sysutil.CgroupVersion
+		resourceType sysutil.ResourceType
+		wantFile     sysutil.Resource
+		wantErr      bool
+	}{
+		{
+			name:         "construct a v2 updater on the v1 host",
+			version:      sysutil.CgroupVersionV2,
+			resourceType: sysutil.MemoryLimitName,
+			wantFile:     sysutil.MemoryLimitV2,
+		},
+		{
+			name:         "construct a v1 updater on the v1 host",
+			version:      sysutil.CgroupVersionV1,
+			resourceType: sysutil.MemoryLimitName,
+			wantFile:     sysutil.MemoryLimit,
+		},
+		{
+			name:         "resource not registered in the version",
+			version:      sysutil.CgroupVersionV2,
+			resourceType: sysutil.BlkioWeightDeviceName,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(false)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			got, gotErr := NewCommonCgroupUpdaterForVersion(tt.version, tt.resourceType, parentDir, "1048576")
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.resourceType, got.ResourceType())
+			assert.Equal(t, tt.wantFile.Path(parentDir), got.Path())
+			assert.Equal(t, tt.version == sysutil.CgroupVersionV2, sysutil.IsCgroupV2Resource(got.(*CgroupResourceUpdater).file))
+		})
+	}
+}
+
 func TestCgroupResourceUpdater_Update(t *testing.T) {
 	type fields struct {
 		UseCgroupsV2 bool
@@ -254,3 +334,1847 @@ func TestDefaultResourceUpdater_Update(t *testing.T) {
 		})
 	}
 }
+
+func TestCgroupResourceUpdater_WouldChange(t *testing.T) {
+	type args struct {
+		resourceType sysutil.ResourceType
+		value        string
+	}
+	tests := []struct {
+		name         string
+		initialValue string
+		args         args
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name:         "value equals to the current",
+			initialValue: "10000",
+			args: args{
+				resourceType: sysutil.MemoryLimitName,
+				value:        "10000",
+			},
+			want: false,
+		},
+		{
+			name:         "value differs from the current",
+			initialValue: "10000",
+			args: args{
+				resourceType: sysutil.MemoryLimitName,
+				value:        "20000",
+			},
+			want: true,
+		},
+		{
+			name:         "cpuset equals to the current in different format",
+			initialValue: "0-2",
+			args: args{
+				resourceType: sysutil.CPUSetCPUSName,
+				value:        "0,1,2",
+			},
+			want: false,
+		},
+		{
+			name:         "mergeable value is smaller than the current",
+			initialValue: "2097152",
+			args: args{
+				resourceType: sysutil.MemoryMinName,
+				value:        "1048576",
+			},
+			want: false,
+		},
+		{
+			name:         "mergeable value is larger than the current",
+			initialValue: "1048576",
+			args: args{
+				resourceType: sysutil.MemoryMinName,
+				value:        "2097152",
+			},
+			want: true,
+		},
+		{
+			name:         "cpu shares equals to the converted weight",
+			initialValue: "39",
+			args: args{
+				resourceType: sysutil.CPUSharesName,
+				value:        "1024",
+			},
+			want: false,
+		},
+		{
+			name:         "cpu shares differs from the converted weight",
+			initialValue: "39",
+			args: args{
+				resourceType: sysutil.CPUSharesName,
+				value:        "2048",
+			},
+			want: true,
+		},
+		{
+			name:         "unlimited value equals to the current max",
+			initialValue: "max",
+			args: args{
+				resourceType: sysutil.MemoryLimitName,
+				value:        "-1",
+			},
+			want: false,
+		},
+		{
+			name: "failed to read current value",
+			args: args{
+				resourceType: sysutil.MemoryLimitName,
+				value:        "10000",
+			},
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(true)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(tt.args.resourceType, parentDir, tt.args.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			if tt.initialValue != "" {
+				helper.WriteCgroupFileContents(parentDir, c.file, tt.initialValue)
+			}
+
+			got, gotErr := c.WouldChange()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, got)
+			if tt.initialValue != "" {
+				assert.Equal(t, tt.initialValue, helper.ReadCgroupFileContents(parentDir, c.file))
+			}
+		})
+	}
+}
+
+func TestDefaultResourceUpdater_WouldChange(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	helper.WriteFileContents("test_dir/test_file", "1234")
+	file := filepath.Join(helper.TempDir, "test_dir", "test_file")
+	u, err := NewCommonDefaultUpdater(file, file, "1234", nil)
+	assert.NoError(t, err)
+	got, err := u.(*DefaultResourceUpdater).WouldChange()
+	assert.NoError(t, err)
+	assert.False(t, got)
+
+	u, err = NewCommonDefaultUpdater(file, file, "5678", nil)
+	assert.NoError(t, err)
+	got, err = u.(*DefaultResourceUpdater).WouldChange()
+	assert.NoError(t, err)
+	assert.True(t, got)
+	assert.Equal(t, "1234", helper.ReadFileContents("test_dir/test_file"))
+}
+
+func TestCgroupWriteOnlyUpdateFunc(t *testing.T) {
+	type fields struct {
+		UseCgroupsV2 bool
+	}
+	type args struct {
+		resourceType sysutil.ResourceType
+		value        string
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		args          args
+		want          string
+		wantErr       bool
+		isUnsupported bool
+	}{
+		{
+			name: "write a valid device rule",
+			args: args{
+				resourceType: sysutil.DevicesAllowName,
+				value:        "c 1:3 mr",
+			},
+			want: "c 1:3 mr",
+		},
+		{
+			name: "write a valid deny rule even if the current is the same",
+			args: args{
+				resourceType: sysutil.DevicesDenyName,
+				value:        "a",
+			},
+			want: "a",
+		},
+		{
+			name: "reject a malformed device rule",
+			args: args{
+				resourceType: sysutil.DevicesAllowName,
+				value:        "c 1-3 mr",
+			},
+			want:    "a",
+			wantErr: true,
+		},
+		{
+			name: "device rule is unsupported on cgroups-v2",
+			fields: fields{
+				UseCgroupsV2: true,
+			},
+			args: args{
+				resourceType: sysutil.DevicesAllowName,
+				value:        "c 1:3 mr",
+			},
+			want:          "a",
+			wantErr:       true,
+			isUnsupported: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.fields.UseCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(tt.args.resourceType, parentDir, tt.args.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			if !tt.isUnsupported {
+				helper.WriteCgroupFileContents(parentDir, c.file, "a")
+			}
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if gotErr != nil {
+				assert.Equal(t, tt.isUnsupported, sysutil.IsResourceUnsupportedErr(gotErr))
+			}
+			if !tt.isUnsupported {
+				assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, c.file))
+			}
+		})
+	}
+}
+
+func TestCgroupUpdatePidsMaxFunc(t *testing.T) {
+	type fields struct {
+		UseCgroupsV2 bool
+		initialValue string
+		pidsCurrent  string
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		value      string
+		want       string
+		wantErr    bool
+		wantMerged bool
+	}{
+		{
+			name: "write a numeric limit",
+			fields: fields{
+				initialValue: "max",
+				pidsCurrent:  "10",
+			},
+			value:      "1024",
+			want:       "1024",
+			wantMerged: true,
+		},
+		{
+			name: "write max on cgroups-v2",
+			fields: fields{
+				UseCgroupsV2: true,
+				initialValue: "1024",
+				pidsCurrent:  "10",
+			},
+			value: "max",
+			want:  "max",
+		},
+		{
+			name: "reject a non-positive limit",
+			fields: fields{
+				initialValue: "max",
+				pidsCurrent:  "10",
+			},
+			value:   "0",
+			want:    "max",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.fields.UseCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			pidsMax, _ := sysutil.GetCgroupResource(sysutil.PidsMaxName)
+			pidsCurrent, _ := sysutil.GetCgroupResource(sysutil.PidsCurrentName)
+			helper.WriteCgroupFileContents(parentDir, pidsMax, tt.fields.initialValue)
+			helper.WriteCgroupFileContents(parentDir, pidsCurrent, tt.fields.pidsCurrent)
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.PidsMaxName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, pidsMax))
+
+			if !tt.wantErr {
+				helper.WriteCgroupFileContents(parentDir, pidsMax, tt.fields.initialValue)
+				merged, gotErr := u.MergeUpdate()
+				assert.NoError(t, gotErr)
+				assert.NotNil(t, merged)
+				if tt.wantMerged {
+					assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, pidsMax))
+				} else {
+					assert.Equal(t, tt.fields.initialValue, helper.ReadCgroupFileContents(parentDir, pidsMax))
+				}
+			}
+		})
+	}
+}
+
+func TestCgroupUpdatePidsMaxFunc_BelowCurrent(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.PidsCurrent, "100")
+
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.PidsMaxName, parentDir, "10", nil)
+	assert.NoError(t, err)
+	// suppose the kernel rejects the write
+	gotErr := wrapPidsMaxErr(u.(*CgroupResourceUpdater), fmt.Errorf("invalid argument"))
+	assert.Error(t, gotErr)
+	assert.Contains(t, gotErr.Error(), "below the pids.current 100")
+}
+
+func TestCgroupResourceUpdater_WithParentDir(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	u, err := NewCommonCgroupUpdater(sysutil.CPUCFSQuotaName, "/kubepods.slice/kubepods-pod1.slice", "10000", nil)
+	assert.NoError(t, err)
+	c := u.(*CgroupResourceUpdater)
+	assert.Equal(t, "/kubepods.slice/kubepods-pod1.slice", c.ParentDir())
+
+	got := c.WithParentDir("/kubepods.slice/kubepods-pod2.slice")
+	assert.Equal(t, "/kubepods.slice/kubepods-pod2.slice", got.(*CgroupResourceUpdater).ParentDir())
+	assert.Equal(t, sysutil.CPUCFSQuota.Path("/kubepods.slice/kubepods-pod2.slice"), got.Path())
+	assert.Equal(t, "10000", got.Value())
+	assert.Equal(t, "/kubepods.slice/kubepods-pod1.slice", c.ParentDir())
+	assert.Equal(t, sysutil.CPUCFSQuota.Path("/kubepods.slice/kubepods-pod1.slice"), c.Path())
+}
+
+func TestDefaultResourceUpdater_WithParentDir(t *testing.T) {
+	u, err := NewCommonDefaultUpdater("/test_dir/test_file", "/test_dir/test_file", "1234", nil)
+	assert.NoError(t, err)
+	d := u.(*DefaultResourceUpdater)
+	assert.Equal(t, "/test_dir", d.ParentDir())
+
+	got := d.WithParentDir("/test_dir1")
+	assert.Equal(t, "/test_dir1", got.(*DefaultResourceUpdater).ParentDir())
+	assert.Equal(t, "/test_dir1/test_file", got.Path())
+	assert.Equal(t, "/test_dir1/test_file", got.Key())
+	assert.Equal(t, "/test_dir/test_file", d.Path())
+	assert.Equal(t, "/test_dir/test_file", d.Key())
+}
+
+func TestCgroupResourceUpdater_CloneWithValue(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	u, err := NewCommonCgroupUpdater(sysutil.CPUCFSQuotaName, "/kubepods.slice/kubepods-pod1.slice", "10000", nil)
+	assert.NoError(t, err)
+	u.UpdateLastUpdateTimestamp(time.Now())
+
+	got := u.(*CgroupResourceUpdater).CloneWithValue("20000")
+	assert.Equal(t, "20000", got.Value())
+	assert.Equal(t, u.Path(), got.Path())
+	assert.True(t, got.GetLastUpdateTimestamp().IsZero())
+	assert.Equal(t, "10000", u.Value())
+	assert.False(t, u.GetLastUpdateTimestamp().IsZero())
+}
+
+func TestDefaultResourceUpdater_CloneWithValue(t *testing.T) {
+	u, err := NewCommonDefaultUpdater("/test_dir/test_file", "/test_dir/test_file", "1234", nil)
+	assert.NoError(t, err)
+	u.UpdateLastUpdateTimestamp(time.Now())
+
+	got := u.(*DefaultResourceUpdater).CloneWithValue("5678")
+	assert.Equal(t, "5678", got.Value())
+	assert.Equal(t, u.Key(), got.Key())
+	assert.Equal(t, u.Path(), got.Path())
+	assert.True(t, got.GetLastUpdateTimestamp().IsZero())
+	assert.Equal(t, "1234", u.Value())
+	assert.False(t, u.GetLastUpdateTimestamp().IsZero())
+}
+
+func TestMergeConditionIfFlagsPreserved(t *testing.T) {
+	tests := []struct {
+		name       string
+		parse      FlagParseFunc
+		oldValue   string
+		newValue   string
+		want       string
+		wantMerged bool
+	}{
+		{
+			name:       "flag in the old value survives",
+			parse:      FlagParserBySeparator(" "),
+			oldValue:   "100 [kernel]",
+			newValue:   "200",
+			want:       "200 [kernel]",
+			wantMerged: true,
+		},
+		{
+			name:       "flag in the new value is replaced by the old",
+			parse:      FlagParserBySeparator(" "),
+			oldValue:   "100 [kernel]",
+			newValue:   "200 [user]",
+			want:       "200 [kernel]",
+			wantMerged: true,
+		},
+		{
+			name:       "no flag in the old value",
+			parse:      FlagParserBySeparator(" "),
+			oldValue:   "100",
+			newValue:   "200",
+			want:       "200",
+			wantMerged: true,
+		},
+		{
+			name:       "no need to merge the same value",
+			parse:      FlagParserBySeparator(":"),
+			oldValue:   "100:flag",
+			newValue:   "100",
+			want:       "100:flag",
+			wantMerged: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotMerged, gotErr := MergeConditionIfFlagsPreserved(tt.parse)(tt.oldValue, tt.newValue)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMerged, gotMerged)
+		})
+	}
+}
+
+func TestMergeConditionJSONFields(t *testing.T) {
+	scaleFactorInRange := func(fields map[string]interface{}) error {
+		v, ok := fields["scale_factor"].(json.Number)
+		if !ok {
+			return nil
+		}
+		if n, err := v.Int64(); err != nil || n < 1 || n > 1000 {
+			return fmt.Errorf("invalid scale_factor %v", v)
+		}
+		return nil
+	}
+	tests := []struct {
+		name       string
+		schema     JSONSchemaFunc
+		oldValue   string
+		newValue   string
+		want       string
+		wantMerged bool
+		wantErr    bool
+	}{
+		{
+			name:       "fields of the old value survive",
+			oldValue:   `{"ratio":95,"scale_factor":50}`,
+			newValue:   `{"ratio":90}`,
+			want:       `{"ratio":90,"scale_factor":50}`,
+			wantMerged: true,
+		},
+		{
+			name:       "merge the nested objects",
+			oldValue:   `{"async":{"enable":1,"distance":10},"ratio":95}`,
+			newValue:   `{"async":{"distance":20}}`,
+			want:       `{"async":{"distance":20,"enable":1},"ratio":95}`,
+			wantMerged: true,
+		},
+		{
+			name:       "merge into the empty old value",
+			oldValue:   "",
+			newValue:   `{"ratio":90}`,
+			want:       `{"ratio":90}`,
+			wantMerged: true,
+		},
+		{
+			name:       "no need to merge the subset",
+			oldValue:   `{"ratio":95,"scale_factor":50}`,
+			newValue:   `{"scale_factor":50}`,
+			want:       `{"ratio":95,"scale_factor":50}`,
+			wantMerged: false,
+		},
+		{
+			name:       "keep the precision of the large integers",
+			oldValue:   `{"limit":9223372036854771712}`,
+			newValue:   `{"ratio":90}`,
+			want:       `{"limit":9223372036854771712,"ratio":90}`,
+			wantMerged: true,
+		},
+		{
+			name:       "validate the merged value by the schema",
+			schema:     scaleFactorInRange,
+			oldValue:   `{"ratio":95,"scale_factor":50}`,
+			newValue:   `{"ratio":90}`,
+			want:       `{"ratio":90,"scale_factor":50}`,
+			wantMerged: true,
+		},
+		{
+			name:     "reject the merged value invalid by the schema",
+			schema:   scaleFactorInRange,
+			oldValue: `{"ratio":95}`,
+			newValue: `{"scale_factor":0}`,
+			want:     `{"scale_factor":0}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid new value",
+			oldValue: `{"ratio":95}`,
+			newValue: `ratio=90`,
+			want:     `ratio=90`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid old value",
+			oldValue: `95`,
+			newValue: `{"ratio":90}`,
+			want:     `{"ratio":90}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotMerged, gotErr := MergeConditionJSONFields(tt.schema)(tt.oldValue, tt.newValue)
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMerged, gotMerged)
+		})
+	}
+}
+
+func TestMergeConditionIfFlagsPreserved_MergeUpdate(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "100 [kernel]")
+	u, err := NewMergeableCgroupUpdaterWithCondition(sysutil.CPUCFSQuotaName, parentDir, "200", CommonCgroupUpdateFunc,
+		MergeConditionIfFlagsPreserved(FlagParserBySeparator(" ")), nil)
+	assert.NoError(t, err)
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "200 [kernel]", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+}
+
+func TestCgroupUpdateCPUWeightNice(t *testing.T) {
+	tests := []struct {
+		name          string
+		useCgroupsV2  bool
+		value         string
+		want          string
+		wantErr       bool
+		isUnsupported bool
+	}{
+		{
+			name:         "write a valid nice value",
+			useCgroupsV2: true,
+			value:        "-5",
+			want:         "-5",
+		},
+		{
+			name:         "write the max nice value",
+			useCgroupsV2: true,
+			value:        "19",
+			want:         "19",
+		},
+		{
+			name:         "reject the nice value below the range",
+			useCgroupsV2: true,
+			value:        "-21",
+			want:         "0",
+			wantErr:      true,
+		},
+		{
+			name:         "reject the nice value above the range",
+			useCgroupsV2: true,
+			value:        "20",
+			want:         "0",
+			wantErr:      true,
+		},
+		{
+			name:          "nice value is unsupported on cgroups-v1",
+			useCgroupsV2:  false,
+			value:         "-5",
+			wantErr:       true,
+			isUnsupported: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUWeightNiceName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			if !tt.isUnsupported {
+				helper.WriteCgroupFileContents(parentDir, c.file, "0")
+			}
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if gotErr != nil {
+				assert.Equal(t, tt.isUnsupported, sysutil.IsResourceUnsupportedErr(gotErr))
+			}
+			if !tt.isUnsupported {
+				assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, c.file))
+			}
+		})
+	}
+}
+
+func TestCgroupUpdateMemorySwappiness(t *testing.T) {
+	tests := []struct {
+		name          string
+		useCgroupsV2  bool
+		maxValue      int64
+		value         string
+		want          string
+		wantErr       bool
+		isUnsupported bool
+	}{
+		{
+			name:  "write a valid swappiness",
+			value: "60",
+			want:  "60",
+		},
+		{
+			name:    "reject the swappiness above the default max",
+			value:   "150",
+			want:    "0",
+			wantErr: true,
+		},
+		{
+			name:     "write the swappiness above 100 on newer kernel",
+			maxValue: 200,
+			value:    "150",
+			want:     "150",
+		},
+		{
+			name:     "reject the swappiness above the max of newer kernel",
+			maxValue: 200,
+			value:    "201",
+			want:     "0",
+			wantErr:  true,
+		},
+		{
+			name:    "reject the negative swappiness",
+			value:   "-1",
+			want:    "0",
+			wantErr: true,
+		},
+		{
+			name:          "swappiness is unsupported on cgroups-v2",
+			useCgroupsV2:  true,
+			value:         "60",
+			wantErr:       true,
+			isUnsupported: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+			if tt.maxValue > 0 {
+				sysutil.SetMemorySwappinessMaxValue(tt.maxValue)
+				defer sysutil.SetMemorySwappinessMaxValue(sysutil.MemorySwappinessMaxValue)
+			}
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.MemorySwappinessName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			if !tt.isUnsupported {
+				helper.WriteCgroupFileContents(parentDir, c.file, "0")
+			}
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if gotErr != nil {
+				assert.Equal(t, tt.isUnsupported, sysutil.IsResourceUnsupportedErr(gotErr))
+			}
+			if !tt.isUnsupported {
+				assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, c.file))
+			}
+		})
+	}
+}
+
+func TestCgroupUpdateIOWeightFunc(t *testing.T) {
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		initValue    string
+		value        string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:      "write a global blkio.weight on cgroups-v1",
+			initValue: "100",
+			value:     "500",
+			want:      "500",
+		},
+		{
+			name:      "reject the blkio.weight out of range",
+			initValue: "100",
+			value:     "5",
+			want:      "100",
+			wantErr:   true,
+		},
+		{
+			name:         "write a global io.weight on cgroups-v2",
+			useCgroupsV2: true,
+			initValue:    "default 100",
+			value:        "default 5000",
+			want:         "default 5000",
+		},
+		{
+			name:         "write a per-device io.weight line",
+			useCgroupsV2: true,
+			initValue:    "default 100",
+			value:        "8:0 200",
+			want:         "8:0 200",
+		},
+		{
+			name:         "skip writing the per-device io.weight line already merged",
+			useCgroupsV2: true,
+			initValue:    "default 100\n8:0 200",
+			value:        "8:0 200",
+			want:         "default 100\n8:0 200",
+		},
+		{
+			name:         "reject the io.weight out of range",
+			useCgroupsV2: true,
+			initValue:    "default 100",
+			value:        "8:0 20000",
+			want:         "default 100",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.BlkioWeightName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			helper.WriteFileContents(c.Path(), tt.initValue) // the multi-line io.weight is not a valid value to write
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, helper.ReadCgroupFileContents(parentDir, c.file))
+		})
+	}
+}
+
+func TestMergeIOWeightValue(t *testing.T) {
+	assert.Equal(t, "default 100\n8:0 200", MergeIOWeightValue("default 100", "8:0 200"))
+	assert.Equal(t, "default 100\n8:0 300\n8:16 200", MergeIOWeightValue("default 100\n8:16 200\n8:0 200", "8:0 300"))
+	assert.Equal(t, "default 500\n8:0 200", MergeIOWeightValue("default 100\n8:0 200", "500"))
+	assert.Equal(t, "default 100", MergeIOWeightValue("default 100\n", ""))
+}
+
+func TestCgroupUpdateIOLatencyFunc(t *testing.T) {
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		initValue    string
+		value        string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:      "unsupported on cgroups-v1",
+			initValue: "",
+			value:     "8:0 target=75",
+			want:      "",
+			wantErr:   true,
+		},
+		{
+			name:         "write the target of a new device",
+			useCgroupsV2: true,
+			initValue:    "8:16 target=100",
+			value:        "8:0 target=75",
+			want:         "8:0 target=75",
+		},
+		{
+			name:         "skip writing the device target already merged",
+			useCgroupsV2: true,
+			initValue:    "8:0 target=75\n8:16 target=100",
+			value:        "8:0 target=75",
+			want:         "8:0 target=75\n8:16 target=100",
+		},
+		{
+			name:         "reject the invalid target",
+			useCgroupsV2: true,
+			initValue:    "8:16 target=100",
+			value:        "8:0 target=-1",
+			want:         "8:16 target=100",
+			wantErr:      true,
+		},
+		{
+			name:         "reject the invalid format",
+			useCgroupsV2: true,
+			initValue:    "8:16 target=100",
+			value:        "8:0 75",
+			want:         "8:16 target=100",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.IOLatencyName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			helper.WriteFileContents(c.Path(), tt.initValue)
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, helper.ReadFileContents(c.Path()))
+		})
+	}
+}
+
+func TestCgroupUpdateIOPrioClass(t *testing.T) {
+	tests := []struct {
+		name            string
+		useCgroupsV2    bool
+		fileAbsent      bool
+		initValue       string
+		value           string
+		want            string
+		wantErr         bool
+		wantUnsupported bool
+	}{
+		{
+			name:            "unsupported on cgroups-v1",
+			fileAbsent:      true,
+			value:           "idle",
+			wantErr:         true,
+			wantUnsupported: true,
+		},
+		{
+			name:            "unsupported on the kernel without the file",
+			useCgroupsV2:    true,
+			fileAbsent:      true,
+			value:           "idle",
+			wantErr:         true,
+			wantUnsupported: true,
+		},
+		{
+			name:         "write the class",
+			useCgroupsV2: true,
+			initValue:    "no-change",
+			value:        "idle",
+			want:         "idle",
+		},
+		{
+			name:         "write the restrict policy",
+			useCgroupsV2: true,
+			initValue:    "no-change",
+			value:        "restrict-to-be",
+			want:         "restrict-to-be",
+		},
+		{
+			name:         "reject the unknown class",
+			useCgroupsV2: true,
+			initValue:    "no-change",
+			value:        "realtime",
+			want:         "no-change",
+			wantErr:      true,
+		},
+		{
+			name:         "reject the class with the level",
+			useCgroupsV2: true,
+			initValue:    "no-change",
+			value:        "rt 0",
+			want:         "no-change",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.IOPrioClassName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			c := u.(*CgroupResourceUpdater)
+			if !tt.fileAbsent {
+				helper.WriteFileContents(c.Path(), tt.initValue)
+			}
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantUnsupported, gotErr != nil && sysutil.IsResourceUnsupportedErr(gotErr), gotErr)
+			if !tt.fileAbsent {
+				assert.Equal(t, tt.want, helper.ReadFileContents(c.Path()))
+			}
+		})
+	}
+}
+
+func TestCgroupUpdateBlkioWeightDeviceFunc(t *testing.T) {
+	tests := []struct {
+		name              string
+		currentDevice     string
+		value             string
+		wantErr           bool
+		wantDevice        string
+		wantDefaultWeight string
+	}{
+		{
+			name:              "add a device weight when none exists",
+			value:             "8:0 800",
+			wantDevice:        "8:0 800",
+			wantDefaultWeight: "100",
+		},
+		{
+			name:              "update the device weight",
+			currentDevice:     "8:0 500",
+			value:             "8:0 800",
+			wantDevice:        "8:0 800",
+			wantDefaultWeight: "100",
+		},
+		{
+			name:              "only write the changed device with the default",
+			currentDevice:     "8:0 500",
+			value:             "default 200\n8:0 500\n8:16 800",
+			wantDevice:        "8:16 800",
+			wantDefaultWeight: "200",
+		},
+		{
+			name:              "weight out of range",
+			currentDevice:     "8:0 500",
+			value:             "8:0 1001",
+			wantErr:           true,
+			wantDevice:        "8:0 500",
+			wantDefaultWeight: "100",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.BlkioWeight, "100")
+			helper.CreateCgroupFile(parentDir, sysutil.BlkioWeightDevice)
+			if tt.currentDevice != "" {
+				helper.WriteCgroupFileContents(parentDir, sysutil.BlkioWeightDevice, tt.currentDevice)
+			}
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.BlkioWeightDeviceName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantDevice, helper.ReadCgroupFileContents(parentDir, sysutil.BlkioWeightDevice))
+			assert.Equal(t, tt.wantDefaultWeight, helper.ReadCgroupFileContents(parentDir, sysutil.BlkioWeight))
+		})
+	}
+}
+
+func TestCgroupUpdateSchedLoadBalanceFunc(t *testing.T) {
+	setupKlogCapture(t)
+
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		parentValue  string
+		value        string
+		wantErr      bool
+		wantValue    string
+		wantWarn     bool
+	}{
+		{
+			name:        "disable the load balancing",
+			parentValue: "0",
+			value:       "0",
+			wantValue:   "0",
+		},
+		{
+			name:        "warn when the parent overrides the disabling",
+			parentValue: "1",
+			value:       "0",
+			wantValue:   "0",
+			wantWarn:    true,
+		},
+		{
+			name:        "enable the load balancing",
+			parentValue: "1",
+			value:       "1",
+			wantValue:   "1",
+		},
+		{
+			name:        "reject the non-binary value",
+			parentValue: "1",
+			value:       "2",
+			wantErr:     true,
+			wantValue:   "1",
+		},
+		{
+			name:         "unsupported on cgroups-v2",
+			useCgroupsV2: true,
+			value:        "0",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice"
+			childDir := "/kubepods.slice/kubepods-pod1.slice"
+			if !tt.useCgroupsV2 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CPUSetSchedLoadBalance, tt.parentValue)
+				helper.WriteCgroupFileContents(childDir, sysutil.CPUSetSchedLoadBalance, "1")
+			}
+			var buf bytes.Buffer
+			klog.SetOutput(&buf)
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetSchedLoadBalanceName, childDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			klog.Flush()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.useCgroupsV2 {
+				assert.True(t, gotErr != nil && sysutil.IsResourceUnsupportedErr(gotErr), gotErr)
+				return
+			}
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(childDir, sysutil.CPUSetSchedLoadBalance))
+			assert.Equal(t, tt.wantWarn, strings.Contains(buf.String(), "still enables sched_load_balance"), buf.String())
+		})
+	}
+}
+
+func TestNewBlkioBFQWeightUpdater(t *testing.T) {
+	tests := []struct {
+		name       string
+		hasBFQ     bool
+		hasCFQ     bool
+		value      string
+		wantNewErr bool
+		wantErr    bool
+		wantBFQ    string
+		wantCFQ    string
+	}{
+		{
+			name:    "resolve to bfq when only the bfq file exists",
+			hasBFQ:  true,
+			value:   "5",
+			wantBFQ: "5",
+		},
+		{
+			name:    "prefer bfq when both files exist",
+			hasBFQ:  true,
+			hasCFQ:  true,
+			value:   "200",
+			wantBFQ: "200",
+			wantCFQ: "100",
+		},
+		{
+			name:    "fall back to cfq",
+			hasCFQ:  true,
+			value:   "200",
+			wantCFQ: "200",
+		},
+		{
+			name:    "validate the cfq range",
+			hasCFQ:  true,
+			value:   "5",
+			wantErr: true,
+			wantCFQ: "100",
+		},
+		{
+			name:    "validate the bfq range",
+			hasBFQ:  true,
+			value:   "1001",
+			wantErr: true,
+			wantBFQ: "100",
+		},
+		{
+			name:       "unsupported when neither exists",
+			value:      "200",
+			wantNewErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice"
+			if tt.hasBFQ {
+				helper.WriteCgroupFileContents(parentDir, sysutil.BlkioBFQWeight, "100")
+			}
+			if tt.hasCFQ {
+				helper.WriteCgroupFileContents(parentDir, sysutil.BlkioWeight, "100")
+			}
+
+			u, gotErr := DefaultCgroupUpdaterFactory.New(sysutil.BlkioBFQWeightName, parentDir, tt.value, nil)
+			assert.Equal(t, tt.wantNewErr, gotErr != nil, gotErr)
+			if tt.wantNewErr {
+				assert.True(t, sysutil.IsResourceUnsupportedErr(gotErr))
+				return
+			}
+			gotErr = u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.hasBFQ {
+				assert.Equal(t, tt.wantBFQ, helper.ReadCgroupFileContents(parentDir, sysutil.BlkioBFQWeight))
+			}
+			if tt.hasCFQ {
+				assert.Equal(t, tt.wantCFQ, helper.ReadCgroupFileContents(parentDir, sysutil.BlkioWeight))
+			}
+		})
+	}
+}
+
+func TestMergeIOLatencyValue(t *testing.T) {
+	assert.Equal(t, "8:0 target=75\n8:16 target=100", MergeIOLatencyValue("8:16 target=100", "8:0 target=75"))
+	assert.Equal(t, "8:0 target=50\n8:16 target=100", MergeIOLatencyValue("8:0 target=75\n8:16 target=100", "8:0 target=50"))
+	assert.Equal(t, "8:16 target=100", MergeIOLatencyValue("8:16 target=100\n", ""))
+}
+
+func TestIOCostUpdater(t *testing.T) {
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		resourceType sysutil.ResourceType
+		initValue    *string
+		value        string
+		mergeUpdate  bool
+		wantNewErr   bool
+		wantErr      bool
+		want         string
+	}{
+		{
+			name:         "unsupported on cgroups-v1",
+			resourceType: sysutil.IOCostQoSName,
+			value:        "8:0 rpct=90",
+			wantNewErr:   true,
+		},
+		{
+			name:         "unsupported without io.cost",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOCostQoSName,
+			value:        "8:0 rpct=90",
+			wantNewErr:   true,
+		},
+		{
+			name:         "update the params of a device",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOCostQoSName,
+			initValue:    pointer.String("8:0 enable=1 ctrl=user rpct=95.00 rlat=5000\n8:16 enable=1 ctrl=user rpct=99.00 rlat=10000"),
+			value:        "8:0 rpct=90",
+			want:         "8:0 rpct=90",
+		},
+		{
+			name:         "skip writing the unchanged params",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOCostQoSName,
+			initValue:    pointer.String("8:0 enable=1 ctrl=user rpct=95.00 rlat=5000\n8:16 enable=1 ctrl=user rpct=99.00 rlat=10000"),
+			value:        "8:0 rlat=5000",
+			want:         "8:0 enable=1 ctrl=user rpct=95.00 rlat=5000\n8:16 enable=1 ctrl=user rpct=99.00 rlat=10000",
+		},
+		{
+			name:         "merge update only writes the targeted device",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOCostQoSName,
+			initValue:    pointer.String("8:0 enable=1 ctrl=user rpct=95.00 rlat=5000\n8:16 enable=1 ctrl=user rpct=99.00 rlat=10000"),
+			value:        "8:16 rpct=90",
+			mergeUpdate:  true,
+			want:         "8:16 enable=1 ctrl=user rpct=90 rlat=10000",
+		},
+		{
+			name:         "update the model params",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOCostModelName,
+			initValue:    pointer.String("8:0 ctrl=auto model=linear rbps=174019176 rseqiops=41708"),
+			value:        "8:0 ctrl=user rbps=200000000",
+			mergeUpdate:  true,
+			want:         "8:0 ctrl=user model=linear rbps=200000000 rseqiops=41708",
+		},
+		{
+			name:         "reject the unknown param",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOCostQoSName,
+			initValue:    pointer.String("8:0 enable=1 ctrl=user rpct=95.00 rlat=5000"),
+			value:        "8:0 rbps=1000",
+			wantErr:      true,
+			want:         "8:0 enable=1 ctrl=user rpct=95.00 rlat=5000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			r, err := sysutil.GetCgroupResource(tt.resourceType)
+			assert.NoError(t, err)
+			if tt.initValue != nil {
+				helper.WriteFileContents(r.Path(""), *tt.initValue)
+			}
+
+			u, gotErr := DefaultCgroupUpdaterFactory.New(tt.resourceType, "", tt.value, nil)
+			assert.Equal(t, tt.wantNewErr, gotErr != nil, gotErr)
+			if tt.wantNewErr {
+				assert.True(t, sysutil.IsResourceUnsupportedErr(gotErr), gotErr)
+				return
+			}
+			if tt.mergeUpdate {
+				_, gotErr = u.MergeUpdate()
+			} else {
+				gotErr = u.update()
+			}
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.want, helper.ReadFileContents(r.Path("")))
+		})
+	}
+}
+
+func TestMergeIOCostValue(t *testing.T) {
+	current := "8:0 enable=1 ctrl=user rpct=95.00\n8:16 enable=0"
+	assert.Equal(t, "8:0 enable=1 ctrl=user rpct=90\n8:16 enable=0", MergeIOCostValue(current, "8:0 rpct=90"))
+	assert.Equal(t, "8:0 enable=1 ctrl=user rpct=95.00\n8:16 enable=1 min=50", MergeIOCostValue(current, "8:16 enable=1 min=50"))
+	assert.Equal(t, "8:0 enable=1 ctrl=user rpct=95.00\n8:16 enable=0\n8:32 enable=1", MergeIOCostValue(current, "8:32 enable=1"))
+	assert.Equal(t, current, MergeIOCostValue(current+"\n", ""))
+}
+
+func TestCgroupUpdateCPUSetFunc(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-3")
+
+	// an empty cpuset is rejected
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "", nil)
+	assert.NoError(t, err)
+	gotErr := u.update()
+	assert.True(t, IsEmptyCPUSetErr(gotErr), gotErr)
+	assert.Equal(t, "0-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+
+	// a non-empty cpuset passes
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "2-5", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	assert.Equal(t, "2-5", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+
+	// an empty cpuset is written with the explicit override
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.(*CgroupResourceUpdater).WithEmptyCPUSetAllowed().update())
+	assert.Equal(t, "", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+}
+
+func TestMergeConditionNonEmptyCPUSet(t *testing.T) {
+	mergeConditionIntersection := func(oldValue, newValue string) (string, bool, error) {
+		old, err := cpuset.Parse(oldValue)
+		if err != nil {
+			return newValue, false, err
+		}
+		v, err := cpuset.Parse(newValue)
+		if err != nil {
+			return newValue, false, err
+		}
+		merged := old.Intersection(v)
+		return merged.String(), !merged.Equals(old), nil
+	}
+
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-3")
+
+	u, err := NewMergeableCgroupUpdaterWithCondition(sysutil.CPUSetCPUSName, parentDir, "2-5", CgroupUpdateCPUSetFunc,
+		MergeConditionNonEmptyCPUSet(mergeConditionIntersection), nil)
+	assert.NoError(t, err)
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "2-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+
+	// the intersection is empty
+	u, err = NewMergeableCgroupUpdaterWithCondition(sysutil.CPUSetCPUSName, parentDir, "6-7", CgroupUpdateCPUSetFunc,
+		MergeConditionNonEmptyCPUSet(mergeConditionIntersection), nil)
+	assert.NoError(t, err)
+	_, gotErr := u.MergeUpdate()
+	assert.True(t, IsEmptyCPUSetErr(gotErr), gotErr)
+	assert.Equal(t, "2-3", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSet))
+}
+
+func TestPlanCPUSetTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		desired string
+		want    []string
+	}{
+		{
+			name:    "no change",
+			current: "0-3",
+			desired: "0-3",
+			want:    nil,
+		},
+		{
+			name:    "only add CPUs",
+			current: "0-1",
+			desired: "0-3",
+			want:    []string{"0-3"},
+		},
+		{
+			name:    "only remove CPUs",
+			current: "0-3",
+			desired: "2-3",
+			want:    []string{"2-3"},
+		},
+		{
+			name:    "from the empty",
+			current: "",
+			desired: "2-3",
+			want:    []string{"2-3"},
+		},
+		{
+			name:    "add CPUs before removing the disjoint",
+			current: "0-1",
+			desired: "2-3",
+			want:    []string{"0-3", "2-3"},
+		},
+		{
+			name:    "add CPUs before removing the overlapped",
+			current: "0-2,5",
+			desired: "2-4",
+			want:    []string{"0-5", "2-4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanCPUSetTransition(cpuset.MustParse(tt.current), cpuset.MustParse(tt.desired))
+			assert.Equal(t, tt.want, got)
+			for _, v := range got {
+				assert.False(t, cpuset.MustParse(v).IsEmpty(), v)
+			}
+		})
+	}
+}
+
+func TestCgroupUpdateCPUSetExclusiveFunc(t *testing.T) {
+	tests := []struct {
+		name          string
+		cpus          string
+		value         string
+		wantErr       bool
+		wantSubsetErr bool
+		wantValue     string
+	}{
+		{
+			name:      "write a valid exclusive set",
+			cpus:      "0-7",
+			value:     "2-3",
+			wantValue: "2-3",
+		},
+		{
+			name:      "clear the exclusive set",
+			cpus:      "0-7",
+			value:     "",
+			wantValue: "",
+		},
+		{
+			name:          "reject the exclusive set not a subset",
+			cpus:          "0-3",
+			value:         "2-5",
+			wantErr:       true,
+			wantSubsetErr: true,
+			wantValue:     "0",
+		},
+		{
+			name:      "reject the invalid cpuset",
+			cpus:      "0-3",
+			value:     "a-b",
+			wantErr:   true,
+			wantValue: "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUSetV2, tt.cpus)
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUSetExclusiveV2, "0")
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSExclusiveName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantSubsetErr, IsExclusiveCPUSetNotSubsetErr(gotErr), gotErr)
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, sysutil.CPUSetExclusiveV2))
+		})
+	}
+}
+
+func TestNewCPUSetExclusiveUpdater(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSetV2, "0-3")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSetExclusiveV2, "0-1")
+
+	// the looser exclusive set is merged
+	u, err := NewCPUSetExclusiveUpdater(sysutil.CPUSetCPUSExclusiveName, parentDir, "2", nil)
+	assert.NoError(t, err)
+	_, err = u.MergeUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, "0-2", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSetExclusiveV2))
+
+	// the merged exclusive set is not a subset
+	u, err = NewCPUSetExclusiveUpdater(sysutil.CPUSetCPUSExclusiveName, parentDir, "4", nil)
+	assert.NoError(t, err)
+	_, gotErr := u.MergeUpdate()
+	assert.True(t, IsExclusiveCPUSetNotSubsetErr(gotErr), gotErr)
+	assert.Equal(t, "0-2", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSetExclusiveV2))
+}
+
+func TestNewSelectiveMergeCgroupUpdaterFunc(t *testing.T) {
+	isGuaranteed := func(metadata map[string]string) bool {
+		return metadata["qos"] == "guaranteed"
+	}
+	tests := []struct {
+		name      string
+		metadata  map[string]string
+		value     string
+		wantValue string
+	}{
+		{
+			name:      "merge looser for the guaranteed",
+			metadata:  map[string]string{"qos": "guaranteed"},
+			value:     "20000",
+			wantValue: "20000",
+		},
+		{
+			name:      "no merge looser for the guaranteed",
+			metadata:  map[string]string{"qos": "guaranteed"},
+			value:     "5000",
+			wantValue: "10000",
+		},
+		{
+			name:      "merge tighter for the others",
+			metadata:  map[string]string{"qos": "burstable"},
+			value:     "5000",
+			wantValue: "5000",
+		},
+		{
+			name:      "no merge tighter for the others",
+			metadata:  map[string]string{"qos": "burstable"},
+			value:     "20000",
+			wantValue: "10000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+
+			newFn := NewSelectiveMergeCgroupUpdaterFunc(CommonCgroupUpdateFunc, MergeConditionIfValueIsLarger,
+				MergeConditionIfValueIsSmaller, isGuaranteed, tt.metadata)
+			u, err := newFn(sysutil.CPUCFSQuotaName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			_, err = u.MergeUpdate()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+		})
+	}
+}
+
+func TestCgroupUpdateFreezerFunc(t *testing.T) {
+	tests := []struct {
+		name        string
+		useCgroupV2 bool
+		current     string
+		value       string
+		wantErr     bool
+		wantValue   string
+	}{
+		{
+			name:      "freeze on cgroups-v1",
+			current:   sysutil.FreezerStateThawed,
+			value:     sysutil.FreezerStateFrozen,
+			wantValue: sysutil.FreezerStateFrozen,
+		},
+		{
+			name:      "thaw on cgroups-v1 with the v2 representation",
+			current:   sysutil.FreezerStateFrozen,
+			value:     sysutil.CgroupFreezeThawed,
+			wantValue: sysutil.FreezerStateThawed,
+		},
+		{
+			name:      "invalid value on cgroups-v1",
+			current:   sysutil.FreezerStateThawed,
+			value:     "PAUSED",
+			wantErr:   true,
+			wantValue: sysutil.FreezerStateThawed,
+		},
+		{
+			name:        "freeze on cgroups-v2 with the v1 representation",
+			useCgroupV2: true,
+			current:     sysutil.CgroupFreezeThawed,
+			value:       sysutil.FreezerStateFrozen,
+			wantValue:   sysutil.CgroupFreezeFrozen,
+		},
+		{
+			name:        "thaw on cgroups-v2",
+			useCgroupV2: true,
+			current:     sysutil.CgroupFreezeFrozen,
+			value:       sysutil.CgroupFreezeThawed,
+			wantValue:   sysutil.CgroupFreezeThawed,
+		},
+		{
+			name:        "invalid value on cgroups-v2",
+			useCgroupV2: true,
+			current:     sysutil.CgroupFreezeThawed,
+			value:       "2",
+			wantErr:     true,
+			wantValue:   sysutil.CgroupFreezeThawed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			r := sysutil.FreezerState
+			if tt.useCgroupV2 {
+				r = sysutil.FreezerStateV2
+			}
+			helper.WriteCgroupFileContents(parentDir, r, tt.current)
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.FreezerStateName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, r))
+		})
+	}
+}
+
+func TestCPUIdleUpdater(t *testing.T) {
+	tests := []struct {
+		name            string
+		fileExists      bool
+		value           string
+		wantErr         bool
+		wantUnsupported bool
+		wantValue       string
+	}{
+		{
+			name:       "mark the cgroup idle",
+			fileExists: true,
+			value:      "1",
+			wantValue:  "1",
+		},
+		{
+			name:       "reject the non-binary value",
+			fileExists: true,
+			value:      "2",
+			wantErr:    true,
+			wantValue:  "0",
+		},
+		{
+			name:            "unsupported on the kernel without cpu.idle",
+			fileExists:      false,
+			value:           "1",
+			wantErr:         true,
+			wantUnsupported: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuotaV2, "max 100000")
+			if tt.fileExists {
+				helper.WriteCgroupFileContents(parentDir, sysutil.CPUIdleV2, "0")
+			}
+
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUIdleName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantUnsupported, gotErr != nil && sysutil.IsResourceUnsupportedErr(gotErr), gotErr)
+			if tt.fileExists {
+				assert.Equal(t, tt.wantValue, helper.ReadCgroupFileContents(parentDir, sysutil.CPUIdleV2))
+			}
+		})
+	}
+}
+
+func TestCgroupBufferedWriteFunc(t *testing.T) {
+	oldWrite := cgroupBufferedFileWrite
+	defer func() {
+		cgroupBufferedFileWrite = oldWrite
+	}()
+
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		resourceType sysutil.ResourceType
+		value        string
+		wantWrites   []string
+		wantErr      bool
+	}{
+		{
+			name:         "write a multi-device io.max one device per call",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOMaxName,
+			value:        "8:0 rbps=1048576 wiops=max\n8:16 wbps=2097152\n",
+			wantWrites:   []string{"8:0 rbps=1048576 wiops=max", "8:16 wbps=2097152"},
+		},
+		{
+			name:         "reject the invalid io.max before writing",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOMaxName,
+			value:        "8:0 rbps=1048576\n8:16 wbps=-1",
+			wantWrites:   nil,
+			wantErr:      true,
+		},
+		{
+			name:         "reject the malformed io.max with a duplicated device",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOMaxName,
+			value:        "8:0 rbps=1048576\n8:0 wbps=2097152",
+			wantWrites:   nil,
+			wantErr:      true,
+		},
+		{
+			name:         "reject the malformed io.max with a control character",
+			useCgroupsV2: true,
+			resourceType: sysutil.IOMaxName,
+			value:        "8:0 rbps=1048576\x00 wbps=2097152",
+			wantWrites:   nil,
+			wantErr:      true,
+		},
+		{
+			name:         "reject the devices rules before writing any line",
+			resourceType: sysutil.DevicesAllowName,
+			value:        "c 1:3 mr\nx 8:* rwm",
+			wantWrites:   nil,
+			wantErr:      true,
+		},
+		{
+			name:         "write the devices rules line by line",
+			resourceType: sysutil.DevicesAllowName,
+			value:        "c 1:3 mr\nb 8:* rwm",
+			wantWrites:   []string{"c 1:3 mr", "b 8:* rwm"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-pod1.slice"
+			u, err := DefaultCgroupUpdaterFactory.New(tt.resourceType, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			helper.WriteFileContents(u.Path(), "")
+
+			var gotWrites []string
+			cgroupBufferedFileWrite = func(cgroupTaskDir string, r sysutil.Resource, value string) error {
+				gotWrites = append(gotWrites, value)
+				return cgroupFileWrite(cgroupTaskDir, r, value)
+			}
+			gotErr := u.(*CgroupResourceUpdater).WithBufferedWrite().update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.Equal(t, tt.wantWrites, gotWrites)
+		})
+	}
+}
+
+func TestCgroupLineWriteFunc(t *testing.T) {
+	oldWrite := cgroupBufferedFileWrite
+	defer func() {
+		cgroupBufferedFileWrite = oldWrite
+	}()
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.IOMaxName, parentDir, "8:0 rbps=1048576\n8:16 wbps=2097152\n8:32 riops=100", nil)
+	assert.NoError(t, err)
+	helper.WriteFileContents(u.Path(), "")
+
+	var gotWrites []string
+	cgroupBufferedFileWrite = func(cgroupTaskDir string, r sysutil.Resource, value string) error {
+		gotWrites = append(gotWrites, value)
+		return cgroupFileWrite(cgroupTaskDir, r, value)
+	}
+	assert.NoError(t, u.update())
+	// the kernel parses one device per write
+	assert.Equal(t, []string{"8:0 rbps=1048576", "8:16 wbps=2097152", "8:32 riops=100"}, gotWrites)
+}
+
+func TestResourceUpdater_IsMergeable(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.MemoryMinName, parentDir, "1048576", nil)
+	assert.NoError(t, err)
+	assert.True(t, u.IsMergeable())
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	assert.False(t, u.IsMergeable())
+	u, err = NewCommonDefaultUpdater("/proc/sys/vm/min_free_kbytes", "/proc/sys/vm/min_free_kbytes", "1024", nil)
+	assert.NoError(t, err)
+	assert.False(t, u.IsMergeable())
+}
+
+func TestCgroupUpdateMemoryReclaimFunc(t *testing.T) {
+	tests := []struct {
+		name         string
+		useCgroupsV2 bool
+		fileExist    bool
+		value        string
+		wantErr      bool
+	}{
+		{
+			name:         "write a valid reclaim",
+			useCgroupsV2: true,
+			fileExist:    true,
+			value:        "1048576",
+		},
+		{
+			name:         "reject the non-positive bytes",
+			useCgroupsV2: true,
+			fileExist:    true,
+			value:        "0",
+			wantErr:      true,
+		},
+		{
+			name:         "unsupported if the kernel lacks the file",
+			useCgroupsV2: true,
+			value:        "1048576",
+			wantErr:      true,
+		},
+		{
+			name:    "unsupported on cgroups-v1",
+			value:   "1048576",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(tt.useCgroupsV2)
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			if tt.useCgroupsV2 {
+				helper.WriteCgroupFileContents(parentDir, sysutil.MemoryUsageV2, "104857600")
+			} else {
+				helper.WriteCgroupFileContents(parentDir, sysutil.MemoryUsage, "104857600")
+			}
+			u, err := DefaultCgroupUpdaterFactory.New(sysutil.MemoryReclaimName, parentDir, tt.value, nil)
+			assert.NoError(t, err)
+			if tt.fileExist {
+				helper.WriteFileContents(u.Path(), "")
+			}
+
+			gotErr := u.update()
+			assert.Equal(t, tt.wantErr, gotErr != nil, gotErr)
+			if tt.useCgroupsV2 && !tt.fileExist || !tt.useCgroupsV2 {
+				assert.True(t, sysutil.IsResourceUnsupportedErr(gotErr), gotErr)
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.value, helper.ReadFileContents(u.Path()))
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+
+	common, err := NewCommonCgroupUpdater(sysutil.CPUSetCPUSName, parentDir, "0-1", nil)
+	assert.NoError(t, err)
+	mergeable, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "0-1", nil)
+	assert.NoError(t, err)
+	assert.False(t, common.IsMergeable())
+	assert.True(t, mergeable.IsMergeable())
+	assert.Equal(t, common.Path(), mergeable.Path())
+	assert.NotEqual(t, CacheKey(common), CacheKey(mergeable))
+
+	// the key is stable for the same kind and path regardless of the value
+	another, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "2-3", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, CacheKey(mergeable), CacheKey(another))
+	assert.Equal(t, CacheKey(mergeable), CacheKey(mergeable.Clone()))
+	// the wrappers of the same path are a different kind
+	assert.NotEqual(t, CacheKey(mergeable), CacheKey(NewReadOnlyUpdater(mergeable)))
+}
+
+// setupKlogCapture makes the klog only write into the output set by the klog.SetOutput, so the tests can capture the
+// logs. The klog flags and the output are restored when the test finishes.
+func setupKlogCapture(t *testing.T) *flag.FlagSet {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	flagNames := []string{"v", "logtostderr", "alsologtostderr", "stderrthreshold"}
+	oldValues := map[string]string{}
+	for _, name := range flagNames {
+		oldValues[name] = fs.Lookup(name).Value.String()
+	}
+	t.Cleanup(func() {
+		for _, name := range flagNames {
+			assert.NoError(t, fs.Set(name, oldValues[name]))
+		}
+		klog.SetOutput(os.Stderr)
+	})
+	assert.NoError(t, fs.Set("logtostderr", "false"))
+	assert.NoError(t, fs.Set("alsologtostderr", "false"))
+	assert.NoError(t, fs.Set("stderrthreshold", "FATAL"))
+	return fs
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"strconv"
+	"strings"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+	"github.com/koordinator-sh/koordinator/pkg/util/cpuset"
+)
+
+// CgroupMemoryUnlimitedValueStr is the `memory.limit_in_bytes` value of the unlimited cgroups-v1 memory, which is
+// math.MaxInt64 rounded down to the page size.
+const CgroupMemoryUnlimitedValueStr = "9223372036854771712"
+
+// ValueNormalizeFunc converts the cgroup value into the canonical form of a resource type.
+type ValueNormalizeFunc func(value string) string
+
+// valueNormalizers is the normalization table of the resource types. The canonical form of an unlimited value is
+// "-1". The resource types not in the table are only trimmed.
+var valueNormalizers = map[sysutil.ResourceType]ValueNormalizeFunc{
+	sysutil.CPUCFSQuotaName:  normalizeCPUMaxField(0),
+	sysutil.CPUCFSPeriodName: normalizeCPUMaxField(1),
+	sysutil.MemoryLimitName:  normalizeUnlimited,
+	sysutil.MemoryHighName:   normalizeUnlimited,
+	sysutil.MemoryMinName:    normalizeUnlimited,
+	sysutil.MemoryLowName:    normalizeUnlimited,
+	sysutil.PidsMaxName:      normalizeUnlimited,
+	sysutil.CPUSetCPUSName:   normalizeCPUSet,
+
+	sysutil.CPUSetCPUSExclusiveName: normalizeCPUSet,
+}
+
+// NormalizeCgroupValue normalizes the value of the given resource type into its canonical form, so that the values
+// read from different kernels can be compared reliably, e.g. "max\n" of `memory.max` is normalized into "-1".
+func NormalizeCgroupValue(resourceType sysutil.ResourceType, value string) string {
+	value = strings.TrimSpace(value)
+	if fn, ok := valueNormalizers[resourceType]; ok {
+		return fn(value)
+	}
+	return value
+}
+
+// ValuesEqual checks whether two values of the resource type are effectively equal by the resource semantics, which
+// ignores the formatting differences, e.g. "0-2" equals "0,1,2" of `cpuset.cpus`, "max" equals "-1" of `memory.max`,
+// and the integers are compared numerically.
+func ValuesEqual(resourceType sysutil.ResourceType, a, b string) bool {
+	a, b = NormalizeCgroupValue(resourceType, a), NormalizeCgroupValue(resourceType, b)
+	if a == b {
+		return true
+	}
+	aInt, aErr := strconv.ParseInt(a, 10, 64)
+	bInt, bErr := strconv.ParseInt(b, 10, 64)
+	return aErr == nil && bErr == nil && aInt == bInt
+}
+
+func normalizeUnlimited(value string) string {
+	switch value {
+	case sysutil.CgroupMaxSymbolStr, CgroupMaxValueStr, CgroupMemoryUnlimitedValueStr:
+		return sysutil.CgroupUnlimitedSymbolStr
+	}
+	return value
+}
+
+// normalizeCPUMaxField picks the field of the cgroups-v2 `cpu.max` ("$MAX $PERIOD"), while the cgroups-v1 values
+// have only one field.
+func normalizeCPUMaxField(idx int) ValueNormalizeFunc {
+	return func(value string) string {
+		fields := strings.Fields(value)
+		if len(fields) == 2 {
+			value = fields[idx]
+		}
+		return normalizeUnlimited(value)
+	}
+}
+
+func normalizeCPUSet(value string) string {
+	s, err := cpuset.Parse(value)
+	if err != nil {
+		return value
+	}
+	return s.String()
+}
+
+// unlimitedConvertedResources are the resource types whose "-1" is written as "max" on the cgroups-v2, see
+// CgroupUpdateWithUnlimitedFunc.
+var unlimitedConvertedResources = map[sysutil.ResourceType]struct{}{
+	sysutil.CPUCFSQuotaName:  {},
+	sysutil.CPUCFSPeriodName: {},
+	sysutil.MemoryLimitName:  {},
+}
+
+// ConvertCgroupValue converts the value into the form actually written into the cgroup file by the update funcs,
+// e.g. the `cpu.shares` is written as the `cpu.weight` and "-1" of the `cpu.max` is written as "max" on the
+// cgroups-v2. The value of the other resources is returned unchanged.
+func ConvertCgroupValue(r sysutil.Resource, value string) (string, error) {
+	if !sysutil.IsCgroupV2Resource(r) {
+		return value, nil
+	}
+	if r.ResourceType() == sysutil.CPUSharesName {
+		v, err := sysutil.ConvertCPUSharesToWeight(value)
+		if err != nil {
+			return value, err
+		}
+		return strconv.FormatInt(v, 10), nil
+	}
+	if _, ok := unlimitedConvertedResources[r.ResourceType()]; ok && value == sysutil.CgroupUnlimitedSymbolStr {
+		return sysutil.CgroupMaxSymbolStr, nil
+	}
+	return value, nil
+}
+
+// IsCgroupValueDesired checks whether the current value read from the cgroup file is the one written for the desired
+// value, i.e. the value converted by ConvertCgroupValue is equal to the current by the resource semantics.
+func IsCgroupValueDesired(r sysutil.Resource, current, value string) bool {
+	converted, err := ConvertCgroupValue(r, value)
+	if err != nil {
+		return false
+	}
+	return isCgroupValueEqual(r, current, converted) || ValuesEqual(r.ResourceType(), current, converted)
+}
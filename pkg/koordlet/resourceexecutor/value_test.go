@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+	"github.com/koordinator-sh/koordinator/pkg/util/cpuset"
+)
+
+func TestNormalizeCgroupValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType sysutil.ResourceType
+		value        string
+		want         string
+	}{
+		{
+			name:         "trim the unknown resource",
+			resourceType: sysutil.CPUBurstName,
+			value:        " 10000\n",
+			want:         "10000",
+		},
+		{
+			name:         "normalize max memory limit",
+			resourceType: sysutil.MemoryLimitName,
+			value:        "max\n",
+			want:         "-1",
+		},
+		{
+			name:         "normalize page-aligned max memory limit",
+			resourceType: sysutil.MemoryLimitName,
+			value:        "9223372036854771712",
+			want:         "-1",
+		},
+		{
+			name:         "normalize cpu.max quota",
+			resourceType: sysutil.CPUCFSQuotaName,
+			value:        "max 100000\n",
+			want:         "-1",
+		},
+		{
+			name:         "normalize cpu.max period",
+			resourceType: sysutil.CPUCFSPeriodName,
+			value:        "max 100000",
+			want:         "100000",
+		},
+		{
+			name:         "normalize cpuset",
+			resourceType: sysutil.CPUSetCPUSName,
+			value:        "0,1,2,4\n",
+			want:         "0-2,4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeCgroupValue(tt.resourceType, tt.value))
+		})
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType sysutil.ResourceType
+		a            string
+		b            string
+		want         bool
+	}{
+		{
+			name:         "cpuset equals in different formats",
+			resourceType: sysutil.CPUSetCPUSName,
+			a:            "0-2",
+			b:            "0,1,2\n",
+			want:         true,
+		},
+		{
+			name:         "cpuset not equal",
+			resourceType: sysutil.CPUSetCPUSName,
+			a:            "0-2",
+			b:            "0-3",
+			want:         false,
+		},
+		{
+			name:         "memory max equals -1",
+			resourceType: sysutil.MemoryLimitName,
+			a:            "max",
+			b:            "-1",
+			want:         true,
+		},
+		{
+			name:         "pids max equals -1",
+			resourceType: sysutil.PidsMaxName,
+			a:            "max\n",
+			b:            "-1",
+			want:         true,
+		},
+		{
+			name:         "max not equal to -1 without the sentinel semantics",
+			resourceType: sysutil.CPUBurstName,
+			a:            "max",
+			b:            "-1",
+			want:         false,
+		},
+		{
+			name:         "integers equal numerically",
+			resourceType: sysutil.CPUSharesName,
+			a:            "01024",
+			b:            "1024",
+			want:         true,
+		},
+		{
+			name:         "integers not equal",
+			resourceType: sysutil.CPUSharesName,
+			a:            "1024",
+			b:            "2048",
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValuesEqual(tt.resourceType, tt.a, tt.b))
+		})
+	}
+}
+
+func TestCgroupResourceUpdater_ReadCurrentNormalized(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.MemoryLimitV2, "max\n")
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.MemoryLimitName, parentDir, "1048576", nil)
+	assert.NoError(t, err)
+
+	got, err := u.(*CgroupResourceUpdater).ReadCurrentNormalized()
+	assert.NoError(t, err)
+	assert.Equal(t, "-1", got)
+}
+
+func TestCgroupResourceUpdater_ReadCurrentTyped(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSet, "0-2,4")
+
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "2048", nil)
+	assert.NoError(t, err)
+	gotShares, err := u.(*CgroupResourceUpdater).ReadCurrentInt64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), gotShares)
+
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUSetCPUSName, parentDir, "0-3", nil)
+	assert.NoError(t, err)
+	gotCPUSet, err := u.(*CgroupResourceUpdater).ReadCurrentCPUSet()
+	assert.NoError(t, err)
+	assert.Equal(t, cpuset.NewCPUSet(0, 1, 2, 4), gotCPUSet)
+
+	// malformed content
+	_, err = u.(*CgroupResourceUpdater).ReadCurrentInt64()
+	assert.Error(t, err)
+	helper.WriteFileContents(u.Path(), "0-a")
+	_, err = u.(*CgroupResourceUpdater).ReadCurrentCPUSet()
+	assert.Error(t, err)
+}
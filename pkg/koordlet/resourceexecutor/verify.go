@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// VerifyPolicy is the semantics to check whether a write sticks by the value read back.
+type VerifyPolicy string
+
+const (
+	// VerifyPolicyExact expects the read-back value equals the requested one by the resource semantics, see ValuesEqual.
+	// It is the policy of the resource types not registered.
+	VerifyPolicyExact VerifyPolicy = "Exact"
+	// VerifyPolicyClamped also accepts the value clamped by the kernel, e.g. the memory limit rounded down to the page
+	// size. It is the policy of the memory byte resources.
+	VerifyPolicyClamped VerifyPolicy = "Clamped"
+	// VerifyPolicyContains expects the read-back lines contain the requested lines, e.g. the pid written into the
+	// `cgroup.procs` is listed among the other pids.
+	VerifyPolicyContains VerifyPolicy = "Contains"
+	// VerifyPolicyNone skips the verification, e.g. the write-only `memory.reclaim`.
+	VerifyPolicyNone VerifyPolicy = "None"
+)
+
+var verifyPolicies = struct {
+	lock     sync.RWMutex
+	policies map[sysutil.ResourceType]VerifyPolicy
+}{
+	policies: map[sysutil.ResourceType]VerifyPolicy{},
+}
+
+func init() {
+	RegisterVerifyPolicy(sysutil.CPUTasksName, VerifyPolicyContains)
+	RegisterVerifyPolicy(sysutil.CPUProcsName, VerifyPolicyContains)
+	RegisterVerifyPolicy(sysutil.CPUThreadsName, VerifyPolicyContains)
+	RegisterVerifyPolicy(sysutil.CPUSetCPUSName, VerifyPolicyExact)
+	RegisterVerifyPolicy(sysutil.CPUSetCPUSExclusiveName, VerifyPolicyExact)
+	RegisterVerifyPolicy(sysutil.MemoryReclaimName, VerifyPolicyNone)
+	for resourceType := range memoryBytesResources {
+		RegisterVerifyPolicy(resourceType, VerifyPolicyClamped)
+	}
+}
+
+// RegisterVerifyPolicy registers the verify policy of the resource type.
+func RegisterVerifyPolicy(resourceType sysutil.ResourceType, policy VerifyPolicy) {
+	verifyPolicies.lock.Lock()
+	defer verifyPolicies.lock.Unlock()
+	verifyPolicies.policies[resourceType] = policy
+}
+
+// GetVerifyPolicy returns the verify policy of the resource type, which is VerifyPolicyExact if not registered.
+func GetVerifyPolicy(resourceType sysutil.ResourceType) VerifyPolicy {
+	verifyPolicies.lock.RLock()
+	defer verifyPolicies.lock.RUnlock()
+	if policy, ok := verifyPolicies.policies[resourceType]; ok {
+		return policy
+	}
+	return VerifyPolicyExact
+}
+
+// VerifyWrite checks whether the write of the requested value sticks by the value read back after the write,
+// according to the verify policy of the resource type. It returns nil if the write is verified.
+func VerifyWrite(resourceType sysutil.ResourceType, requested, readBack string) error {
+	policy := GetVerifyPolicy(resourceType)
+	verified := false
+	switch policy {
+	case VerifyPolicyNone:
+		verified = true
+	case VerifyPolicyExact:
+		verified = isValueExactlyWritten(resourceType, requested, readBack)
+	case VerifyPolicyContains:
+		verified = containsAllLines(readBack, requested)
+	case VerifyPolicyClamped:
+		verified = isValueExactlyWritten(resourceType, requested, readBack) || isKernelClamped(resourceType, readBack, requested)
+	default:
+		verified = isValueExactlyWritten(resourceType, requested, readBack)
+	}
+	if !verified {
+		return fmt.Errorf("read-back value %q of %s does not match the requested %q by the policy %s",
+			readBack, resourceType, requested, policy)
+	}
+	return nil
+}
+
+func isValueExactlyWritten(resourceType sysutil.ResourceType, requested, readBack string) bool {
+	// compatible with cgroup valued "max"
+	if requested == CgroupMaxValueStr && strings.TrimSpace(readBack) == sysutil.CgroupMaxSymbolStr {
+		return true
+	}
+	return ValuesEqual(resourceType, requested, readBack)
+}
+
+// containsAllLines checks if each non-empty line of the requested is a line of the content.
+func containsAllLines(content, requested string) bool {
+	lines := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		lines[strings.TrimSpace(line)] = true
+	}
+	for _, line := range strings.Split(requested, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !lines[line] {
+			return false
+		}
+	}
+	return true
+}
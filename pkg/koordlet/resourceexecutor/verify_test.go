@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestVerifyWrite(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+	tests := []struct {
+		name         string
+		resourceType sysutil.ResourceType
+		requested    string
+		readBack     string
+		wantErr      bool
+	}{
+		{
+			name:         "append-list contains the requested line",
+			resourceType: sysutil.CPUProcsName,
+			requested:    "12345",
+			readBack:     "100\n12345\n200\n",
+		},
+		{
+			name:         "append-list missing the requested line",
+			resourceType: sysutil.CPUProcsName,
+			requested:    "12345",
+			readBack:     "100\n123456\n",
+			wantErr:      true,
+		},
+		{
+			name:         "exact cpuset in a different format",
+			resourceType: sysutil.CPUSetCPUSName,
+			requested:    "0,1,2",
+			readBack:     "0-2\n",
+		},
+		{
+			name:         "exact cpuset differs",
+			resourceType: sysutil.CPUSetCPUSName,
+			requested:    "0-3",
+			readBack:     "0-2",
+			wantErr:      true,
+		},
+		{
+			name:         "clamped memory limit",
+			resourceType: sysutil.MemoryLimitName,
+			requested:    strconv.FormatInt(pageSize*10+1, 10),
+			readBack:     strconv.FormatInt(pageSize*10, 10),
+		},
+		{
+			name:         "unlimited memory limit",
+			resourceType: sysutil.MemoryLimitName,
+			requested:    "-1",
+			readBack:     CgroupMemoryUnlimitedValueStr,
+		},
+		{
+			name:         "reverted value",
+			resourceType: sysutil.CPUCFSQuotaName,
+			requested:    "20000",
+			readBack:     "10000",
+			wantErr:      true,
+		},
+		{
+			name:         "page-rounded value of a non-memory resource",
+			resourceType: sysutil.CPUCFSQuotaName,
+			requested:    strconv.FormatInt(pageSize*10+1, 10),
+			readBack:     strconv.FormatInt(pageSize*10, 10),
+			wantErr:      true,
+		},
+		{
+			name:         "write-only resource",
+			resourceType: sysutil.MemoryReclaimName,
+			requested:    "1048576",
+			readBack:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWrite(tt.resourceType, tt.requested, tt.readBack)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
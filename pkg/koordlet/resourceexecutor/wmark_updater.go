@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+var _ ResourceUpdater = &MemoryWmarkUpdater{}
+
+// MemoryWmarkUpdater updates the `memory.wmark_ratio` and `memory.wmark_scale_factor` as a coupled pair, and the
+// optional `memory.wmark_min_adj`. The async memory reclamation uses the watermarks:
+//
+//	`memory.wmark_high` := limit * memory.wmark_ratio / 100
+//	`memory.wmark_low` := limit * (memory.wmark_ratio / 100 - memory.wmark_scale_factor / 1000)
+//
+// So the pair is inconsistent when the wmark_low is negative, i.e. the scale factor exceeds the ratio. The pair is
+// validated together before writing, and written in the order where the intermediate state is also consistent.
+// The `memory.wmark_min_adj` adjusts the global min watermark independently, so it is written after the pair.
+type MemoryWmarkUpdater struct {
+	ratio               *CgroupResourceUpdater
+	scaleFactor         *CgroupResourceUpdater
+	minAdj              *CgroupResourceUpdater // optional
+	lastUpdateTimestamp time.Time
+}
+
+// NewMemoryWmarkUpdater returns a MemoryWmarkUpdater for the cgroup. The minAdj is not updated if it is empty.
+func NewMemoryWmarkUpdater(parentDir string, ratio, scaleFactor, minAdj string, e *audit.EventHelper) (*MemoryWmarkUpdater, error) {
+	ratioUpdater, err := NewCommonCgroupUpdater(sysutil.MemoryWmarkRatioName, parentDir, ratio, e)
+	if err != nil {
+		return nil, err
+	}
+	scaleFactorUpdater, err := NewCommonCgroupUpdater(sysutil.MemoryWmarkScaleFactorName, parentDir, scaleFactor, e)
+	if err != nil {
+		return nil, err
+	}
+	u := &MemoryWmarkUpdater{
+		ratio:       ratioUpdater.(*CgroupResourceUpdater),
+		scaleFactor: scaleFactorUpdater.(*CgroupResourceUpdater),
+	}
+	if minAdj != "" {
+		minAdjUpdater, err := NewCommonCgroupUpdater(sysutil.MemoryWmarkMinAdjName, parentDir, minAdj, e)
+		if err != nil {
+			return nil, err
+		}
+		u.minAdj = minAdjUpdater.(*CgroupResourceUpdater)
+	}
+	return u, nil
+}
+
+func (u *MemoryWmarkUpdater) ResourceType() sysutil.ResourceType {
+	return u.ratio.ResourceType()
+}
+
+func (u *MemoryWmarkUpdater) Key() string {
+	return u.ratio.Key()
+}
+
+func (u *MemoryWmarkUpdater) Path() string {
+	return u.ratio.Path()
+}
+
+// Paths returns the paths of the ratio, the scale factor and the optional min adj.
+func (u *MemoryWmarkUpdater) Paths() []string {
+	paths := []string{u.ratio.Path(), u.scaleFactor.Path()}
+	if u.minAdj != nil {
+		paths = append(paths, u.minAdj.Path())
+	}
+	return paths
+}
+
+func (u *MemoryWmarkUpdater) Value() string {
+	if u.minAdj == nil {
+		return fmt.Sprintf("ratio=%s,scale_factor=%s", u.ratio.value, u.scaleFactor.value)
+	}
+	return fmt.Sprintf("ratio=%s,scale_factor=%s,min_adj=%s", u.ratio.value, u.scaleFactor.value, u.minAdj.value)
+}
+
+func (u *MemoryWmarkUpdater) IsMergeable() bool {
+	return false
+}
+
+func (u *MemoryWmarkUpdater) MergeUpdate() (ResourceUpdater, error) {
+	return nil, u.update()
+}
+
+func (u *MemoryWmarkUpdater) Clone() ResourceUpdater {
+	c := &MemoryWmarkUpdater{
+		ratio:               u.ratio.Clone().(*CgroupResourceUpdater),
+		scaleFactor:         u.scaleFactor.Clone().(*CgroupResourceUpdater),
+		lastUpdateTimestamp: u.lastUpdateTimestamp,
+	}
+	if u.minAdj != nil {
+		c.minAdj = u.minAdj.Clone().(*CgroupResourceUpdater)
+	}
+	return c
+}
+
+func (u *MemoryWmarkUpdater) GetLastUpdateTimestamp() time.Time {
+	return u.lastUpdateTimestamp
+}
+
+func (u *MemoryWmarkUpdater) UpdateLastUpdateTimestamp(time time.Time) {
+	u.lastUpdateTimestamp = time
+}
+
+func (u *MemoryWmarkUpdater) update() error {
+	ratio, scaleFactor, err := u.validate()
+	if err != nil {
+		return err
+	}
+
+	// write the ratio first if it increases, otherwise the scale factor first, so the intermediate pair is consistent
+	updaters := []*CgroupResourceUpdater{u.ratio, u.scaleFactor}
+	currentRatio, err := readCgroupAndParseInt64(u.ratio.parentDir, u.ratio.file)
+	if err == nil && ratio < currentRatio {
+		updaters = []*CgroupResourceUpdater{u.scaleFactor, u.ratio}
+	} else if err != nil {
+		klog.V(6).Infof("failed to read current %s, write the ratio first, err: %v", u.ratio.Path(), err)
+	}
+	if u.minAdj != nil {
+		updaters = append(updaters, u.minAdj)
+	}
+	for _, updater := range updaters {
+		if err = updater.update(); err != nil {
+			return fmt.Errorf("failed to update memory wmark ratio %d, scale factor %d, err: %w", ratio, scaleFactor, err)
+		}
+	}
+	return nil
+}
+
+func (u *MemoryWmarkUpdater) validate() (ratio int64, scaleFactor int64, err error) {
+	for _, c := range []*CgroupResourceUpdater{u.ratio, u.scaleFactor, u.minAdj} {
+		if c == nil {
+			continue
+		}
+		if valid, msg := c.file.IsValid(c.value); !valid {
+			return 0, 0, fmt.Errorf("invalid %s value %v, msg: %s", c.ResourceType(), c.value, msg)
+		}
+	}
+	ratio, err = strconv.ParseInt(u.ratio.value, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	scaleFactor, err = strconv.ParseInt(u.scaleFactor.value, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	// ratio 0 disables the async reclamation, where the scale factor does not take effect
+	if ratio > 0 && scaleFactor > ratio*10 {
+		return 0, 0, fmt.Errorf("inconsistent memory wmark pair, scale factor %d permill exceeds ratio %d percent, the wmark_low would be negative",
+			scaleFactor, ratio)
+	}
+	return ratio, scaleFactor, nil
+}
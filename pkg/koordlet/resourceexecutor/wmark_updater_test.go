@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestMemoryWmarkUpdater(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratio       string
+		scaleFactor string
+		minAdj      string
+		wantOrder   []string
+		wantErrMsg  string
+	}{
+		{
+			name:        "write a valid pair with the ratio increased",
+			ratio:       "95",
+			scaleFactor: "20",
+			minAdj:      "-25",
+			wantOrder:   []string{sysutil.MemoryWmarkRatioName, sysutil.MemoryWmarkScaleFactorName, sysutil.MemoryWmarkMinAdjName},
+		},
+		{
+			name:        "write a valid pair with the ratio decreased",
+			ratio:       "40",
+			scaleFactor: "30",
+			wantOrder:   []string{sysutil.MemoryWmarkScaleFactorName, sysutil.MemoryWmarkRatioName},
+		},
+		{
+			name:        "write the scale factor with the ratio disabled",
+			ratio:       "0",
+			scaleFactor: "1000",
+			wantOrder:   []string{sysutil.MemoryWmarkScaleFactorName, sysutil.MemoryWmarkRatioName},
+		},
+		{
+			name:        "reject an inconsistent pair",
+			ratio:       "2",
+			scaleFactor: "50",
+			wantErrMsg:  "inconsistent memory wmark pair, scale factor 50 permill exceeds ratio 2 percent",
+		},
+		{
+			name:        "reject an invalid ratio",
+			ratio:       "101",
+			scaleFactor: "50",
+			wantErrMsg:  "invalid memory.wmark_ratio value 101",
+		},
+		{
+			name:        "reject an invalid min adj",
+			ratio:       "95",
+			scaleFactor: "50",
+			minAdj:      "51",
+			wantErrMsg:  "invalid memory.wmark_min_adj value 51",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := sysutil.NewFileTestUtil(t)
+			defer helper.Cleanup()
+			helper.SetCgroupsV2(true)
+
+			parentDir := "/kubepods.slice/kubepods-besteffort.slice"
+			helper.WriteCgroupFileContents(parentDir, sysutil.MemoryWmarkRatioV2, "50")
+			helper.WriteCgroupFileContents(parentDir, sysutil.MemoryWmarkScaleFactorV2, "50")
+			helper.WriteCgroupFileContents(parentDir, sysutil.MemoryWmarkMinAdjV2, "0")
+
+			u, err := NewMemoryWmarkUpdater(parentDir, tt.ratio, tt.scaleFactor, tt.minAdj, nil)
+			assert.NoError(t, err)
+			var gotOrder []string
+			for _, c := range []*CgroupResourceUpdater{u.ratio, u.scaleFactor, u.minAdj} {
+				if c == nil {
+					continue
+				}
+				c.WithUpdateFunc(func(resource ResourceUpdater) error {
+					gotOrder = append(gotOrder, string(resource.ResourceType()))
+					return CommonCgroupUpdateFunc(resource)
+				})
+			}
+
+			gotErr := u.Clone().update()
+			if tt.wantErrMsg != "" {
+				assert.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tt.wantErrMsg)
+				assert.Empty(t, gotOrder)
+				assert.Equal(t, "50", helper.ReadCgroupFileContents(parentDir, sysutil.MemoryWmarkRatioV2))
+				return
+			}
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tt.wantOrder, gotOrder)
+			assert.Equal(t, tt.ratio, helper.ReadCgroupFileContents(parentDir, sysutil.MemoryWmarkRatioV2))
+			assert.Equal(t, tt.scaleFactor, helper.ReadCgroupFileContents(parentDir, sysutil.MemoryWmarkScaleFactorV2))
+			if tt.minAdj != "" {
+				assert.Equal(t, tt.minAdj, helper.ReadCgroupFileContents(parentDir, sysutil.MemoryWmarkMinAdjV2))
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ErrorClass describes how the caller should treat a failed cgroup write.
+type ErrorClass string
+
+const (
+	// ErrorClassRetryable means the write failed transiently and can be retried, e.g. the cgroup is busy.
+	ErrorClassRetryable ErrorClass = "Retryable"
+	// ErrorClassBenign means the target has gone, e.g. the cgroup or the task has exited, so the write can be ignored.
+	ErrorClassBenign ErrorClass = "Benign"
+	// ErrorClassFatal means the write is rejected by the kernel and would fail again with the same value.
+	ErrorClassFatal ErrorClass = "Fatal"
+)
+
+// errnoClasses centralizes the errno mapping so all updaters classify the write errors consistently.
+var errnoClasses = map[syscall.Errno]ErrorClass{
+	syscall.EAGAIN: ErrorClassRetryable,
+	syscall.EINTR:  ErrorClassRetryable,
+	syscall.EBUSY:  ErrorClassRetryable,
+	syscall.ENOENT: ErrorClassBenign,
+	syscall.ESRCH:  ErrorClassBenign,
+	syscall.EINVAL: ErrorClassFatal,
+	syscall.EPERM:  ErrorClassFatal,
+}
+
+// ClassifyWriteError classifies the error returned by a resource update. The errno is unwrapped from the error chain,
+// e.g. an *os.PathError. A throttled write is retryable, and a nil error is benign. The unknown errors are treated as
+// fatal so that the callers do not retry them blindly.
+func ClassifyWriteError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassBenign
+	}
+	if IsWriteThrottledErr(err) {
+		return ErrorClassRetryable
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if class, ok := errnoClasses[errno]; ok {
+			return class
+		}
+	}
+	return ErrorClassFatal
+}
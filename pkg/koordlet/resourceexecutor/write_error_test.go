@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  error
+		want ErrorClass
+	}{
+		{
+			name: "nil error is benign",
+			arg:  nil,
+			want: ErrorClassBenign,
+		},
+		{
+			name: "EBUSY is retryable",
+			arg:  &os.PathError{Op: "write", Path: "/sys/fs/cgroup/cpu/cpu.cfs_quota_us", Err: syscall.EBUSY},
+			want: ErrorClassRetryable,
+		},
+		{
+			name: "throttled write is retryable",
+			arg:  fmt.Errorf("failed to update: %w", ErrWriteThrottled),
+			want: ErrorClassRetryable,
+		},
+		{
+			name: "ENOENT is benign",
+			arg:  &os.PathError{Op: "open", Path: "/sys/fs/cgroup/cpu/pod/cpu.shares", Err: syscall.ENOENT},
+			want: ErrorClassBenign,
+		},
+		{
+			name: "wrapped ESRCH is benign",
+			arg:  fmt.Errorf("failed to write cgroup.procs, err: %w", syscall.ESRCH),
+			want: ErrorClassBenign,
+		},
+		{
+			name: "EINVAL is fatal",
+			arg:  &os.PathError{Op: "write", Path: "/sys/fs/cgroup/cpuset/cpuset.cpus", Err: syscall.EINVAL},
+			want: ErrorClassFatal,
+		},
+		{
+			name: "unknown error is fatal",
+			arg:  errors.New("unknown"),
+			want: ErrorClassFatal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyWriteError(tt.arg))
+		})
+	}
+}
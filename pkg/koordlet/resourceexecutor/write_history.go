@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"sync/atomic"
+	"time"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+// WriteEvent is a write of a resource recorded in the write history.
+type WriteEvent struct {
+	Timestamp    time.Time
+	ResourceType sysutil.ResourceType
+	Path         string
+	Value        string
+	// Error is the error message of the failed write, which is empty if succeeded.
+	Error string
+}
+
+// writeHistoryRing is a ring buffer of the write events. The writers claim the slots by an atomic counter without
+// locking, so a reader racing with the writers may see a slot overwritten by a newer event.
+type writeHistoryRing struct {
+	slots []atomic.Value // *WriteEvent
+	next  uint64
+}
+
+func newWriteHistoryRing(size int) *writeHistoryRing {
+	return &writeHistoryRing{slots: make([]atomic.Value, size)}
+}
+
+func (r *writeHistoryRing) add(e *WriteEvent) {
+	i := atomic.AddUint64(&r.next, 1) - 1
+	r.slots[i%uint64(len(r.slots))].Store(e)
+}
+
+func (r *writeHistoryRing) list() []WriteEvent {
+	next := atomic.LoadUint64(&r.next)
+	size := uint64(len(r.slots))
+	start := uint64(0)
+	if next > size {
+		start = next - size
+	}
+	events := make([]WriteEvent, 0, next-start)
+	for i := start; i < next; i++ {
+		if e, ok := r.slots[i%size].Load().(*WriteEvent); ok {
+			events = append(events, *e)
+		}
+	}
+	return events
+}
+
+// writeHistory is the current *writeHistoryRing, which is nil if the history is disabled.
+var writeHistory atomic.Value
+
+// SetWriteHistorySize sets the number of the recent writes kept in memory, which is disabled if the size is not
+// positive. The recorded writes are dropped.
+func SetWriteHistorySize(size int) {
+	if size <= 0 {
+		writeHistory.Store((*writeHistoryRing)(nil))
+		return
+	}
+	writeHistory.Store(newWriteHistoryRing(size))
+}
+
+// RecentWrites returns the recent writes of the resources from the oldest to the newest, which helps to diagnose why
+// a cgroup changed after the fact. It returns nil if the write history is disabled.
+func RecentWrites() []WriteEvent {
+	r, _ := writeHistory.Load().(*writeHistoryRing)
+	if r == nil {
+		return nil
+	}
+	return r.list()
+}
+
+// recordWriteHistory records the write issued, where the writes skipped for the unchanged values or by the options
+// are not recorded.
+func recordWriteHistory(w issuedWrite) {
+	r, _ := writeHistory.Load().(*writeHistoryRing)
+	if r == nil {
+		return
+	}
+	e := &WriteEvent{
+		Timestamp:    time.Now(),
+		ResourceType: w.resourceType,
+		Path:         w.path,
+		Value:        w.value,
+	}
+	if w.err != nil {
+		e.Error = w.err.Error()
+	}
+	r.add(e)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestRecentWrites(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	defer SetWriteHistorySize(0)
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUShares, "1024")
+	update := func(value string) error {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u.update()
+	}
+	valuesOf := func(events []WriteEvent) []string {
+		var values []string
+		for _, e := range events {
+			values = append(values, e.Value)
+		}
+		return values
+	}
+
+	// disabled by default
+	assert.NoError(t, update("2048"))
+	assert.Nil(t, RecentWrites())
+
+	SetWriteHistorySize(3)
+	assert.Empty(t, RecentWrites())
+	assert.NoError(t, update("100"))
+	assert.NoError(t, update("200"))
+	events := RecentWrites()
+	assert.Equal(t, []string{"100", "200"}, valuesOf(events))
+	assert.Equal(t, sysutil.ResourceType(sysutil.CPUSharesName), events[0].ResourceType)
+	assert.Equal(t, sysutil.CPUShares.Path(parentDir), events[0].Path)
+	assert.False(t, events[0].Timestamp.IsZero())
+	assert.Empty(t, events[0].Error)
+
+	// retain the last N and overwrite the older ones
+	for i := 3; i <= 5; i++ {
+		assert.NoError(t, update(strconv.Itoa(i*100)))
+	}
+	assert.Equal(t, []string{"300", "400", "500"}, valuesOf(RecentWrites()))
+	// the writes not issued are not recorded, e.g. the unchanged value and the invalid value
+	assert.NoError(t, update("500"))
+	assert.Error(t, update("1"))
+	assert.Equal(t, []string{"300", "400", "500"}, valuesOf(RecentWrites()))
+	// the failed write is recorded with the error
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "600", nil)
+	assert.NoError(t, err)
+	c := u.(*CgroupResourceUpdater)
+	c.updateFunc = func(u ResourceUpdater) error {
+		c := u.(*CgroupResourceUpdater)
+		return c.writeFileBy(func(string, sysutil.Resource, string) error {
+			return fmt.Errorf("write failed")
+		}, c.file, c.value)
+	}
+	assert.Error(t, c.update())
+	events = RecentWrites()
+	assert.Equal(t, []string{"400", "500", "600"}, valuesOf(events))
+	assert.Equal(t, "write failed", events[2].Error)
+}
+
+func TestRecentWrites_WrittenValue(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+	SetWriteHistorySize(3)
+	defer SetWriteHistorySize(0)
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUSharesV2, "100")
+
+	// the converted value is recorded rather than the value of the updater
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUSharesName, parentDir, "1024", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	events := RecentWrites()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "39", events[0].Value)
+	assert.Equal(t, "39", helper.ReadCgroupFileContents(parentDir, sysutil.CPUSharesV2))
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+// ErrWriteThrottled is returned when the write is skipped since the node-level write budget is exhausted. The
+// cacheable updates are not cached on this error, so the skipped writes are retried in the next reconcile.
+var ErrWriteThrottled = errors.New("write budget exhausted")
+
+func IsWriteThrottledErr(err error) bool {
+	return errors.Is(err, ErrWriteThrottled)
+}
+
+type WriteLimitPolicy string
+
+const (
+	// WriteLimitPolicySkip skips the write and returns ErrWriteThrottled when the budget is exhausted.
+	WriteLimitPolicySkip WriteLimitPolicy = "Skip"
+	// WriteLimitPolicyBlock blocks the write until the budget is available.
+	WriteLimitPolicyBlock WriteLimitPolicy = "Block"
+)
+
+var globalWriteLimiter = struct {
+	lock    sync.RWMutex
+	limiter *rate.Limiter
+	policy  WriteLimitPolicy
+}{
+	policy: WriteLimitPolicySkip,
+}
+
+// SetGlobalWriteLimiter sets a token bucket shared by all updaters to limit the writes per second on the node, which
+// protects the kernel from the write storms, e.g. during a mass reconcile. The limiter is disabled if r <= 0.
+func SetGlobalWriteLimiter(r float64, burst int) {
+	globalWriteLimiter.lock.Lock()
+	defer globalWriteLimiter.lock.Unlock()
+	if r <= 0 {
+		globalWriteLimiter.limiter = nil
+		return
+	}
+	globalWriteLimiter.limiter = rate.NewLimiter(rate.Limit(r), burst)
+}
+
+// SetGlobalWriteLimitPolicy sets whether to skip or block the writes when the budget is exhausted.
+func SetGlobalWriteLimitPolicy(policy WriteLimitPolicy) {
+	globalWriteLimiter.lock.Lock()
+	defer globalWriteLimiter.lock.Unlock()
+	globalWriteLimiter.policy = policy
+}
+
+// acquireWriteBudget charges the budget right before a write is issued, so the writes skipped for the unchanged values
+// do not consume the budget.
+func acquireWriteBudget(path, value string) error {
+	globalWriteLimiter.lock.RLock()
+	limiter, policy := globalWriteLimiter.limiter, globalWriteLimiter.policy
+	globalWriteLimiter.lock.RUnlock()
+	if limiter == nil {
+		return nil
+	}
+	if policy == WriteLimitPolicyBlock {
+		return limiter.Wait(context.Background())
+	}
+	if !limiter.Allow() {
+		klog.V(5).Infof("skip updating resource %s to %v, write budget exhausted", path, value)
+		return fmt.Errorf("update resource %s skipped, err: %w", path, ErrWriteThrottled)
+	}
+	return nil
+}
+
+// acquireWriteBudget charges the budget on the first write of an update, so an update writing multiple times, e.g. the
+// line-by-line `io.max`, is charged once and never left partially written by the throttling. The restoring updaters
+// of the rollbacks are exempted since a throttled rollback leaves the resources half-applied.
+func (u *CgroupResourceUpdater) acquireWriteBudget(path, value string) error {
+	if u.writeBudgetExempt || u.state.budgetCharged {
+		return nil
+	}
+	if err := acquireWriteBudget(path, value); err != nil {
+		return err
+	}
+	u.state.budgetCharged = true
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestGlobalWriteLimiter(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	SetGlobalWriteLimiter(0.001, 2)
+	defer SetGlobalWriteLimiter(0, 0)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	e := NewTestResourceExecutor().(*ResourceUpdateExecutorImpl)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	e.Run(stopCh)
+
+	// writes beyond the burst are throttled
+	for i, v := range []string{"20000", "30000"} {
+		u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, v, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, u.update(), "write %d", i)
+	}
+	// the write skipped for the unchanged value does not consume the budget
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "30000", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "40000", nil)
+	assert.NoError(t, err)
+	updated, err := e.Update(true, u)
+	assert.False(t, updated)
+	assert.True(t, IsWriteThrottledErr(err), err)
+	assert.Equal(t, "30000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	_, err = u.MergeUpdate()
+	assert.True(t, IsWriteThrottledErr(err), err)
+
+	// the throttled write is not cached and retried in the next reconcile
+	assert.True(t, e.needUpdate(u))
+	SetGlobalWriteLimiter(1000, 1)
+	updated, err = e.Update(true, u)
+	assert.True(t, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, "40000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+
+	// block policy waits for the budget
+	SetGlobalWriteLimitPolicy(WriteLimitPolicyBlock)
+	defer SetGlobalWriteLimitPolicy(WriteLimitPolicySkip)
+	SetGlobalWriteLimiter(50, 1)
+	start := time.Now()
+	for _, v := range []string{"50000", "60000"} {
+		u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, v, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, u.update())
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Equal(t, "60000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+}
+
+func TestGlobalWriteLimiter_ChargeOncePerUpdate(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+	helper.SetCgroupsV2(true)
+
+	SetGlobalWriteLimiter(0.001, 1)
+	defer SetGlobalWriteLimiter(0, 0)
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.IOMaxName, parentDir, "8:0 rbps=1048576\n8:16 wbps=2097152", nil)
+	assert.NoError(t, err)
+	helper.WriteFileContents(u.Path(), "")
+	// the lines of an update are charged once, so the update is not partially written
+	assert.NoError(t, u.update())
+	assert.Equal(t, "8:16 wbps=2097152", helper.ReadFileContents(u.Path()))
+	// the budget is exhausted
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.IOMaxName, parentDir, "8:0 rbps=2097152", nil)
+	assert.NoError(t, err)
+	assert.True(t, IsWriteThrottledErr(u.update()))
+}
+
+func TestGlobalWriteLimiter_RollbackExempted(t *testing.T) {
+	helper := sysutil.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	helper.WriteCgroupFileContents(parentDir, sysutil.CPUCFSQuota, "10000")
+	schedstatsPath := filepath.Join(helper.TempDir, "sched_schedstats")
+	helper.WriteFileContents(schedstatsPath, "0")
+
+	SetGlobalWriteLimiter(0.001, 2)
+	defer SetGlobalWriteLimiter(0, 0)
+
+	u, err := DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "20000", nil)
+	assert.NoError(t, err)
+	previous, err := snapshotUpdater(u)
+	assert.NoError(t, err)
+	assert.NoError(t, u.update())
+	d, err := NewCommonDefaultUpdater(schedstatsPath, schedstatsPath, "1", nil)
+	assert.NoError(t, err)
+	previousDefault, err := snapshotUpdater(d)
+	assert.NoError(t, err)
+	assert.NoError(t, d.update())
+
+	// the budget is exhausted, while the rollback still restores the values
+	u, err = DefaultCgroupUpdaterFactory.New(sysutil.CPUCFSQuotaName, parentDir, "30000", nil)
+	assert.NoError(t, err)
+	assert.True(t, IsWriteThrottledErr(u.update()))
+	assert.NoError(t, rollbackUpdaters([]ResourceUpdater{previous, previousDefault}))
+	assert.Equal(t, "10000", helper.ReadCgroupFileContents(parentDir, sysutil.CPUCFSQuota))
+	assert.Equal(t, "0", helper.ReadFileContents(schedstatsPath))
+}
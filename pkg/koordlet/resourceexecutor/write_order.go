@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+const (
+	cgroupSubtreeControlName = "cgroup.subtree_control" // cgroups-v2 only
+	cpusetMemsName           = "cpuset.mems"
+)
+
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+func IsDependencyCycleErr(err error) bool {
+	return errors.Is(err, ErrDependencyCycle)
+}
+
+var resourceDependencies = struct {
+	lock sync.RWMutex
+	// resource type -> the prerequisite resource types
+	prerequisites map[sysutil.ResourceType][]sysutil.ResourceType
+}{
+	prerequisites: map[sysutil.ResourceType][]sysutil.ResourceType{},
+}
+
+func init() {
+	// the controllers should be enabled before setting the values of the controller interface files. The cgroups-v2
+	// interfaces are keyed by the resource types, e.g. the `cpu.max` is the `cpu.cfs_quota_us` and the
+	// `cpu.cfs_period_us`, and the `memory.max` is the `memory.limit_in_bytes`.
+	for _, rt := range []sysutil.ResourceType{
+		sysutil.CPUCFSQuotaName, sysutil.CPUCFSPeriodName, sysutil.CPUBurstName, sysutil.CPUSharesName,
+		sysutil.CPUWeightNiceName, sysutil.CPUIdleName, sysutil.CPUSetCPUSName, cpusetMemsName,
+		sysutil.CPUSetCPUSExclusiveName, sysutil.MemoryMinName, sysutil.MemoryLowName, sysutil.MemoryHighName,
+		sysutil.MemoryLimitName, sysutil.MemoryOomGroupName, sysutil.BlkioWeightName, sysutil.IOLatencyName,
+		sysutil.IOMaxName, sysutil.PidsMaxName,
+	} {
+		RegisterResourceDependency(rt, cgroupSubtreeControlName)
+	}
+	// the cpuset cgroup cannot be used before both the mems and the cpus are set
+	RegisterResourceDependency(sysutil.CPUSetCPUSName, cpusetMemsName)
+	RegisterResourceDependency(sysutil.CPUSetCPUSExclusiveName, sysutil.CPUSetCPUSName)
+	// the quota and the burst are validated against the period and the quota
+	RegisterResourceDependency(sysutil.CPUCFSQuotaName, sysutil.CPUCFSPeriodName)
+	RegisterResourceDependency(sysutil.CPUBurstName, sysutil.CPUCFSQuotaName)
+}
+
+// RegisterResourceDependency declares the prerequisites of the resource type, which should be written before it by
+// TopoSortUpdaters.
+func RegisterResourceDependency(resourceType sysutil.ResourceType, prerequisites ...sysutil.ResourceType) {
+	resourceDependencies.lock.Lock()
+	defer resourceDependencies.lock.Unlock()
+	for _, p := range prerequisites {
+		if !containsResourceType(resourceDependencies.prerequisites[resourceType], p) {
+			resourceDependencies.prerequisites[resourceType] = append(resourceDependencies.prerequisites[resourceType], p)
+		}
+	}
+}
+
+// GetResourceDependencies returns the declared prerequisites of the resource type.
+func GetResourceDependencies(resourceType sysutil.ResourceType) []sysutil.ResourceType {
+	resourceDependencies.lock.RLock()
+	defer resourceDependencies.lock.RUnlock()
+	prerequisites := resourceDependencies.prerequisites[resourceType]
+	if len(prerequisites) <= 0 {
+		return nil
+	}
+	return append([]sysutil.ResourceType{}, prerequisites...)
+}
+
+func containsResourceType(types []sysutil.ResourceType, rt sysutil.ResourceType) bool {
+	for _, t := range types {
+		if t == rt {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyKey returns the key of the updater in the dependency graph. Like GetController, the file path of a
+// DefaultResourceUpdater is keyed by its filename.
+func dependencyKey(resourceType sysutil.ResourceType) sysutil.ResourceType {
+	return sysutil.ResourceType(filepath.Base(string(resourceType)))
+}
+
+// TopoSortUpdaters returns a copy of the updaters in a write order where each updater is placed after the updaters of
+// its declared prerequisites. The original order is kept as far as the dependencies allow. It returns an error if the
+// dependencies of the given updaters form a cycle.
+func TopoSortUpdaters(updaters []ResourceUpdater) ([]ResourceUpdater, error) {
+	indexesOfType := map[sysutil.ResourceType][]int{}
+	for i, u := range updaters {
+		key := dependencyKey(u.ResourceType())
+		indexesOfType[key] = append(indexesOfType[key], i)
+	}
+
+	// edges: prerequisite index -> dependent indexes
+	dependents := make([][]int, len(updaters))
+	inDegree := make([]int, len(updaters))
+	for i, u := range updaters {
+		for _, p := range GetResourceDependencies(dependencyKey(u.ResourceType())) {
+			for _, j := range indexesOfType[p] {
+				dependents[j] = append(dependents[j], i)
+				inDegree[i]++
+			}
+		}
+	}
+
+	// Kahn's algorithm, always picking the ready updater of the smallest original index
+	var ready []int
+	for i := range updaters {
+		if inDegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	sorted := make([]ResourceUpdater, 0, len(updaters))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, updaters[i])
+		for _, j := range dependents[i] {
+			inDegree[j]--
+			if inDegree[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	if len(sorted) < len(updaters) {
+		var cyclic []string
+		for i, u := range updaters {
+			if inDegree[i] > 0 {
+				cyclic = append(cyclic, string(u.ResourceType()))
+			}
+		}
+		return nil, fmt.Errorf("%w among resources [%s]", ErrDependencyCycle, strings.Join(cyclic, ", "))
+	}
+	return sorted, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexecutor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sysutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func TestTopoSortUpdaters(t *testing.T) {
+	parentDir := "/kubepods.slice/kubepods-pod1.slice"
+	newUpdater := func(resourceType sysutil.ResourceType, value string) ResourceUpdater {
+		u, err := DefaultCgroupUpdaterFactory.New(resourceType, parentDir, value, nil)
+		assert.NoError(t, err)
+		return u
+	}
+	newFileUpdater := func(filename string, value string) ResourceUpdater {
+		u, err := NewCommonDefaultUpdater(filename, filepath.Join("/sys/fs/cgroup/cpuset", parentDir, filename), value, nil)
+		assert.NoError(t, err)
+		return u
+	}
+	typesOf := func(updaters []ResourceUpdater) []string {
+		var types []string
+		for _, u := range updaters {
+			types = append(types, filepath.Base(string(u.ResourceType())))
+		}
+		return types
+	}
+
+	t.Run("sort by the declared dependencies", func(t *testing.T) {
+		updaters := []ResourceUpdater{
+			newUpdater(sysutil.CPUBurstName, "10000"),
+			newUpdater(sysutil.MemoryLimitName, "1048576"),
+			newUpdater(sysutil.CPUSetCPUSName, "0-1"),
+			newUpdater(sysutil.CPUCFSQuotaName, "20000"),
+			newFileUpdater(cpusetMemsName, "0"),
+			newUpdater(sysutil.CPUCFSPeriodName, "100000"),
+		}
+		sorted, err := TopoSortUpdaters(updaters)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			sysutil.MemoryLimitName,
+			cpusetMemsName,
+			sysutil.CPUSetCPUSName,
+			sysutil.CPUCFSPeriodName,
+			sysutil.CPUCFSQuotaName,
+			sysutil.CPUBurstName,
+		}, typesOf(sorted))
+		// the input is not modified
+		assert.Equal(t, sysutil.ResourceType(sysutil.CPUBurstName), updaters[0].ResourceType())
+	})
+
+	t.Run("enable the controllers first on cgroups-v2", func(t *testing.T) {
+		helper := sysutil.NewFileTestUtil(t)
+		defer helper.Cleanup()
+		helper.SetCgroupsV2(true)
+
+		memoryMax := newUpdater(sysutil.MemoryLimitName, "1048576")
+		assert.Equal(t, "memory.max", filepath.Base(memoryMax.Path()))
+		cpuMax := newUpdater(sysutil.CPUCFSQuotaName, "20000")
+		subtreeControl, err := NewCommonDefaultUpdater(cgroupSubtreeControlName,
+			filepath.Join("/sys/fs/cgroup", parentDir, cgroupSubtreeControlName), "+cpu +memory", nil)
+		assert.NoError(t, err)
+		sorted, err := TopoSortUpdaters([]ResourceUpdater{memoryMax, cpuMax, subtreeControl})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			cgroupSubtreeControlName,
+			sysutil.MemoryLimitName,
+			sysutil.CPUCFSQuotaName,
+		}, typesOf(sorted))
+	})
+
+	t.Run("keep the order without dependencies", func(t *testing.T) {
+		updaters := []ResourceUpdater{
+			newUpdater(sysutil.MemoryLimitName, "1048576"),
+			newUpdater(sysutil.CPUSharesName, "1024"),
+			newUpdater(sysutil.CPUCFSPeriodName, "100000"),
+		}
+		sorted, err := TopoSortUpdaters(updaters)
+		assert.NoError(t, err)
+		assert.Equal(t, typesOf(updaters), typesOf(sorted))
+	})
+
+	t.Run("error on a cycle", func(t *testing.T) {
+		oldPrerequisites := GetResourceDependencies(sysutil.CPUCFSPeriodName)
+		RegisterResourceDependency(sysutil.CPUCFSPeriodName, sysutil.CPUBurstName)
+		defer func() {
+			resourceDependencies.lock.Lock()
+			resourceDependencies.prerequisites[sysutil.CPUCFSPeriodName] = oldPrerequisites
+			resourceDependencies.lock.Unlock()
+		}()
+
+		sorted, err := TopoSortUpdaters([]ResourceUpdater{
+			newUpdater(sysutil.CPUSharesName, "1024"),
+			newUpdater(sysutil.CPUBurstName, "10000"),
+			newUpdater(sysutil.CPUCFSQuotaName, "20000"),
+			newUpdater(sysutil.CPUCFSPeriodName, "100000"),
+		})
+		assert.Nil(t, sorted)
+		assert.True(t, IsDependencyCycleErr(err), err)
+		assert.NotContains(t, err.Error(), sysutil.CPUSharesName)
+	})
+}
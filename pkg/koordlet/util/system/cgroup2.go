@@ -19,10 +19,19 @@ package system
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
+// the values of cgroups-v2 `cgroup.type`
+const (
+	CgroupTypeDomain         = "domain"
+	CgroupTypeDomainThreaded = "domain threaded"
+	CgroupTypeDomainInvalid  = "domain invalid"
+	CgroupTypeThreaded       = "threaded"
+)
+
 type CPUStatV2Raw struct {
 	UsageUsec  int64
 	UserUsec   int64
@@ -67,6 +76,103 @@ func ParseCPUCFSPeriodV2(content string) (int64, error) {
 	return v, nil
 }
 
+// ParseCPUMax parses the content of the cgroups-v2 `cpu.max` ("$MAX $PERIOD") into the quota and the period. The
+// quota "max" is parsed as -1. e.g. "max 100000" is parsed as (-1, 100000).
+func ParseCPUMax(content string) (int64, int64, error) {
+	ss := strings.Fields(content)
+	if len(ss) != 2 {
+		return -1, -1, fmt.Errorf("parse cpu.max failed, raw content: %s, err: invalid pattern", content)
+	}
+	quota := int64(-1)
+	if ss[0] != CgroupMaxSymbolStr {
+		v, err := strconv.ParseInt(ss[0], 10, 64)
+		if err != nil {
+			return -1, -1, fmt.Errorf("parse cpu.max failed, content: %s, err: %v", ss[0], err)
+		}
+		quota = v
+	}
+	period, err := strconv.ParseInt(ss[1], 10, 64)
+	if err != nil {
+		return -1, -1, fmt.Errorf("parse cpu.max failed, content: %s, err: %v", ss[1], err)
+	}
+	return quota, period, nil
+}
+
+// FormatCPUMax formats the quota and the period into the content of the cgroups-v2 `cpu.max`. A negative quota is
+// formatted as "max". e.g. FormatCPUMax(-1, 100000) = "max 100000".
+func FormatCPUMax(quota, period int64) string {
+	if quota < 0 {
+		return fmt.Sprintf("%s %d", CgroupMaxSymbolStr, period)
+	}
+	return fmt.Sprintf("%d %d", quota, period)
+}
+
+// IOMaxKeys are the keys of the limits in the cgroups-v2 `io.max`, in the order of the kernel output.
+var IOMaxKeys = []string{"rbps", "wbps", "riops", "wiops"}
+
+// IODeviceLimit is the limits of a device in the cgroups-v2 `io.max`. The limit "max" is represented as -1.
+type IODeviceLimit struct {
+	// Device is the device number in the format of "major:minor", e.g. "8:0".
+	Device string
+	// Limits is the limits of the IOMaxKeys. The keys not present are not set.
+	Limits map[string]int64
+}
+
+// ParseIOMax parses the content of the cgroups-v2 `io.max` into the device limits in the order of the lines, e.g.
+// "8:0 rbps=1048576 wbps=max riops=max wiops=max".
+func ParseIOMax(content string) ([]IODeviceLimit, error) {
+	var devices []IODeviceLimit
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) <= 0 {
+			continue
+		}
+		if len(fields) < 2 || !strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("parse io.max failed, line %q is not in the format of \"major:minor key=value...\"", line)
+		}
+		d := IODeviceLimit{Device: fields[0], Limits: map[string]int64{}}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 || !isStringInSlice(kv[0], IOMaxKeys) {
+				return nil, fmt.Errorf("parse io.max failed, limit %q is not in the format of \"key=value\", supported keys %v", field, IOMaxKeys)
+			}
+			if kv[1] == CgroupMaxSymbolStr {
+				d.Limits[kv[0]] = -1
+				continue
+			}
+			v, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("parse io.max failed, limit %q is not a non-negative integer or \"max\"", field)
+			}
+			d.Limits[kv[0]] = v
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// FormatIOMax formats the device limits into the content of the cgroups-v2 `io.max`, one line per device. The limits
+// are formatted in the order of the IOMaxKeys, and a negative limit is formatted as "max".
+func FormatIOMax(devices []IODeviceLimit) string {
+	lines := make([]string, 0, len(devices))
+	for _, d := range devices {
+		fields := []string{d.Device}
+		for _, key := range IOMaxKeys {
+			v, ok := d.Limits[key]
+			if !ok {
+				continue
+			}
+			if v < 0 {
+				fields = append(fields, key+"="+CgroupMaxSymbolStr)
+			} else {
+				fields = append(fields, key+"="+strconv.FormatInt(v, 10))
+			}
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func ParseCPUAcctStatRawV2(content string) (*CPUStatV2Raw, error) {
 	cpuStatRaw := &CPUStatV2Raw{}
 
@@ -317,3 +423,57 @@ func ConvertCPUSharesToWeight(s string) (int64, error) {
 	}
 	return w, nil
 }
+
+// schedPrioToWeight is the kernel's load weight of the nice values [-20, 19].
+// https://github.com/torvalds/linux/blob/v5.15/kernel/sched/core.c
+var schedPrioToWeight = [...]int64{
+	/* -20 */ 88761, 71755, 56483, 46273, 36291,
+	/* -15 */ 29154, 23254, 18705, 14949, 11916,
+	/* -10 */ 9548, 7620, 6100, 4904, 3906,
+	/*  -5 */ 3121, 2501, 1991, 1586, 1277,
+	/*   0 */ 1024, 820, 655, 526, 423,
+	/*   5 */ 335, 272, 215, 172, 137,
+	/*  10 */ 110, 87, 70, 56, 45,
+	/*  15 */ 36, 29, 23, 18, 15,
+}
+
+// ConvertCPUWeightNiceToWeight converts the value of `cpu.weight.nice` into the value of `cpu.weight` the kernel reports
+// after the nice value is written.
+func ConvertCPUWeightNiceToWeight(nice int64) (int64, error) {
+	isValid, msg := CPUWeightNiceValidator.Validate(strconv.FormatInt(nice, 10))
+	if !isValid {
+		return -1, fmt.Errorf("invalid cpu.weight.nice value, err: %s", msg)
+	}
+	// weight = DIV_ROUND_CLOSEST(sched_prio_to_weight[nice+20] * 100, 1024)
+	w := (schedPrioToWeight[nice-CPUWeightNiceMinValue]*100 + 512) / 1024
+	if w < CPUWeightMinValue {
+		w = CPUWeightMinValue
+	} else if w > CPUWeightMaxValue {
+		w = CPUWeightMaxValue
+	}
+	return w, nil
+}
+
+// ConvertCPUWeightToNice converts the value of `cpu.weight` into the closest value of `cpu.weight.nice` in the same way
+// as the kernel.
+func ConvertCPUWeightToNice(v int64) (int64, error) {
+	isValid, msg := CPUWeightValidator.Validate(strconv.FormatInt(v, 10))
+	if !isValid {
+		return 0, fmt.Errorf("invalid cpu.weight value, err: %s", msg)
+	}
+	// load weight = DIV_ROUND_CLOSEST(weight * 1024, 100)
+	loadWeight := (v*1024 + 50) / 100
+	lastDelta := int64(math.MaxInt64)
+	prio := 0
+	for ; prio < len(schedPrioToWeight); prio++ {
+		delta := schedPrioToWeight[prio] - loadWeight
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta >= lastDelta {
+			break
+		}
+		lastDelta = delta
+	}
+	return int64(prio-1) + CPUWeightNiceMinValue, nil
+}
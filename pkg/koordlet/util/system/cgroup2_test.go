@@ -18,6 +18,8 @@ package system
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParseCPUCFSQuotaV2(t *testing.T) {
@@ -445,3 +447,140 @@ func TestConvertCPUSharesToWeight(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertCPUWeightNice(t *testing.T) {
+	tests := []struct {
+		name       string
+		nice       int64
+		wantWeight int64
+		wantErr    bool
+	}{
+		{
+			name:       "default nice",
+			nice:       0,
+			wantWeight: 100,
+		},
+		{
+			name:       "min nice",
+			nice:       -20,
+			wantWeight: 8668,
+		},
+		{
+			name:       "max nice",
+			nice:       19,
+			wantWeight: 1,
+		},
+		{
+			name:       "nice 5",
+			nice:       5,
+			wantWeight: 33,
+		},
+		{
+			name:    "nice out of range",
+			nice:    20,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertCPUWeightNiceToWeight(tt.nice)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.wantWeight, got)
+			gotNice, err := ConvertCPUWeightToNice(got)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.nice, gotNice)
+		})
+	}
+
+	_, err := ConvertCPUWeightToNice(0)
+	assert.Error(t, err)
+	got, err := ConvertCPUWeightToNice(CPUWeightMaxValue)
+	assert.NoError(t, err)
+	assert.Equal(t, CPUWeightNiceMinValue, got)
+}
+
+func TestParseAndFormatCPUMax(t *testing.T) {
+	tests := []struct {
+		content    string
+		wantQuota  int64
+		wantPeriod int64
+		wantErr    bool
+	}{
+		{content: "max 100000", wantQuota: -1, wantPeriod: 100000},
+		{content: "200000 100000", wantQuota: 200000, wantPeriod: 100000},
+		{content: "0 100000", wantQuota: 0, wantPeriod: 100000},
+		{content: "100000", wantErr: true},
+		{content: "100000 max", wantErr: true},
+		{content: "not_a_number 100000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			quota, period, err := ParseCPUMax(tt.content)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.wantQuota, quota)
+			assert.Equal(t, tt.wantPeriod, period)
+			assert.Equal(t, tt.content, FormatCPUMax(quota, period))
+		})
+	}
+}
+
+func TestParseAndFormatIOMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []IODeviceLimit
+		wantErr bool
+	}{
+		{
+			name:    "single device",
+			content: "8:0 rbps=1048576 wbps=max riops=max wiops=100",
+			want: []IODeviceLimit{
+				{Device: "8:0", Limits: map[string]int64{"rbps": 1048576, "wbps": -1, "riops": -1, "wiops": 100}},
+			},
+		},
+		{
+			name:    "multiple devices with partial keys",
+			content: "8:0 rbps=1048576\n253:16 riops=1000 wiops=max",
+			want: []IODeviceLimit{
+				{Device: "8:0", Limits: map[string]int64{"rbps": 1048576}},
+				{Device: "253:16", Limits: map[string]int64{"riops": 1000, "wiops": -1}},
+			},
+		},
+		{
+			name:    "empty content",
+			content: "",
+		},
+		{
+			name:    "unknown key",
+			content: "8:0 rlat=100",
+			wantErr: true,
+		},
+		{
+			name:    "invalid limit",
+			content: "8:0 rbps=-1",
+			wantErr: true,
+		},
+		{
+			name:    "missing device",
+			content: "rbps=1048576",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIOMax(tt.content)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.content, FormatIOMax(got))
+		})
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"path/filepath"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 )
 
@@ -91,15 +93,29 @@ func GetCurrentCgroupVersion() CgroupVersion {
 }
 
 func GetCgroupResource(resourceType ResourceType) (Resource, error) {
-	r, ok := DefaultRegistry.Get(GetCurrentCgroupVersion(), resourceType)
+	return GetCgroupResourceForVersion(GetCurrentCgroupVersion(), resourceType)
+}
+
+// GetCgroupResourceForVersion returns the resource of the given cgroup version instead of the detected one.
+func GetCgroupResourceForVersion(version CgroupVersion, resourceType ResourceType) (Resource, error) {
+	r, ok := DefaultRegistry.Get(version, resourceType)
 	if !ok {
-		return nil, fmt.Errorf("%s not found in cgroup registry", resourceType)
+		return nil, NewUnsupportedResourceErr(resourceType, version, fmt.Sprintf("%s not found in cgroup registry", resourceType))
 	}
 	return r, nil
 }
 
+// GetCgroupVersionOf returns the cgroup version of the resource, which is cgroups-v1 if the resource is not versioned.
+func GetCgroupVersionOf(r Resource) CgroupVersion {
+	if IsCgroupV2Resource(r) {
+		return CgroupVersionV2
+	}
+	return CgroupVersionV1
+}
+
 func IsCgroupV2Resource(r Resource) bool {
-	if conv, ok := r.(*CgroupResource); ok {
+	// accept the wrappers of the CgroupResource which expose the cgroup version
+	if conv, ok := r.(interface{ GetCgroupVersion() CgroupVersion }); ok {
 		return conv.GetCgroupVersion() == CgroupVersionV2
 	}
 	return false
@@ -111,33 +127,86 @@ const ( // subsystems
 	CgroupCPUAcctDir string = "cpuacct/"
 	CgroupMemDir     string = "memory/"
 	CgroupBlkioDir   string = "blkio/"
+	CgroupDevicesDir string = "devices/"
+	CgroupPidsDir    string = "pids/"
+	CgroupFreezerDir string = "freezer/"
 
 	CgroupV2Dir = ""
 )
 
+// the values of cgroups-v1 `freezer.state` and cgroups-v2 `cgroup.freeze`
+const (
+	FreezerStateFrozen   = "FROZEN"
+	FreezerStateFreezing = "FREEZING" // read-only
+	FreezerStateThawed   = "THAWED"
+	CgroupFreezeFrozen   = "1"
+	CgroupFreezeThawed   = "0"
+)
+
+// the policies of cgroups-v2 `io.prio.class`, where "no-change" is the default of the kernel
 const (
-	CFSBasePeriodValue int64 = 100000
-	CFSQuotaMinValue   int64 = 1000 // min value except `-1`
-	CPUSharesMinValue  int64 = 2
-	CPUSharesMaxValue  int64 = 262144
-	CPUWeightMinValue  int64 = 1
-	CPUWeightMaxValue  int64 = 10000
-
-	CPUStatName      = "cpu.stat"
-	CPUSharesName    = "cpu.shares"
-	CPUCFSQuotaName  = "cpu.cfs_quota_us"
-	CPUCFSPeriodName = "cpu.cfs_period_us"
-	CPUBVTWarpNsName = "cpu.bvt_warp_ns"
-	CPUBurstName     = "cpu.cfs_burst_us"
-	CPUTasksName     = "tasks"
-	CPUProcsName     = "cgroup.procs"
-	CPUThreadsName   = "cgroup.threads"
-	CPUMaxName       = "cpu.max"
-	CPUMaxBurstName  = "cpu.max.burst"
-	CPUWeightName    = "cpu.weight"
-
-	CPUSetCPUSName          = "cpuset.cpus"
-	CPUSetCPUSEffectiveName = "cpuset.cpus.effective"
+	IOPrioClassNoChange     = "no-change"
+	IOPrioClassPromoteToRT  = "promote-to-rt"
+	IOPrioClassNoneToRT     = "none-to-rt"
+	IOPrioClassRestrictToBE = "restrict-to-be"
+	IOPrioClassIdle         = "idle"
+)
+
+const cpuWeightNiceV1UnsupportedMsg = "cpu.weight.nice is only available in cgroups-v2, use cpu.shares instead"
+
+const memorySwappinessV2UnsupportedMsg = "per-cgroup memory.swappiness is removed in cgroups-v2"
+
+const ioLatencyV1UnsupportedMsg = "io.latency is only available in cgroups-v2"
+
+const ioMaxV1UnsupportedMsg = "io.max is only available in cgroups-v2, use blkio.throttle.* instead"
+const ioCostV1UnsupportedMsg = "io.cost is only available in cgroups-v2, use blkio.cost.* instead"
+const ioPrioClassV1UnsupportedMsg = "io.prio.class is only available in cgroups-v2"
+
+const cpusetExclusiveV1UnsupportedMsg = "cpuset.cpus.exclusive is only available in cgroups-v2, use cpuset.cpu_exclusive instead"
+
+const cpusetSchedLoadBalanceV2UnsupportedMsg = "cpuset.sched_load_balance is only available in cgroups-v1, use cpuset.cpus.partition instead"
+
+const memoryReclaimV1UnsupportedMsg = "memory.reclaim is only available in cgroups-v2"
+
+const devicesV2UnsupportedMsg = "device controller in cgroups-v2 is implemented by BPF_PROG_TYPE_CGROUP_DEVICE program, devices.allow/devices.deny are not available"
+
+const (
+	CFSBasePeriodValue    int64 = 100000
+	CFSQuotaMinValue      int64 = 1000 // min value except `-1`
+	CPUSharesMinValue     int64 = 2
+	CPUSharesMaxValue     int64 = 262144
+	CPUWeightMinValue     int64 = 1
+	CPUWeightMaxValue     int64 = 10000
+	CPUWeightNiceMinValue int64 = -20
+	CPUWeightNiceMaxValue int64 = 19
+	// MemorySwappinessMaxValue is the max value of memory.swappiness, which is raised to 200 since kernel 5.8.
+	MemorySwappinessMaxValue int64 = 100
+	BlkioWeightMinValue      int64 = 10
+	BlkioWeightMaxValue      int64 = 1000
+	BlkioBFQWeightMinValue   int64 = 1
+	IOWeightMinValue         int64 = 1
+	IOWeightMaxValue         int64 = 10000
+
+	CPUStatName       = "cpu.stat"
+	CPUSharesName     = "cpu.shares"
+	CPUCFSQuotaName   = "cpu.cfs_quota_us"
+	CPUCFSPeriodName  = "cpu.cfs_period_us"
+	CPUBVTWarpNsName  = "cpu.bvt_warp_ns"
+	CPUBurstName      = "cpu.cfs_burst_us"
+	CPUTasksName      = "tasks"
+	CPUProcsName      = "cgroup.procs"
+	CPUThreadsName    = "cgroup.threads"
+	CgroupTypeName    = "cgroup.type" // cgroups-v2 only
+	CPUMaxName        = "cpu.max"
+	CPUMaxBurstName   = "cpu.max.burst"
+	CPUWeightName     = "cpu.weight"
+	CPUWeightNiceName = "cpu.weight.nice"
+	CPUIdleName       = "cpu.idle" // kernel >= 5.15
+
+	CPUSetCPUSName             = "cpuset.cpus"
+	CPUSetCPUSEffectiveName    = "cpuset.cpus.effective"
+	CPUSetCPUSExclusiveName    = "cpuset.cpus.exclusive"     // cgroups-v2 only
+	CPUSetSchedLoadBalanceName = "cpuset.sched_load_balance" // cgroups-v1 only
 
 	CPUAcctStatName           = "cpuacct.stat"
 	CPUAcctUsageName          = "cpuacct.usage"
@@ -161,13 +230,35 @@ const (
 	MemoryUsePriorityOomName   = "memory.use_priority_oom"
 	MemoryOomGroupName         = "memory.oom.group"
 	MemoryIdlePageStatsName    = "memory.idle_page_stats"
-
-	BlkioTRIopsName   = "blkio.throttle.read_iops_device"
-	BlkioTRBpsName    = "blkio.throttle.read_bps_device"
-	BlkioTWIopsName   = "blkio.throttle.write_iops_device"
-	BlkioTWBpsName    = "blkio.throttle.write_bps_device"
-	BlkioIOWeightName = "blkio.cost.weight"
-	BlkioIOQoSName    = "blkio.cost.qos"
+	MemorySwappinessName       = "memory.swappiness"
+	MemoryReclaimName          = "memory.reclaim" // cgroups-v2 only, write-only
+
+	BlkioTRIopsName       = "blkio.throttle.read_iops_device"
+	BlkioTRBpsName        = "blkio.throttle.read_bps_device"
+	BlkioTWIopsName       = "blkio.throttle.write_iops_device"
+	BlkioTWBpsName        = "blkio.throttle.write_bps_device"
+	BlkioIOWeightName     = "blkio.cost.weight"
+	BlkioIOQoSName        = "blkio.cost.qos"
+	BlkioWeightName       = "blkio.weight"
+	BlkioWeightDeviceName = "blkio.weight_device" // cgroups-v1 only
+	BlkioBFQWeightName    = "blkio.bfq.weight"    // cgroups-v1 only, on the BFQ io scheduler
+	IOWeightName          = "io.weight"           // cgroups-v2 only
+	IOLatencyName         = "io.latency"          // cgroups-v2 only
+	IOMaxName             = "io.max"              // cgroups-v2 only
+	IOCostQoSName         = "io.cost.qos"         // cgroups-v2 only, in the root cgroup
+	IOCostModelName       = "io.cost.model"       // cgroups-v2 only, in the root cgroup
+	IOPrioClassName       = "io.prio.class"       // cgroups-v2 only, on the newer kernels
+
+	FreezerStateName        = "freezer.state"
+	FreezerSelfFreezingName = "freezer.self_freezing" // cgroups-v1 only
+	CgroupFreezeName        = "cgroup.freeze"         // cgroups-v2 only
+	CgroupEventsName        = "cgroup.events"         // cgroups-v2 only
+
+	DevicesAllowName = "devices.allow"
+	DevicesDenyName  = "devices.deny"
+
+	PidsMaxName     = "pids.max"
+	PidsCurrentName = "pids.current"
 )
 
 var (
@@ -176,7 +267,10 @@ var (
 	CPUSharesValidator                      = &RangeValidator{min: CPUSharesMinValue, max: CPUSharesMaxValue}
 	CPUBurstValidator                       = &RangeValidator{min: 0, max: 100 * 10 * 100000}
 	CPUBvtWarpNsValidator                   = &RangeValidator{min: -1, max: 2}
+	CPUIdleValidator                        = &RangeValidator{min: 0, max: 1}
+	CPUSetSchedLoadBalanceValidator         = &RangeValidator{min: 0, max: 1}
 	CPUWeightValidator                      = &RangeValidator{min: CPUWeightMinValue, max: CPUWeightMaxValue}
+	CPUWeightNiceValidator                  = &RangeValidator{min: CPUWeightNiceMinValue, max: CPUWeightNiceMaxValue}
 	CPUMaxBurstValidator                    = &RangeValidator{min: 0, max: math.MaxInt64}
 	MemoryWmarkRatioValidator               = &RangeValidator{min: 0, max: 100}
 	MemoryPriorityValidator                 = &RangeValidator{min: 0, max: 12}
@@ -190,8 +284,23 @@ var (
 	BlkioTWBpsValidator                     = &BlkIORangeValidator{min: 0, max: math.MaxInt64, resource: BlkioTWBpsName}
 	BlkioIOWeightValidator                  = &BlkIORangeValidator{min: 1, max: 100, resource: BlkioIOWeightName}
 	BlkioIOQoSValidator                     = &BlkIORangeValidator{min: 0, max: math.MaxInt64, resource: BlkioIOQoSName}
-
-	CPUSetCPUSValidator = &CPUSetStrValidator{}
+	IOLatencyValidator                      = &BlkIORangeValidator{min: 0, max: math.MaxInt64, resource: IOLatencyName}
+	IOMaxValidator                          = &DeviceLimitValidator{keys: IOMaxKeys}
+	IOCostQoSValidator                      = &DeviceParamsValidator{keys: []string{"enable", "ctrl", "rpct", "rlat", "wpct", "wlat", "min", "max"}}
+	IOCostModelValidator                    = &DeviceParamsValidator{keys: []string{"ctrl", "model", "rbps", "rseqiops", "rrandiops", "wbps", "wseqiops", "wrandiops"}}
+	IOPrioClassValidator                    = &StrEnumValidator{values: []string{IOPrioClassNoChange, IOPrioClassPromoteToRT, IOPrioClassNoneToRT, IOPrioClassRestrictToBE, IOPrioClassIdle}}
+	PidsMaxValidator                        = &RangeValidator{min: 1, max: math.MaxInt64}
+	MemoryReclaimValidator                  = &RangeValidator{min: 1, max: math.MaxInt64}
+	MemorySwappinessValidator               = &RangeValidator{min: 0, max: MemorySwappinessMaxValue}
+	BlkioWeightValidator                    = &RangeValidator{min: BlkioWeightMinValue, max: BlkioWeightMaxValue}
+	BlkioWeightDeviceValidator              = &KeyedWeightValidator{min: BlkioWeightMinValue, max: BlkioWeightMaxValue}
+	BlkioBFQWeightValidator                 = &RangeValidator{min: BlkioBFQWeightMinValue, max: BlkioWeightMaxValue}
+	IOWeightValidator                       = &KeyedWeightValidator{min: IOWeightMinValue, max: IOWeightMaxValue}
+	FreezerStateValidator                   = &StrEnumValidator{values: []string{FreezerStateFrozen, FreezerStateThawed}}
+	CgroupFreezeValidator                   = &StrEnumValidator{values: []string{CgroupFreezeFrozen, CgroupFreezeThawed}}
+
+	CPUSetCPUSValidator  = &CPUSetStrValidator{}
+	DevicesRuleValidator = &DeviceRuleValidator{}
 )
 
 // for cgroup resources, we use the corresponding cgroups-v1 filename as its resource type
@@ -204,10 +313,15 @@ var (
 	CPUCFSPeriod = DefaultFactory.New(CPUCFSPeriodName, CgroupCPUDir)
 	CPUBurst     = DefaultFactory.New(CPUBurstName, CgroupCPUDir).WithValidator(CPUBurstValidator).WithCheckSupported(SupportedIfFileExists)
 	CPUBVTWarpNs = DefaultFactory.New(CPUBVTWarpNsName, CgroupCPUDir).WithValidator(CPUBvtWarpNsValidator).WithCheckSupported(SupportedIfFileExists)
+	CPUIdle      = DefaultFactory.New(CPUIdleName, CgroupCPUDir).WithValidator(CPUIdleValidator).WithCheckSupported(SupportedIfFileExists)
 	CPUTasks     = DefaultFactory.New(CPUTasksName, CgroupCPUDir)
 	CPUProcs     = DefaultFactory.New(CPUProcsName, CgroupCPUDir)
+	// cgroups-v1 only has the cpu.shares for the cpu weight
+	CPUWeightNice = DefaultFactory.New(CPUWeightNiceName, CgroupCPUDir).WithValidator(CPUWeightNiceValidator).WithSupported(false, cpuWeightNiceV1UnsupportedMsg)
 
-	CPUSet = DefaultFactory.New(CPUSetCPUSName, CgroupCPUSetDir).WithValidator(CPUSetCPUSValidator)
+	CPUSet                 = DefaultFactory.New(CPUSetCPUSName, CgroupCPUSetDir).WithValidator(CPUSetCPUSValidator)
+	CPUSetExclusive        = DefaultFactory.New(CPUSetCPUSExclusiveName, CgroupCPUSetDir).WithValidator(CPUSetCPUSValidator).WithSupported(false, cpusetExclusiveV1UnsupportedMsg)
+	CPUSetSchedLoadBalance = DefaultFactory.New(CPUSetSchedLoadBalanceName, CgroupCPUSetDir).WithValidator(CPUSetSchedLoadBalanceValidator).WithCheckSupported(SupportedIfFileExists)
 
 	CPUAcctStat           = DefaultFactory.New(CPUAcctStatName, CgroupCPUAcctDir)
 	CPUAcctUsage          = DefaultFactory.New(CPUAcctUsageName, CgroupCPUAcctDir)
@@ -217,6 +331,7 @@ var (
 
 	MemoryLimit            = DefaultFactory.New(MemoryLimitName, CgroupMemDir)
 	MemoryUsage            = DefaultFactory.New(MemoryUsageName, CgroupMemDir)
+	MemoryReclaim          = DefaultFactory.New(MemoryReclaimName, CgroupMemDir).WithValidator(MemoryReclaimValidator).WithSupported(false, memoryReclaimV1UnsupportedMsg)
 	MemoryStat             = DefaultFactory.New(MemoryStatName, CgroupMemDir)
 	MemoryNumaStat         = DefaultFactory.New(MemoryNumaStatName, CgroupMemDir)
 	MemoryWmarkRatio       = DefaultFactory.New(MemoryWmarkRatioName, CgroupMemDir).WithValidator(MemoryWmarkRatioValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
@@ -229,13 +344,31 @@ var (
 	MemoryUsePriorityOom   = DefaultFactory.New(MemoryUsePriorityOomName, CgroupMemDir).WithValidator(MemoryUsePriorityOomValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
 	MemoryOomGroup         = DefaultFactory.New(MemoryOomGroupName, CgroupMemDir).WithValidator(MemoryOomGroupValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
 	MemoryIdlePageStats    = DefaultFactory.New(MemoryIdlePageStatsName, CgroupMemDir).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-
-	BlkioReadIops  = DefaultFactory.New(BlkioTRIopsName, CgroupBlkioDir).WithValidator(BlkioTRIopsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-	BlkioReadBps   = DefaultFactory.New(BlkioTRBpsName, CgroupBlkioDir).WithValidator(BlkioTRBpsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-	BlkioWriteIops = DefaultFactory.New(BlkioTWIopsName, CgroupBlkioDir).WithValidator(BlkioTWIopsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-	BlkioWriteBps  = DefaultFactory.New(BlkioTWBpsName, CgroupBlkioDir).WithValidator(BlkioTWBpsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-	BlkioIOWeight  = DefaultFactory.New(BlkioIOWeightName, CgroupBlkioDir).WithValidator(BlkioIOWeightValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-	BlkioIOQoS     = DefaultFactory.New(BlkioIOQoSName, CgroupBlkioDir).WithValidator(BlkioIOQoSValidator).WithSupported(SupportedIfFileExistsInRootCgroup(BlkioIOQoSName, CgroupBlkioDir))
+	MemorySwappiness       = DefaultFactory.New(MemorySwappinessName, CgroupMemDir).WithValidator(MemorySwappinessValidator)
+
+	BlkioReadIops     = DefaultFactory.New(BlkioTRIopsName, CgroupBlkioDir).WithValidator(BlkioTRIopsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
+	BlkioReadBps      = DefaultFactory.New(BlkioTRBpsName, CgroupBlkioDir).WithValidator(BlkioTRBpsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
+	BlkioWriteIops    = DefaultFactory.New(BlkioTWIopsName, CgroupBlkioDir).WithValidator(BlkioTWIopsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
+	BlkioWriteBps     = DefaultFactory.New(BlkioTWBpsName, CgroupBlkioDir).WithValidator(BlkioTWBpsValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
+	BlkioIOWeight     = DefaultFactory.New(BlkioIOWeightName, CgroupBlkioDir).WithValidator(BlkioIOWeightValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
+	BlkioIOQoS        = DefaultFactory.New(BlkioIOQoSName, CgroupBlkioDir).WithValidator(BlkioIOQoSValidator).WithSupported(SupportedIfFileExistsInRootCgroup(BlkioIOQoSName, CgroupBlkioDir))
+	BlkioWeight       = DefaultFactory.New(BlkioWeightName, CgroupBlkioDir).WithValidator(BlkioWeightValidator)
+	BlkioWeightDevice = DefaultFactory.New(BlkioWeightDeviceName, CgroupBlkioDir).WithValidator(BlkioWeightDeviceValidator).WithCheckSupported(SupportedIfFileExists)
+	BlkioBFQWeight    = DefaultFactory.New(BlkioBFQWeightName, CgroupBlkioDir).WithValidator(BlkioBFQWeightValidator).WithCheckSupported(SupportedIfFileExists)
+	IOLatency         = DefaultFactory.New(IOLatencyName, CgroupBlkioDir).WithValidator(IOLatencyValidator).WithSupported(false, ioLatencyV1UnsupportedMsg)
+	IOMax             = DefaultFactory.New(IOMaxName, CgroupBlkioDir).WithValidator(IOMaxValidator).WithSupported(false, ioMaxV1UnsupportedMsg)
+	IOCostQoS         = DefaultFactory.New(IOCostQoSName, CgroupBlkioDir).WithValidator(IOCostQoSValidator).WithSupported(false, ioCostV1UnsupportedMsg)
+	IOCostModel       = DefaultFactory.New(IOCostModelName, CgroupBlkioDir).WithValidator(IOCostModelValidator).WithSupported(false, ioCostV1UnsupportedMsg)
+	IOPrioClass       = DefaultFactory.New(IOPrioClassName, CgroupBlkioDir).WithValidator(IOPrioClassValidator).WithSupported(false, ioPrioClassV1UnsupportedMsg)
+
+	DevicesAllow = DefaultFactory.New(DevicesAllowName, CgroupDevicesDir).WithValidator(DevicesRuleValidator)
+	DevicesDeny  = DefaultFactory.New(DevicesDenyName, CgroupDevicesDir).WithValidator(DevicesRuleValidator)
+
+	PidsMax     = DefaultFactory.New(PidsMaxName, CgroupPidsDir).WithValidator(PidsMaxValidator)
+	PidsCurrent = DefaultFactory.New(PidsCurrentName, CgroupPidsDir)
+
+	FreezerState        = DefaultFactory.New(FreezerStateName, CgroupFreezerDir).WithValidator(FreezerStateValidator)
+	FreezerSelfFreezing = DefaultFactory.New(FreezerSelfFreezingName, CgroupFreezerDir)
 
 	knownCgroupResources = []Resource{
 		CPUStat,
@@ -245,15 +378,20 @@ var (
 		CPUBurst,
 		CPUTasks,
 		CPUBVTWarpNs,
+		CPUIdle,
 		CPUSet,
+		CPUSetExclusive,
+		CPUSetSchedLoadBalance,
 		CPUAcctStat,
 		CPUAcctUsage,
 		CPUAcctCPUPressure,
 		CPUAcctMemoryPressure,
 		CPUAcctIOPressure,
 		CPUProcs,
+		CPUWeightNice,
 		MemoryLimit,
 		MemoryUsage,
+		MemoryReclaim,
 		MemoryStat,
 		MemoryNumaStat,
 		MemoryWmarkRatio,
@@ -266,22 +404,39 @@ var (
 		MemoryUsePriorityOom,
 		MemoryOomGroup,
 		MemoryIdlePageStats,
+		MemorySwappiness,
 		BlkioReadIops,
 		BlkioReadBps,
 		BlkioWriteIops,
 		BlkioWriteBps,
 		BlkioIOWeight,
 		BlkioIOQoS,
+		BlkioWeight,
+		BlkioWeightDevice,
+		BlkioBFQWeight,
+		IOLatency,
+		IOMax,
+		IOCostQoS,
+		IOCostModel,
+		IOPrioClass,
+		DevicesAllow,
+		DevicesDeny,
+		PidsMax,
+		PidsCurrent,
+		FreezerState,
+		FreezerSelfFreezing,
 	}
 
-	CPUCFSQuotaV2  = DefaultFactory.NewV2(CPUCFSQuotaName, CPUMaxName)
-	CPUCFSPeriodV2 = DefaultFactory.NewV2(CPUCFSPeriodName, CPUMaxName)
-	CPUSharesV2    = DefaultFactory.NewV2(CPUSharesName, CPUWeightName).WithValidator(CPUWeightValidator)
-	CPUStatV2      = DefaultFactory.NewV2(CPUStatName, CPUStatName)
-	CPUAcctStatV2  = DefaultFactory.NewV2(CPUAcctStatName, CPUStatName)
-	CPUAcctUsageV2 = DefaultFactory.NewV2(CPUAcctUsageName, CPUStatName)
-	CPUBurstV2     = DefaultFactory.NewV2(CPUBurstName, CPUMaxBurstName).WithValidator(CPUMaxBurstValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
-	CPUBVTWarpNsV2 = DefaultFactory.NewV2(CPUBVTWarpNsName, CPUBVTWarpNsName).WithValidator(CPUBvtWarpNsValidator).WithCheckSupported(SupportedIfFileExists)
+	CPUCFSQuotaV2   = DefaultFactory.NewV2(CPUCFSQuotaName, CPUMaxName)
+	CPUCFSPeriodV2  = DefaultFactory.NewV2(CPUCFSPeriodName, CPUMaxName)
+	CPUSharesV2     = DefaultFactory.NewV2(CPUSharesName, CPUWeightName).WithValidator(CPUWeightValidator)
+	CPUWeightNiceV2 = DefaultFactory.NewV2(CPUWeightNiceName, CPUWeightNiceName).WithValidator(CPUWeightNiceValidator)
+	CPUStatV2       = DefaultFactory.NewV2(CPUStatName, CPUStatName)
+	CPUAcctStatV2   = DefaultFactory.NewV2(CPUAcctStatName, CPUStatName)
+	CPUAcctUsageV2  = DefaultFactory.NewV2(CPUAcctUsageName, CPUStatName)
+	CPUBurstV2      = DefaultFactory.NewV2(CPUBurstName, CPUMaxBurstName).WithValidator(CPUMaxBurstValidator).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
+	CPUBVTWarpNsV2  = DefaultFactory.NewV2(CPUBVTWarpNsName, CPUBVTWarpNsName).WithValidator(CPUBvtWarpNsValidator).WithCheckSupported(SupportedIfFileExists)
+	CPUIdleV2       = DefaultFactory.NewV2(CPUIdleName, CPUIdleName).WithValidator(CPUIdleValidator).WithCheckSupported(SupportedIfFileExists)
 
 	CPUAcctCPUPressureV2    = DefaultFactory.NewV2(CPUAcctCPUPressureName, CPUAcctCPUPressureName).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
 	CPUAcctMemoryPressureV2 = DefaultFactory.NewV2(CPUAcctMemoryPressureName, CPUAcctMemoryPressureName).WithCheckSupported(SupportedIfFileExistsInKubepods).WithCheckOnce(true)
@@ -289,10 +444,14 @@ var (
 
 	CPUSetV2                 = DefaultFactory.NewV2(CPUSetCPUSName, CPUSetCPUSName).WithValidator(CPUSetCPUSValidator)
 	CPUSetEffectiveV2        = DefaultFactory.NewV2(CPUSetCPUSEffectiveName, CPUSetCPUSEffectiveName) // TODO: unify the R/W
+	CPUSetExclusiveV2        = DefaultFactory.NewV2(CPUSetCPUSExclusiveName, CPUSetCPUSExclusiveName).WithValidator(CPUSetCPUSValidator).WithCheckSupported(SupportedIfFileExists)
+	CPUSetSchedLoadBalanceV2 = DefaultFactory.NewV2(CPUSetSchedLoadBalanceName, CPUSetSchedLoadBalanceName).WithValidator(CPUSetSchedLoadBalanceValidator).WithSupported(false, cpusetSchedLoadBalanceV2UnsupportedMsg)
 	CPUTasksV2               = DefaultFactory.NewV2(CPUTasksName, CPUThreadsName)
 	CPUProcsV2               = DefaultFactory.NewV2(CPUProcsName, CPUProcsName)
+	CgroupTypeV2             = DefaultFactory.NewV2(CgroupTypeName, CgroupTypeName)
 	MemoryLimitV2            = DefaultFactory.NewV2(MemoryLimitName, MemoryMaxName)
 	MemoryUsageV2            = DefaultFactory.NewV2(MemoryUsageName, MemoryCurrentName)
+	MemoryReclaimV2          = DefaultFactory.NewV2(MemoryReclaimName, MemoryReclaimName).WithValidator(MemoryReclaimValidator).WithCheckSupported(SupportedIfFileExists)
 	MemoryStatV2             = DefaultFactory.NewV2(MemoryStatName, MemoryStatName)
 	MemoryNumaStatV2         = DefaultFactory.NewV2(MemoryNumaStatName, MemoryNumaStatName)
 	MemoryMinV2              = DefaultFactory.NewV2(MemoryMinName, MemoryMinName).WithValidator(NaturalInt64Validator)
@@ -304,25 +463,49 @@ var (
 	MemoryPriorityV2         = DefaultFactory.NewV2(MemoryPriorityName, MemoryPriorityName).WithValidator(MemoryPriorityValidator).WithCheckSupported(SupportedIfFileExists)
 	MemoryUsePriorityOomV2   = DefaultFactory.NewV2(MemoryUsePriorityOomName, MemoryUsePriorityOomName).WithValidator(MemoryUsePriorityOomValidator).WithCheckSupported(SupportedIfFileExists)
 	MemoryOomGroupV2         = DefaultFactory.NewV2(MemoryOomGroupName, MemoryOomGroupName).WithValidator(MemoryOomGroupValidator).WithCheckSupported(SupportedIfFileExists)
+	MemorySwappinessV2       = DefaultFactory.NewV2(MemorySwappinessName, MemorySwappinessName).WithValidator(MemorySwappinessValidator).WithSupported(false, memorySwappinessV2UnsupportedMsg)
+
+	// cgroups-v2 has no device controller interface files, the device access is controlled by the eBPF program
+	// attached to the cgroup (BPF_PROG_TYPE_CGROUP_DEVICE)
+	DevicesAllowV2 = DefaultFactory.NewV2(DevicesAllowName, DevicesAllowName).WithValidator(DevicesRuleValidator).WithSupported(false, devicesV2UnsupportedMsg)
+	DevicesDenyV2  = DefaultFactory.NewV2(DevicesDenyName, DevicesDenyName).WithValidator(DevicesRuleValidator).WithSupported(false, devicesV2UnsupportedMsg)
+	PidsMaxV2      = DefaultFactory.NewV2(PidsMaxName, PidsMaxName).WithValidator(PidsMaxValidator)
+	PidsCurrentV2  = DefaultFactory.NewV2(PidsCurrentName, PidsCurrentName)
+	BlkioWeightV2  = DefaultFactory.NewV2(BlkioWeightName, IOWeightName).WithValidator(IOWeightValidator)
+	IOLatencyV2    = DefaultFactory.NewV2(IOLatencyName, IOLatencyName).WithValidator(IOLatencyValidator)
+	IOMaxV2        = DefaultFactory.NewV2(IOMaxName, IOMaxName).WithValidator(IOMaxValidator)
+	IOCostQoSV2    = DefaultFactory.NewV2(IOCostQoSName, IOCostQoSName).WithValidator(IOCostQoSValidator).WithCheckSupported(SupportedIfFileExistsInRoot)
+	IOCostModelV2  = DefaultFactory.NewV2(IOCostModelName, IOCostModelName).WithValidator(IOCostModelValidator).WithCheckSupported(SupportedIfFileExistsInRoot)
+	IOPrioClassV2  = DefaultFactory.NewV2(IOPrioClassName, IOPrioClassName).WithValidator(IOPrioClassValidator).WithCheckSupported(SupportedIfFileExists)
+
+	// the cgroups-v2 freezer is the `cgroup.freeze` of the core, whose state is reported by the `cgroup.events`
+	FreezerStateV2 = DefaultFactory.NewV2(FreezerStateName, CgroupFreezeName).WithValidator(CgroupFreezeValidator).WithCheckSupported(SupportedIfFileExists)
+	CgroupEventsV2 = DefaultFactory.NewV2(CgroupEventsName, CgroupEventsName)
 
 	knownCgroupV2Resources = []Resource{
 		CPUCFSQuotaV2,
 		CPUCFSPeriodV2,
 		CPUSharesV2,
+		CPUWeightNiceV2,
 		CPUStatV2,
 		CPUAcctStatV2,
 		CPUAcctUsageV2,
 		CPUBurstV2,
 		CPUBVTWarpNsV2,
+		CPUIdleV2,
 		CPUAcctCPUPressureV2,
 		CPUAcctMemoryPressureV2,
 		CPUAcctIOPressureV2,
 		CPUSetV2,
 		CPUSetEffectiveV2,
+		CPUSetExclusiveV2,
+		CPUSetSchedLoadBalanceV2,
 		CPUTasksV2,
 		CPUProcsV2,
+		CgroupTypeV2,
 		MemoryLimitV2,
 		MemoryUsageV2,
+		MemoryReclaimV2,
 		MemoryStatV2,
 		MemoryNumaStatV2,
 		MemoryMinV2,
@@ -334,11 +517,59 @@ var (
 		MemoryPriorityV2,
 		MemoryUsePriorityOomV2,
 		MemoryOomGroupV2,
+		MemorySwappinessV2,
+		DevicesAllowV2,
+		DevicesDenyV2,
+		PidsMaxV2,
+		PidsCurrentV2,
+		BlkioWeightV2,
+		IOLatencyV2,
+		IOMaxV2,
+		IOCostQoSV2,
+		IOCostModelV2,
+		IOPrioClassV2,
+		FreezerStateV2,
+		CgroupEventsV2,
 		BlkioIOWeight,
 		BlkioIOQoS,
 	}
 )
 
+// SetMemorySwappinessMaxValue sets the max value of memory.swappiness according to the kernel, e.g. 200 for the
+// kernel 5.8 or newer. It is safe to call concurrently with the validations.
+func SetMemorySwappinessMaxValue(max int64) {
+	MemorySwappinessValidator.setMax(max)
+}
+
+// memorySwappinessMaxValueSinceKernel58 is the max value of memory.swappiness since kernel 5.8.
+const memorySwappinessMaxValueSinceKernel58 int64 = 200
+
+// initMemorySwappinessMaxValue sets the max value of memory.swappiness by the running kernel. The default max is kept
+// if the kernel version is unknown.
+func initMemorySwappinessMaxValue() {
+	max := getMemorySwappinessMaxValue()
+	SetMemorySwappinessMaxValue(max)
+	klog.V(4).Infof("set the max value of memory.swappiness to %v", max)
+}
+
+func getMemorySwappinessMaxValue() int64 {
+	kernelVersion, err := GetKernelVersion()
+	if err != nil {
+		klog.V(4).Infof("failed to get kernel version for the max value of memory.swappiness, err: %v", err)
+		return MemorySwappinessMaxValue
+	}
+	v, err := version.ParseGeneric(kernelVersion)
+	if err != nil {
+		klog.V(4).Infof("failed to parse kernel version %s for the max value of memory.swappiness, err: %v",
+			kernelVersion, err)
+		return MemorySwappinessMaxValue
+	}
+	if v.AtLeast(version.MustParseGeneric("5.8")) {
+		return memorySwappinessMaxValueSinceKernel58
+	}
+	return MemorySwappinessMaxValue
+}
+
 var _ Resource = &CgroupResource{}
 
 type CgroupResource struct {
@@ -381,7 +612,11 @@ func (c *CgroupResource) IsSupported(parentDir string) (bool, string) {
 	return isSupported, msg
 }
 
+// IsValid checks the value against the common grammar of the cgroup files, then the validator of the resource.
 func (c *CgroupResource) IsValid(v string) (bool, string) {
+	if valid, msg := ValidateCgroupValueGrammar(v); !valid {
+		return false, msg
+	}
 	if c.Validator == nil {
 		return true, ""
 	}
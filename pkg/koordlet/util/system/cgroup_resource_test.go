@@ -17,6 +17,7 @@ limitations under the License.
 package system
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,6 +51,25 @@ func TestCgroupResourceRegistry(t *testing.T) {
 	})
 }
 
+func TestGetCgroupResourceForVersion_Unsupported(t *testing.T) {
+	// the blkio.throttle.* is not registered in cgroups-v2
+	_, err := GetCgroupResourceForVersion(CgroupVersionV2, BlkioTRIopsName)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUnsupportedResource))
+	assert.True(t, IsResourceUnsupportedErr(err))
+	var unsupportedErr *UnsupportedResourceError
+	assert.True(t, errors.As(err, &unsupportedErr))
+	assert.Equal(t, ResourceType(BlkioTRIopsName), unsupportedErr.ResourceType)
+	assert.Equal(t, CgroupVersionV2, unsupportedErr.CgroupVersion)
+
+	_, err = GetCgroupResourceForVersion(CgroupVersionV1, BlkioTRIopsName)
+	assert.NoError(t, err)
+
+	// the ad hoc unsupported errors match the sentinel too
+	assert.True(t, errors.Is(ResourceUnsupportedErr("file not exist"), ErrUnsupportedResource))
+	assert.False(t, IsResourceUnsupportedErr(nil))
+}
+
 func TestCgroupResource(t *testing.T) {
 	type fields struct {
 		isV2             bool
@@ -118,3 +138,62 @@ func TestCgroupResource(t *testing.T) {
 		})
 	}
 }
+
+func TestInitMemorySwappinessMaxValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		kernelVersion string
+		want          int64
+	}{
+		{
+			name: "keep the default max if the kernel version is unknown",
+			want: MemorySwappinessMaxValue,
+		},
+		{
+			name:          "keep the default max on older kernel",
+			kernelVersion: "4.19.91-27.an8.x86_64",
+			want:          MemorySwappinessMaxValue,
+		},
+		{
+			name:          "raise the max on kernel 5.8",
+			kernelVersion: "5.8.0",
+			want:          memorySwappinessMaxValueSinceKernel58,
+		},
+		{
+			name:          "raise the max on newer kernel",
+			kernelVersion: "5.10.134-13.an8.x86_64",
+			want:          memorySwappinessMaxValueSinceKernel58,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := NewFileTestUtil(t)
+			defer helper.Cleanup()
+			kernelVersionCache.version = ""
+			defer func() {
+				kernelVersionCache.version = ""
+				SetMemorySwappinessMaxValue(MemorySwappinessMaxValue)
+			}()
+			if tt.kernelVersion != "" {
+				helper.WriteFileContents(GetProcSysFilePath(KernelOSReleaseFileName), tt.kernelVersion)
+			}
+
+			initMemorySwappinessMaxValue()
+			assert.Equal(t, tt.want, MemorySwappinessValidator.max)
+		})
+	}
+
+	// the max is set concurrently with the validations
+	defer SetMemorySwappinessMaxValue(MemorySwappinessMaxValue)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			MemorySwappinessValidator.Validate("150")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		SetMemorySwappinessMaxValue(memorySwappinessMaxValueSinceKernel58)
+	}
+	<-done
+}
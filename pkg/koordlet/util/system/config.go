@@ -58,6 +58,7 @@ func init() {
 func initSupportConfigs() {
 	initCgroupsVersion()
 	HostSystemInfo = collectVersionInfo()
+	initMemorySwappinessMaxValue()
 	_, _ = IsSupportResctrl()
 }
 
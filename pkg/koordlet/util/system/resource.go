@@ -17,6 +17,7 @@ limitations under the License.
 package system
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
@@ -65,12 +66,41 @@ func ValidateResourceValue(value *int64, dynamicPath string, r Resource) bool {
 	return true
 }
 
+// ErrUnsupportedResource is the sentinel of the errors returned when the resource is unavailable on the running
+// kernel, e.g. the file does not exist or the resource is not registered in the cgroup version. The callers can branch
+// on it with errors.Is.
+var ErrUnsupportedResource = errors.New(ErrResourceUnsupportedPrefix)
+
+// UnsupportedResourceError is the ErrUnsupportedResource carrying the resource type and the cgroup version, which can
+// be retrieved with errors.As.
+type UnsupportedResourceError struct {
+	ResourceType  ResourceType
+	CgroupVersion CgroupVersion
+	Reason        string
+}
+
+func (e *UnsupportedResourceError) Error() string {
+	return fmt.Sprintf("%s, reason: %s", ErrResourceUnsupportedPrefix, e.Reason)
+}
+
+func (e *UnsupportedResourceError) Unwrap() error {
+	return ErrUnsupportedResource
+}
+
+// NewUnsupportedResourceErr returns the UnsupportedResourceError of the resource type in the cgroup version.
+func NewUnsupportedResourceErr(resourceType ResourceType, version CgroupVersion, reason string) error {
+	return &UnsupportedResourceError{ResourceType: resourceType, CgroupVersion: version, Reason: reason}
+}
+
 func ResourceUnsupportedErr(msg string) error {
-	return fmt.Errorf("%s, reason: %s", ErrResourceUnsupportedPrefix, msg)
+	return fmt.Errorf("%w, reason: %s", ErrUnsupportedResource, msg)
 }
 
 func IsResourceUnsupportedErr(err error) bool {
-	return strings.HasPrefix(err.Error(), ErrResourceUnsupportedPrefix)
+	if errors.Is(err, ErrUnsupportedResource) {
+		return true
+	}
+	return err != nil && strings.HasPrefix(err.Error(), ErrResourceUnsupportedPrefix)
 }
 
 func SupportedIfFileExistsInKubepods(r Resource, _ string) (bool, string) {
@@ -107,6 +137,19 @@ func SupportedIfFileExistsInRootCgroup(filename string, subfs string) (bool, str
 	return true, ""
 }
 
+// SupportedIfFileExistsInRoot checks if the file of the resource exists in the root cgroup, e.g. the cgroups-v2
+// `io.cost.qos` which is only available in the root.
+func SupportedIfFileExistsInRoot(r Resource, _ string) (bool, string) {
+	exists, err := PathExists(r.Path(""))
+	if err != nil {
+		return false, fmt.Sprintf("cannot check if %s exists in root cgroup, err: %v", r.ResourceType(), err)
+	}
+	if !exists {
+		return false, "file not exist in root cgroup"
+	}
+	return true, ""
+}
+
 func CheckIfAllSupported(checkSupportedFns ...func() (bool, string)) func() (bool, string) {
 	return func() (bool, string) {
 		for _, fn := range checkSupportedFns {
@@ -21,6 +21,8 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode"
 
 	"github.com/koordinator-sh/koordinator/pkg/util/cpuset"
 )
@@ -31,6 +33,8 @@ type ResourceValidator interface {
 }
 
 type RangeValidator struct {
+	// max is accessed atomically since it can be updated at runtime, e.g. by SetMemorySwappinessMaxValue. Keep it the
+	// first field to be 64-bit aligned.
 	max int64
 	min int64
 }
@@ -49,12 +53,17 @@ func (r *RangeValidator) Validate(value string) (bool, string) {
 			return false, fmt.Sprintf("value %v is not an integer, err: %v", value, err)
 		}
 	}
-	if v < r.min || v > r.max {
-		return false, fmt.Sprintf("value %v is not in [min:%d, max:%d]", value, r.min, r.max)
+	max := atomic.LoadInt64(&r.max)
+	if v < r.min || v > max {
+		return false, fmt.Sprintf("value %v is not in [min:%d, max:%d]", value, r.min, max)
 	}
 	return true, ""
 }
 
+func (r *RangeValidator) setMax(max int64) {
+	atomic.StoreInt64(&r.max, max)
+}
+
 type CPUSetStrValidator struct{}
 
 func (c *CPUSetStrValidator) Validate(value string) (bool, string) {
@@ -65,6 +74,196 @@ func (c *CPUSetStrValidator) Validate(value string) (bool, string) {
 	return true, ""
 }
 
+// StrEnumValidator validates the value is one of the enumerated strings, e.g. "FROZEN" or "THAWED" of `freezer.state`.
+type StrEnumValidator struct {
+	values []string
+}
+
+func (s *StrEnumValidator) Validate(value string) (bool, string) {
+	if !isStringInSlice(value, s.values) {
+		return false, fmt.Sprintf("value %v is not in %v", value, s.values)
+	}
+	return true, ""
+}
+
+// DeviceRuleValidator validates the rule of the cgroups-v1 device controller, which is in the format of
+// "type major:minor access", e.g. "c 1:3 mr", "b 8:* rwm", "a *:* rwm". A single "a" is also valid for all devices.
+type DeviceRuleValidator struct{}
+
+func (d *DeviceRuleValidator) Validate(value string) (bool, string) {
+	if value == "a" {
+		return true, ""
+	}
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return false, fmt.Sprintf("value %v is not in the format of \"type major:minor access\"", value)
+	}
+	if t := fields[0]; t != "a" && t != "b" && t != "c" {
+		return false, fmt.Sprintf("device type %v is not one of a, b, c", t)
+	}
+	majMin := strings.Split(fields[1], ":")
+	if len(majMin) != 2 {
+		return false, fmt.Sprintf("device number %v is not in the format of \"major:minor\"", fields[1])
+	}
+	for _, n := range majMin {
+		if n == "*" {
+			continue
+		}
+		if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+			return false, fmt.Sprintf("device number %v is neither an integer nor \"*\"", n)
+		}
+	}
+	access := fields[2]
+	if len(access) > 3 {
+		return false, fmt.Sprintf("device access %v is invalid", access)
+	}
+	for _, a := range access {
+		if a != 'r' && a != 'w' && a != 'm' || strings.Count(access, string(a)) > 1 {
+			return false, fmt.Sprintf("device access %v is not a combination of r, w, m", access)
+		}
+	}
+	return true, ""
+}
+
+// KeyedWeightValidator validates the weight in the flat keyed format of cgroups-v2 `io.weight`, which can be
+// "default weight", "major:minor weight" or a single weight for the default.
+type KeyedWeightValidator struct {
+	max int64
+	min int64
+}
+
+func (k *KeyedWeightValidator) Validate(value string) (bool, string) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return false, fmt.Sprintf("value %v is not in the format of \"[default|major:minor] weight\"", value)
+	}
+	if len(fields) == 2 && fields[0] != "default" {
+		majMin := strings.Split(fields[0], ":")
+		if len(majMin) != 2 {
+			return false, fmt.Sprintf("device number %v is not in the format of \"major:minor\"", fields[0])
+		}
+		for _, n := range majMin {
+			if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+				return false, fmt.Sprintf("device number %v is not an integer", n)
+			}
+		}
+	}
+	v, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return false, fmt.Sprintf("weight %v is not an integer, err: %v", fields[len(fields)-1], err)
+	}
+	if v < k.min || v > k.max {
+		return false, fmt.Sprintf("weight %v is not in [min:%d, max:%d]", v, k.min, k.max)
+	}
+	return true, ""
+}
+
+// DeviceLimitValidator validates the per-device limits in the nested keyed format of cgroups-v2 `io.max`, e.g.
+// "8:0 rbps=1048576 wiops=max". The value can contain multiple lines for the devices.
+type DeviceLimitValidator struct {
+	keys []string
+}
+
+func (d *DeviceLimitValidator) Validate(value string) (bool, string) {
+	lines := strings.Split(strings.TrimSpace(value), "\n")
+	devices := map[string]bool{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return false, fmt.Sprintf("line %q is not in the format of \"major:minor key=value...\"", line)
+		}
+		majMin := strings.Split(fields[0], ":")
+		if len(majMin) != 2 {
+			return false, fmt.Sprintf("device number %v is not in the format of \"major:minor\"", fields[0])
+		}
+		for _, n := range majMin {
+			if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+				return false, fmt.Sprintf("device number %v is not an integer", n)
+			}
+		}
+		// the later line or limit of the same device silently overrides the former, which is likely a mistake
+		if devices[fields[0]] {
+			return false, fmt.Sprintf("device %v is duplicated", fields[0])
+		}
+		devices[fields[0]] = true
+		keys := map[string]bool{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 || !isStringInSlice(kv[0], d.keys) {
+				return false, fmt.Sprintf("limit %v is not in the format of \"key=value\", supported keys %v", field, d.keys)
+			}
+			if keys[kv[0]] {
+				return false, fmt.Sprintf("limit %v of device %v is duplicated", kv[0], fields[0])
+			}
+			keys[kv[0]] = true
+			if kv[1] == CgroupMaxSymbolStr {
+				continue
+			}
+			if _, err := strconv.ParseUint(kv[1], 10, 64); err != nil {
+				return false, fmt.Sprintf("limit %v is not a non-negative integer or \"max\"", field)
+			}
+		}
+	}
+	return true, ""
+}
+
+// DeviceParamsValidator validates a line of the device-keyed parameters, e.g. "8:0 enable=1 ctrl=user rpct=95.00" of
+// the cgroups-v2 `io.cost.qos`. Only one device is allowed since the kernel parses one device per write. The values
+// are checked to be non-empty, and left to the kernel to parse.
+type DeviceParamsValidator struct {
+	keys []string
+}
+
+func (d *DeviceParamsValidator) Validate(value string) (bool, string) {
+	if strings.Contains(strings.TrimSpace(value), "\n") {
+		return false, fmt.Sprintf("value %q should contain only one device", value)
+	}
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return false, fmt.Sprintf("value %q is not in the format of \"major:minor key=value...\"", value)
+	}
+	majMin := strings.Split(fields[0], ":")
+	if len(majMin) != 2 {
+		return false, fmt.Sprintf("device number %v is not in the format of \"major:minor\"", fields[0])
+	}
+	for _, n := range majMin {
+		if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+			return false, fmt.Sprintf("device number %v is not an integer", n)
+		}
+	}
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 || kv[1] == "" || !isStringInSlice(kv[0], d.keys) {
+			return false, fmt.Sprintf("param %v is not in the format of \"key=value\", supported keys %v", field, d.keys)
+		}
+	}
+	return true, ""
+}
+
+// ValidateCgroupValueGrammar checks the common grammar of the values written into the cgroup files, which are parsed
+// by the kernel as the whitespace-delimited fields without any quoting or escaping. The control characters other than
+// the line and field separators are rejected since they can corrupt the parsing, e.g. a NUL terminates the value.
+func ValidateCgroupValueGrammar(value string) (bool, string) {
+	for i, r := range value {
+		if r == '\n' || r == '\t' {
+			continue
+		}
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return false, fmt.Sprintf("value %q contains the invalid character %U at %d", value, r, i)
+		}
+	}
+	return true, ""
+}
+
+func isStringInSlice(s string, slice []string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 type BlkIORangeValidator struct {
 	resource string
 	max      int64
@@ -85,6 +284,13 @@ func (r *BlkIORangeValidator) Validate(value string) (bool, string) {
 		if len(rst) == 2 {
 			newValues = append(newValues, rst[1])
 		}
+	case IOLatencyName:
+		// 253:16 target=75
+		rst := strings.Split(value, " ")
+		if len(rst) != 2 || !strings.HasPrefix(rst[1], "target=") {
+			return false, fmt.Sprintf("value %v is not in the format of \"major:minor target=<us>\"", value)
+		}
+		newValues = append(newValues, strings.TrimPrefix(rst[1], "target="))
 	case BlkioIOQoSName:
 		// 253:16 enable=1 ctrl=user rlat=3000 wlat=4000
 		// 253:16 enable=0
@@ -70,3 +70,307 @@ func Test_RangeValidate(t *testing.T) {
 		})
 	}
 }
+
+func Test_DeviceRuleValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{
+			name:   "valid char device rule",
+			value:  "c 1:3 mr",
+			expect: true,
+		},
+		{
+			name:   "valid block device rule with wildcard",
+			value:  "b 8:* rwm",
+			expect: true,
+		},
+		{
+			name:   "valid all devices",
+			value:  "a",
+			expect: true,
+		},
+		{
+			name:   "invalid device type",
+			value:  "x 1:3 r",
+			expect: false,
+		},
+		{
+			name:   "invalid device number",
+			value:  "c 1-3 r",
+			expect: false,
+		},
+		{
+			name:   "invalid access",
+			value:  "c 1:3 rx",
+			expect: false,
+		},
+		{
+			name:   "duplicate access",
+			value:  "c 1:3 rr",
+			expect: false,
+		},
+		{
+			name:   "missing fields",
+			value:  "c 1:3",
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := DevicesRuleValidator.Validate(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func Test_KeyedWeightValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{
+			name:   "valid single weight",
+			value:  "100",
+			expect: true,
+		},
+		{
+			name:   "valid default weight",
+			value:  "default 10000",
+			expect: true,
+		},
+		{
+			name:   "valid device weight",
+			value:  "8:0 200",
+			expect: true,
+		},
+		{
+			name:   "weight out of range",
+			value:  "8:0 10001",
+			expect: false,
+		},
+		{
+			name:   "zero weight",
+			value:  "default 0",
+			expect: false,
+		},
+		{
+			name:   "invalid device number",
+			value:  "sda 200",
+			expect: false,
+		},
+		{
+			name:   "empty value",
+			value:  "",
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := IOWeightValidator.Validate(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func Test_StrEnumValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{
+			name:   "valid frozen",
+			value:  "FROZEN",
+			expect: true,
+		},
+		{
+			name:   "valid thawed",
+			value:  "THAWED",
+			expect: true,
+		},
+		{
+			name:   "read-only freezing",
+			value:  "FREEZING",
+			expect: false,
+		},
+		{
+			name:   "case sensitive",
+			value:  "frozen",
+			expect: false,
+		},
+		{
+			name:   "empty value",
+			value:  "",
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := FreezerStateValidator.Validate(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func Test_IOPrioValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{name: "valid no-change", value: "no-change", expect: true},
+		{name: "valid promote-to-rt", value: "promote-to-rt", expect: true},
+		{name: "valid none-to-rt", value: "none-to-rt", expect: true},
+		{name: "valid restrict-to-be", value: "restrict-to-be", expect: true},
+		{name: "valid idle", value: "idle", expect: true},
+		{name: "ioprio class name", value: "be", expect: false},
+		{name: "ioprio class with level", value: "rt 0", expect: false},
+		{name: "unknown policy", value: "none", expect: false},
+		{name: "empty value", value: "", expect: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := IOPrioClassValidator.Validate(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func Test_DeviceParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{
+			name:   "valid qos params",
+			value:  "8:0 enable=1 ctrl=user rpct=95.00 rlat=5000",
+			expect: true,
+		},
+		{
+			name:   "unknown key",
+			value:  "8:0 enable=1 rbps=1000",
+			expect: false,
+		},
+		{
+			name:   "empty param value",
+			value:  "8:0 enable=",
+			expect: false,
+		},
+		{
+			name:   "no params",
+			value:  "8:0",
+			expect: false,
+		},
+		{
+			name:   "invalid device number",
+			value:  "sda enable=1",
+			expect: false,
+		},
+		{
+			name:   "multiple devices",
+			value:  "8:0 enable=1\n8:16 enable=1",
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := IOCostQoSValidator.Validate(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func Test_ValidateCgroupValueGrammar(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{
+			name:   "valid single value",
+			value:  "1024",
+			expect: true,
+		},
+		{
+			name:   "valid multi-line value",
+			value:  "8:0 rbps=1048576\n8:16 wiops=max\n",
+			expect: true,
+		},
+		{
+			name:   "NUL truncates the value",
+			value:  "8:0 rbps=1048576\x00wbps=1",
+			expect: false,
+		},
+		{
+			name:   "carriage return",
+			value:  "8:0 rbps=1048576\r\n",
+			expect: false,
+		},
+		{
+			name:   "invalid utf-8",
+			value:  "8:0 rbps=\xff",
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := ValidateCgroupValueGrammar(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func Test_DeviceLimitValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect bool
+	}{
+		{
+			name:   "valid limits",
+			value:  "8:0 rbps=1048576 wiops=max",
+			expect: true,
+		},
+		{
+			name:   "valid limits of multiple devices",
+			value:  "8:0 rbps=1048576\n8:16 wbps=max",
+			expect: true,
+		},
+		{
+			name:   "fields of two devices in a line",
+			value:  "8:0 rbps=1048576 8:16 wbps=max",
+			expect: false,
+		},
+		{
+			name:   "duplicated device",
+			value:  "8:0 rbps=1048576\n8:0 wbps=max",
+			expect: false,
+		},
+		{
+			name:   "duplicated key",
+			value:  "8:0 rbps=1048576 rbps=1024",
+			expect: false,
+		},
+		{
+			name:   "unquoted shell-like value",
+			value:  "8:0 rbps=1048576;wbps=1024",
+			expect: false,
+		},
+		{
+			name:   "empty line between devices",
+			value:  "8:0 rbps=1048576\n\n8:16 wbps=max",
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := IOMaxValidator.Validate(tt.value)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
@@ -17,11 +17,16 @@ limitations under the License.
 package system
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"k8s.io/klog/v2"
 )
 
+const KernelOSReleaseFileName = "kernel/osrelease"
+
 var HostSystemInfo = collectVersionInfo()
 
 func collectVersionInfo() VersionInfo {
@@ -56,3 +61,24 @@ func isSupportBvtOrWmarRatio() bool {
 
 	return false
 }
+
+var kernelVersionCache = struct {
+	lock    sync.Mutex
+	version string
+}{}
+
+// GetKernelVersion returns the release of the running kernel, e.g. "5.10.134-13.an8.x86_64". It is read from the
+// `/proc/sys/kernel/osrelease` once and cached.
+func GetKernelVersion() (string, error) {
+	kernelVersionCache.lock.Lock()
+	defer kernelVersionCache.lock.Unlock()
+	if kernelVersionCache.version != "" {
+		return kernelVersionCache.version, nil
+	}
+	data, err := os.ReadFile(GetProcSysFilePath(KernelOSReleaseFileName))
+	if err != nil {
+		return "", err
+	}
+	kernelVersionCache.version = strings.TrimSpace(string(data))
+	return kernelVersionCache.version, nil
+}
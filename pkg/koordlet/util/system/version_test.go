@@ -66,3 +66,27 @@ func TestIsAnolisOS(t *testing.T) {
 	}
 
 }
+
+func TestGetKernelVersion(t *testing.T) {
+	helper := NewFileTestUtil(t)
+	defer helper.Cleanup()
+	defer func() {
+		kernelVersionCache.version = ""
+	}()
+
+	kernelVersionCache.version = ""
+	_, err := GetKernelVersion()
+	assert.Error(t, err)
+
+	osReleasePath := GetProcSysFilePath(KernelOSReleaseFileName)
+	helper.WriteFileContents(osReleasePath, "5.10.134-13.an8.x86_64\n")
+	got, err := GetKernelVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "5.10.134-13.an8.x86_64", got)
+
+	// the version is cached
+	helper.WriteFileContents(osReleasePath, "6.1.0\n")
+	got, err = GetKernelVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "5.10.134-13.an8.x86_64", got)
+}